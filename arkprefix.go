@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// errNoMatchingRows is returned for a CSV that has no rows whose Item ARK starts with the --ark-prefix
+var errNoMatchingRows = errors.New("no rows match the ARK prefix")
+
+// filterByARKPrefix keeps the header and the rows whose Item ARK starts with the prefix, along with the
+// collection rows if asked to, and returns the number of data rows that were kept and dropped
+func filterByARKPrefix(records [][]string, prefix string, keepCollections bool) ([][]string, int, int, error) {
+	if len(records) == 0 {
+		return records, 0, 0, nil
+	}
+
+	header := records[0]
+	itemIndex := columnIndex(header, itemARKColumn)
+	if itemIndex == -1 {
+		return nil, 0, 0, fmt.Errorf("can't filter rows by ARK prefix without an %s column", itemARKColumn)
+	}
+	typeIndex := columnIndex(header, objectTypeColumn)
+
+	filtered := [][]string{header}
+	kept := 0
+	for _, row := range records[1:] {
+		matches := strings.HasPrefix(cell(row, itemIndex), prefix)
+		isCollection := normalizeObjectType(cell(row, typeIndex)) == objectTypeCollection
+		if matches || (keepCollections && isCollection) {
+			filtered = append(filtered, row)
+		}
+		if matches {
+			kept++
+		}
+	}
+	return filtered, kept, len(records) - len(filtered), nil
+}
+
+// FilterByARKPrefix keeps the rows whose Item ARK starts with the prefix, and the header; with keepCollections,
+// collection rows are kept as well, so that the works in the subset still have their collection
+func FilterByARKPrefix(prefix string, keepCollections bool) CSVTransform {
+	return func(records [][]string) ([][]string, error) {
+		filtered, kept, _, err := filterByARKPrefix(records, prefix, keepCollections)
+		if err == nil && kept == 0 {
+			err = errNoMatchingRows
+		}
+		return filtered, err
+	}
+}
+
+// countARKPrefixRows reports how many of a CSV's rows the ARK prefix keeps and drops, returning
+// errNoMatchingRows if it keeps none
+func countARKPrefixRows(path, filename, prefix string, keepCollections bool, input CSVInput) error {
+	// The Item ARK column can be one that --map renames
+	records, err := input.readRenamedRecords(path)
+	if err != nil {
+		return err
+	}
+	_, kept, dropped, err := filterByARKPrefix(records, prefix, keepCollections)
+	if err != nil {
+		return err
+	}
+
+	Logger.Info("Filtered rows by ARK prefix",
+		zap.String("filename", filename),
+		zap.String("ark_prefix", prefix),
+		zap.Int("kept", kept),
+		zap.Int("dropped", dropped))
+	if kept == 0 {
+		return errNoMatchingRows
+	}
+	fmt.Fprintf(humanOutput(), "%s: uploading %d rows with an Item ARK starting with %s, dropping %d\n",
+		filename, kept, prefix, dropped)
+	return nil
+}
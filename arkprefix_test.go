@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testARKPrefixCSV is a shared CSV holding works from two shoulders
+const testARKPrefixCSV = `Item ARK,Parent ARK,Object Type,Title
+ark:/21198/zz0001,,Collection,Papers
+ark:/21198/zz0002,ark:/21198/zz0001,Work,Letter
+ark:/21198/yy0003,ark:/21198/zz0001,Work,Map
+ark:/21198/zz0004,ark:/21198/zz0002,Page,Page 1
+`
+
+// TestFilterByARKPrefix tests that only the matching rows and the header are kept, with or without the
+// collection rows
+func TestFilterByARKPrefix(t *testing.T) {
+	records, err := readCSVRecords(writeTestCSV(t, "shared.csv", testARKPrefixCSV))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	filtered, err := FilterByARKPrefix("ark:/21198/yy", true)(records)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"Item ARK", "Parent ARK", "Object Type", "Title"},
+		{"ark:/21198/zz0001", "", "Collection", "Papers"},
+		{"ark:/21198/yy0003", "ark:/21198/zz0001", "Work", "Map"},
+	}, filtered)
+
+	filtered, kept, dropped, err := filterByARKPrefix(records, "ark:/21198/zz", false)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, kept)
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, records[0], filtered[0])
+	assert.Len(t, filtered, 4)
+
+	filtered, kept, dropped, err = filterByARKPrefix(records, "ark:/21198/yy", false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, kept)
+	assert.Equal(t, 3, dropped)
+	assert.Len(t, filtered, 2)
+}
+
+// TestFilterByARKPrefixInvalid tests that a CSV without matching rows, or without Item ARKs, isn't filtered
+func TestFilterByARKPrefixInvalid(t *testing.T) {
+	records, err := readCSVRecords(writeTestCSV(t, "shared.csv", testARKPrefixCSV))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = FilterByARKPrefix("ark:/13030/", true)(records)
+	assert.ErrorIs(t, err, errNoMatchingRows)
+
+	_, err = FilterByARKPrefix("ark:/21198/", true)([][]string{{"Title"}, {"Papers"}})
+	assert.ErrorContains(t, err, "without an Item ARK column")
+}
+
+// TestRunARKPrefix tests that only the filtered subset is uploaded, and that a file without matching rows is
+// skipped rather than uploaded
+func TestRunARKPrefix(t *testing.T) {
+	var uploaded []string
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		if file, _, err := r.FormFile("file"); err == nil {
+			content, _ := io.ReadAll(file)
+			uploaded = append(uploaded, string(content))
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	shared := writeTestCSV(t, "shared.csv", testARKPrefixCSV)
+	other := writeTestCSV(t, "other.csv", testCollectionCSV)
+	cfg := testConfig(t, h.server.URL, shared, other)
+	cfg.ARKPrefix, cfg.KeepCollectionRows = "ark:/21198/yy", true
+	exitCode, results, err := run(context.Background(), cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, []string{"Item ARK,Parent ARK,Object Type,Title\n" +
+		"ark:/21198/zz0001,,Collection,Papers\n" +
+		"ark:/21198/yy0003,ark:/21198/zz0001,Work,Map\n"}, uploaded)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, StatusSuccess, results[0].Status)
+		assert.Equal(t, StatusSkipped, results[1].Status)
+	}
+	assert.Contains(t, h.stdout.String(), "shared.csv: uploading 1 rows with an Item ARK starting with ark:/21198/yy, dropping 2")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// tokenEnvVar is the environment variable the bearer token is read from when --token isn't given
+const tokenEnvVar string = "FESTERIZE_TOKEN"
+
+// Authenticator adds credentials to a request sent to Fester
+type Authenticator func(request *http.Request)
+
+// noAuth leaves the request unauthenticated
+func noAuth(*http.Request) {}
+
+// BasicAuth authenticates requests with a username and password
+func BasicAuth(username, password string) Authenticator {
+	return func(request *http.Request) {
+		request.SetBasicAuth(username, password)
+	}
+}
+
+// BearerAuth authenticates requests with an OAuth bearer token
+func BearerAuth(token string) Authenticator {
+	return func(request *http.Request) {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// NewAuthenticator picks how requests to a Fester URL are authenticated: basic auth when the URL holds
+// credentials, or a bearer token when one is given. The two can't be combined.
+func NewAuthenticator(rawURL, token string) (Authenticator, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	user := parsedURL.User
+	switch {
+	case user != nil && token != "":
+		return nil, errors.New("a bearer token can't be used with a server URL that has basic auth credentials")
+	case user != nil:
+		password, _ := user.Password()
+		return BasicAuth(user.Username(), password), nil
+	case token != "":
+		return BearerAuth(token), nil
+	default:
+		return noAuth, nil
+	}
+}
+
+// authenticate adds credentials to a request; credentials are moved out of the URL so they aren't logged
+func authenticate(request *http.Request, token string) error {
+	authenticator, err := NewAuthenticator(request.URL.String(), token)
+	if err != nil {
+		return err
+	}
+	authenticator(request)
+	request.URL.User = nil
+	return nil
+}
+
+// ValidateAuth checks that the configured servers and token don't ask for conflicting authentication
+func ValidateAuth() error {
+	if authToken == "" {
+		authToken = os.Getenv(tokenEnvVar)
+	}
+	for _, server := range servers {
+		if _, err := NewAuthenticator(server, authToken); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// wideRanges are the ranges of runes that terminals draw two columns wide: East Asian wide and fullwidth
+// characters, and emoji
+var wideRanges = []struct{ first, last rune }{
+	{0x1100, 0x115F},
+	{0x2614, 0x2615},
+	{0x26A1, 0x26A1},
+	{0x2705, 0x2705},
+	{0x2728, 0x2728},
+	{0x274C, 0x274C},
+	{0x2E80, 0x303E},
+	{0x3041, 0xA4CF},
+	{0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF},
+	{0xFE30, 0xFE4F},
+	{0xFF00, 0xFF60},
+	{0xFFE0, 0xFFE6},
+	{0x1F300, 0x1F64F},
+	{0x1F680, 0x1F6FF},
+	{0x1F900, 0x1FAFF},
+	{0x20000, 0x3FFFD},
+}
+
+// runeWidth returns the number of terminal columns a rune takes up
+func runeWidth(r rune) int {
+	if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.Is(unicode.Cf, r) ||
+		unicode.Is(unicode.Variation_Selector, r) {
+		return 0
+	}
+	for _, wide := range wideRanges {
+		if r >= wide.first && r <= wide.last {
+			return 2
+		}
+	}
+	return 1
+}
+
+// displayWidth returns the number of terminal columns a string takes up
+func displayWidth(value string) int {
+	width := 0
+	for _, r := range value {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// successBanner frames a message between two lines of the border emoji that are at least as wide as the message
+func successBanner(border, message string) string {
+	line := border + " " + message + " " + border
+	borderWidth := max(displayWidth(border), 1)
+	count := (displayWidth(line) + borderWidth - 1) / borderWidth
+	edge := strings.Repeat(border, count)
+	return edge + "\n" + line + "\n" + edge + "\n"
+}
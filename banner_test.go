@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDisplayWidth tests the column widths of ASCII, accented, wide, and emoji text
+func TestDisplayWidth(t *testing.T) {
+	assert.Equal(t, 10, displayWidth("ballin.csv"))
+	assert.Equal(t, 8, displayWidth("café.csv"))
+	assert.Equal(t, 8, displayWidth("cafe\u0301.csv"))
+	assert.Equal(t, 8, displayWidth("文書.csv"))
+	assert.Equal(t, 2, displayWidth("✨"))
+	assert.Equal(t, 2, displayWidth("✔️ "))
+}
+
+// TestSuccessBanner tests that the border lines match the message line for ASCII and multibyte filenames
+func TestSuccessBanner(t *testing.T) {
+	for _, border := range []string{"🎉", "✨", "✔️ "} {
+		for _, filename := range []string{"ballin.csv", "café.csv", "文書目録.csv", "ballin_😎.csv"} {
+			lines := strings.Split(strings.TrimSuffix(successBanner(border, "SUCCESS! Uploaded "+filename), "\n"), "\n")
+			assert.Len(t, lines, 3)
+
+			messageWidth := displayWidth(lines[1])
+			for _, edge := range []string{lines[0], lines[2]} {
+				assert.GreaterOrEqual(t, displayWidth(edge), messageWidth, filename)
+				assert.Less(t, displayWidth(edge), messageWidth+displayWidth(border), filename)
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizeBasePath puts a --base-path in the form it's joined to servers in: with a leading slash, no
+// trailing slash, and no empty segments, or empty if it has no segments at all
+func NormalizeBasePath(value string) string {
+	var segments []string
+	for _, segment := range strings.Split(strings.TrimSpace(value), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// ValidateBasePath checks that a --base-path is only a path, without a scheme, host, query, or fragment
+func ValidateBasePath(value string) error {
+	if strings.Contains(value, "://") || strings.ContainsAny(value, "?#") {
+		return fmt.Errorf("invalid --base-path %q: expected a path such as /fester", value)
+	}
+	return nil
+}
+
+// endpointURL returns the URL of one of a server's endpoints, under the --base-path that Fester is mounted at
+func endpointURL(server, endpoint string) string {
+	return strings.TrimRight(server, "/") + NormalizeBasePath(basePath) + "/" + strings.TrimLeft(endpoint, "/")
+}
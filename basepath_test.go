@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEndpointURL tests building endpoint URLs with and without a base path, whatever slashes they have
+func TestEndpointURL(t *testing.T) {
+	defer func() { basePath = "" }()
+
+	tests := []struct {
+		server   string
+		basePath string
+		url      string
+	}{
+		{"https://host", "", "https://host/collections"},
+		{"https://host/", "", "https://host/collections"},
+		{"https://host", "/", "https://host/collections"},
+		{"https://host", "/fester", "https://host/fester/collections"},
+		{"https://host/", "fester/", "https://host/fester/collections"},
+		{"https://host//", "//fester//", "https://host/fester/collections"},
+		{"https://host", " /iiif//fester/ ", "https://host/iiif/fester/collections"},
+		{"https://host/ingest", "fester", "https://host/ingest/fester/collections"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.server+" "+tc.basePath, func(t *testing.T) {
+			basePath = tc.basePath
+			assert.Equal(t, tc.url, collectionsURL(tc.server))
+		})
+	}
+
+	basePath = "fester"
+	assert.Equal(t, "https://host/fester/fester/status", statusURL("https://host/"))
+}
+
+// TestValidateBasePath tests that a base path can't be a URL or carry a query
+func TestValidateBasePath(t *testing.T) {
+	assert.NoError(t, ValidateBasePath(""))
+	assert.NoError(t, ValidateBasePath("/fester/"))
+	assert.Error(t, ValidateBasePath("https://host/fester"))
+	assert.Error(t, ValidateBasePath("/fester?debug=true"))
+	assert.Error(t, ValidateBasePath("/fester#top"))
+}
+
+// TestFesterStatusBasePath tests that the status check goes to Fester under its base path
+func TestFesterStatusBasePath(t *testing.T) {
+	defer func() { basePath = "" }()
+	basePath = "/fester/"
+
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	statusCode, err := FesterStatus(statusURL(ts.URL + "/"))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, []string{"/fester/fester/status"}, paths)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// defaultCanonicalColumns are the columns a canonical header starts with when the schema doesn't list its own
+// order, in the order our spreadsheets have them
+var defaultCanonicalColumns = []string{itemARKColumn, parentARKColumn, objectTypeColumn}
+
+// canonicalColumns returns the canonical column order: the schema's own, or the identifying columns followed
+// by the columns each object type requires and may have
+func canonicalColumns(schema *Schema) []string {
+	if schema != nil && len(schema.Columns) > 0 {
+		return schema.Columns
+	}
+
+	columns := slices.Clone(defaultCanonicalColumns)
+	if schema == nil {
+		return columns
+	}
+	for _, objectType := range []string{objectTypeCollection, objectTypeWork, objectTypePage} {
+		typeSchema, _ := schema.forType(objectType)
+		for _, column := range append(slices.Clone(typeSchema.Required), typeSchema.Optional...) {
+			if !slices.Contains(columns, column) {
+				columns = append(columns, column)
+			}
+		}
+	}
+	return columns
+}
+
+// fillableColumn checks whether a canonical column that's missing from a CSV can be added to it empty; the
+// columns Fester reads itself and the ones the schema requires are left for validation to report instead
+func fillableColumn(schema *Schema, column string) bool {
+	if slices.Contains(defaultCanonicalColumns, column) || column == manifestURLColumn {
+		return false
+	}
+	if schema == nil {
+		return false
+	}
+	for _, typeSchema := range schema.Types {
+		if slices.Contains(typeSchema.Required, column) {
+			return false
+		}
+	}
+	return true
+}
+
+// CanonicalHeader reorders a CSV's columns to the canonical order, adding the optional columns it's missing
+// with empty values; canonical columns are named as the canonical order names them, columns outside it keep
+// their order after the canonical ones, and no values are changed
+func CanonicalHeader(schema *Schema) CSVTransform {
+	return func(records [][]string) ([][]string, error) {
+		if len(records) == 0 {
+			return records, nil
+		}
+
+		header := records[0]
+		placed := make([]bool, len(header))
+		var order []int
+		var newHeader []string
+		for _, column := range canonicalColumns(schema) {
+			index := columnIndex(header, column)
+			switch {
+			case index != -1 && !placed[index]:
+				placed[index] = true
+				order = append(order, index)
+				newHeader = append(newHeader, column)
+			case index == -1 && fillableColumn(schema, column):
+				order = append(order, -1)
+				newHeader = append(newHeader, column)
+			}
+		}
+		for index, column := range header {
+			if !placed[index] {
+				order = append(order, index)
+				newHeader = append(newHeader, strings.TrimPrefix(column, "\ufeff"))
+			}
+		}
+
+		reordered := make([][]string, len(records))
+		reordered[0] = newHeader
+		for rowIndex, row := range records[1:] {
+			newRow := make([]string, len(order), max(len(order), len(row)))
+			for position, index := range order {
+				if index != -1 && index < len(row) {
+					newRow[position] = row[index]
+				}
+			}
+			// Cells past the end of the header are kept at the end of the row
+			if len(row) > len(header) {
+				newRow = append(newRow, row[len(header):]...)
+			}
+			reordered[rowIndex+1] = newRow
+		}
+		return reordered, nil
+	}
+}
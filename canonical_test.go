@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCanonicalHeaderReorders tests that columns are put in the schema's order, with the columns outside it
+// kept after it in their original order and every value moved with its column
+func TestCanonicalHeaderReorders(t *testing.T) {
+	schema, err := LoadSchema(TestSchemaDir + "/columns.yaml")
+	if !assert.Nil(t, err) {
+		return
+	}
+	records := [][]string{
+		{"\ufeffProject Name", "Title", "Object Type", "Item ARK", "Item Sequence", "Notes", "File Name", "Parent ARK",
+			"Description.note"},
+		{"Papers", " Letter ", "Work", "ark:/21198/zz0002", "", "fragile", "work.tif", "ark:/21198/zz0001", "Undated"},
+	}
+
+	reordered, err := CanonicalHeader(schema)(records)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"Item ARK", "Parent ARK", "Object Type", "Title", "File Name", "Item Sequence", "Description.note",
+			"Project Name", "Notes"},
+		{"ark:/21198/zz0002", "ark:/21198/zz0001", "Work", " Letter ", "work.tif", "", "Undated", "Papers", "fragile"},
+	}, reordered)
+}
+
+// TestCanonicalHeaderAddsMissingColumns tests that the optional columns a CSV is missing are added empty, but
+// required columns and the ones Fester reads itself are left missing for validation to report
+func TestCanonicalHeaderAddsMissingColumns(t *testing.T) {
+	schema, err := LoadSchema(TestSchemaDir + "/columns.yaml")
+	if !assert.Nil(t, err) {
+		return
+	}
+	records := [][]string{
+		{"Object Type", "Item ARK"},
+		{"Work", "ark:/21198/zz0002"},
+		{"Page"},
+	}
+
+	reordered, err := CanonicalHeader(schema)(records)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"Item ARK", "Object Type", "File Name", "Item Sequence", "Description.note"},
+		{"ark:/21198/zz0002", "Work", "", "", ""},
+		{"", "Page", "", "", ""},
+	}, reordered)
+}
+
+// TestCanonicalHeaderWithoutSchema tests that without a schema, only the identifying columns are moved to the front
+func TestCanonicalHeaderWithoutSchema(t *testing.T) {
+	records := [][]string{
+		{"Title", "Object Type", "Parent ARK", "Item ARK"},
+		{"Letter", "Work", "ark:/21198/zz0001", "ark:/21198/zz0002", "extra"},
+	}
+
+	reordered, err := CanonicalHeader(nil)(records)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"Item ARK", "Parent ARK", "Object Type", "Title"},
+		{"ark:/21198/zz0002", "ark:/21198/zz0001", "Work", "Letter", "extra"},
+	}, reordered)
+}
+
+// TestCanonicalColumnsFromTypes tests that without a column order, the schema's columns follow the identifying
+// ones, collection columns first
+func TestCanonicalColumnsFromTypes(t *testing.T) {
+	schema, err := LoadSchema(TestSchemaDir + "/schema.yaml")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, []string{"Item ARK", "Parent ARK", "Object Type", "Title", "File Name", "Rights.statementLocal",
+		"Date.creation", "Description.note", "Item Sequence"}, canonicalColumns(schema))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// maxLoggedCellLength is the number of bytes of a long cell included in its warning
+const maxLoggedCellLength int = 100
+
+// FindLongCells finds the cells, header included, that are longer than maxLength characters
+func FindLongCells(records [][]string, maxLength int) []ValidationProblem {
+	if len(records) == 0 || maxLength <= 0 {
+		return nil
+	}
+
+	var problems []ValidationProblem
+	header := records[0]
+	for rowIndex, row := range records {
+		for index, value := range row {
+			length := utf8.RuneCountInString(value)
+			if length <= maxLength {
+				continue
+			}
+
+			column := fmt.Sprintf("column %d", index+1)
+			if index < len(header) && rowIndex > 0 {
+				column = header[index]
+			}
+			reason := fmt.Sprintf("%d characters, more than the %d allowed", length, maxLength)
+			problems = append(problems, ValidationProblem{rowIndex + 1, column, truncate(value, maxLoggedCellLength), reason})
+		}
+	}
+	return problems
+}
+
+// warnAboutLongCells warns about the cells Fester may reject or truncate; they're not errors, since Fester
+// takes most of them as they are
+func warnAboutLongCells(path string, records [][]string, maxLength int) {
+	longCells := FindLongCells(records, maxLength)
+	if len(longCells) == 0 {
+		return
+	}
+
+	for _, longCell := range longCells {
+		Logger.Warn("Cell longer than the maximum cell length",
+			zap.String("path", path),
+			zap.Int("row", longCell.Row),
+			zap.String("column", longCell.Column),
+			zap.String("value", longCell.Value),
+			zap.String("reason", longCell.Reason))
+	}
+	fmt.Fprintf(humanOutput(), "Warning: %d cells in %s are longer than %d characters and may be truncated "+
+		"by Fester (see log)\n", len(longCells), filepath.Base(path), maxLength)
+}
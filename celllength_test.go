@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFindLongCells tests that only the cells over the maximum are reported, counting characters rather than bytes
+func TestFindLongCells(t *testing.T) {
+	description := "<p>" + strings.Repeat("Letter to Mabel. ", 10) + "</p>"
+	records := [][]string{
+		{"Item ARK", "Title", "Description.note"},
+		{"ark:/21198/zz0001", "Édouard Manet, né à Paris", ""},
+		{"ark:/21198/zz0002", "Letters", description, "an extra cell past the header"},
+	}
+
+	problems := FindLongCells(records, 25)
+	assert.Equal(t, []ValidationProblem{
+		{3, "Description.note", truncate(description, maxLoggedCellLength), "177 characters, more than the 25 allowed"},
+		{3, "column 4", "an extra cell past the header", "29 characters, more than the 25 allowed"},
+	}, problems)
+	assert.Empty(t, FindLongCells(records, 0))
+}
+
+// TestValidateFileLongCells tests that an over-length cell is warned about, without failing validation
+func TestValidateFileLongCells(t *testing.T) {
+	logger, sink := createLogger()
+	Logger = logger
+
+	var problems []ValidationProblem
+	var err error
+	path := writeTestCSV(t, "long.csv", "Item ARK,Title\nark:/21198/zz0001,A title that goes on for far too long\n")
+	output := captureStdout(t, func() { problems, err = ValidateFile(path, Config{MaxCellLength: 20}) })
+
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+	assert.Contains(t, output, "Warning: 1 cells in long.csv are longer than 20 characters")
+	assert.Contains(t, sink.String(), `"M":"Cell longer than the maximum cell length"`)
+	assert.Contains(t, sink.String(), `"row":2,"column":"Title"`)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// defaultPartContentType is the content type of the uploaded CSV's part of the multipart request
+const defaultPartContentType string = "text/csv"
+
+// quoteEscaper escapes a filename for a Content-Disposition header, as mime/multipart does
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// maxRedirects is the number of redirects followed before an upload is given up, as with http.DefaultClient
+const maxRedirects int = 10
+
+// defaultMaxIdleConns is the number of idle connections kept for reuse, as with http.DefaultTransport
+const defaultMaxIdleConns int = 100
+
+// uploadTransport is shared by the clients that upload CSVs, so connections to Fester are reused across files;
+// when it's nil, they use http.DefaultTransport
+var uploadTransport http.RoundTripper
+
+// ValidateUploadName validates the --upload-name, which names a single upload, so only one file can be given
+func ValidateUploadName(name string, sources []string) error {
+	switch {
+	case name == "":
+		return nil
+	case filepath.Base(name) != name:
+		return fmt.Errorf("invalid upload name %q: expected a filename without a directory", name)
+	case len(sources) != 1:
+		return errors.New("--upload-name names a single upload, so exactly one file must be given")
+	default:
+		return nil
+	}
+}
+
+// multipartFilename returns the filename an upload is sent to Fester under: the --upload-name override if there
+// is one, or else the upload's base name, so that local directories aren't sent to the server
+func multipartFilename(uploadName, override string) string {
+	if override != "" {
+		return override
+	}
+	return filepath.Base(uploadName)
+}
+
+// ValidatePartContentType validates the content type of the uploaded CSV's part of the request
+func ValidatePartContentType(contentType string) error {
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		return fmt.Errorf("invalid part content type %q: %w", contentType, err)
+	}
+	return nil
+}
+
+// filePartHeader returns the header of the uploaded CSV's part of the multipart request; it's CreateFormFile's
+// header with the content type set, since CreateFormFile always sends application/octet-stream
+func filePartHeader(filename, contentType string) textproto.MIMEHeader {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, quoteEscaper.Replace(filename)))
+	header.Set("Content-Type", contentType)
+	return header
+}
+
+// ValidateConnectionPool validates the limits on the connections kept to Fester
+func ValidateConnectionPool(maxIdle, maxPerHost int) error {
+	switch {
+	case maxIdle < 0:
+		return fmt.Errorf("invalid --max-idle-conns %d: expected 0 (no limit) or more", maxIdle)
+	case maxPerHost < 0:
+		return fmt.Errorf("invalid --max-conns-per-host %d: expected 0 (no limit) or more", maxPerHost)
+	default:
+		return nil
+	}
+}
+
+// newUploadTransport creates the transport that uploads CSVs to Fester, tuning Go's default transport's
+// connection pool; as uploads all go to the same host, as many idle connections are kept to it as may be open
+func newUploadTransport(maxIdle, maxPerHost int, disableKeepAlives bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = maxIdle
+	transport.MaxConnsPerHost = maxPerHost
+	transport.DisableKeepAlives = disableKeepAlives
+	if maxPerHost > transport.MaxIdleConnsPerHost {
+		transport.MaxIdleConnsPerHost = maxPerHost
+	}
+	return transport
+}
+
+// newUploadClient creates the HTTP client that uploads CSVs to Fester
+func newUploadClient() *http.Client {
+	return &http.Client{Transport: uploadTransport, CheckRedirect: checkRedirect}
+}
+
+// checkRedirect decides whether to follow a redirect from Fester, re-attaching the credentials that Go drops when
+// a redirect moves to one of the configured Fester servers, unless it would send them over plain HTTP after HTTPS
+func checkRedirect(request *http.Request, via []*http.Request) error {
+	if !followRedirects {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= maxRedirects {
+		return errors.New("stopped after too many redirects")
+	}
+
+	original := via[0]
+	Logger.Debug("Following redirect",
+		zap.String("from", via[len(via)-1].URL.String()),
+		zap.String("to", request.URL.String()),
+		zap.Int("status_code", request.Response.StatusCode),
+		zap.String("method", request.Method))
+
+	authorization := original.Header.Get("Authorization")
+	downgraded := original.URL.Scheme == "https" && request.URL.Scheme != "https"
+	if authorization != "" && request.Header.Get("Authorization") == "" && !downgraded &&
+		isTrustedRedirect(original.URL, request.URL) {
+		request.Header.Set("Authorization", authorization)
+	}
+	return nil
+}
+
+// isTrustedRedirect reports whether a redirect stays on the original host or goes to one of the --server hosts,
+// the only places the credentials are meant for
+func isTrustedRedirect(original, target *url.URL) bool {
+	if target.Host == original.Host {
+		return true
+	}
+	for _, server := range servers {
+		if parsed, err := url.Parse(server); err == nil && parsed.Host == target.Host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newRedirectingFester starts a server that 307-redirects uploads to a second handler, which records what it gets
+func newRedirectingFester(t *testing.T, method, authorization *string) *httptest.Server {
+	redirecting, _ := newRedirectingFesterVia(t, method, authorization, "")
+	return redirecting
+}
+
+// newRedirectingFesterVia is newRedirectingFester with the second handler addressed by another hostname, so that
+// the redirects leave the original host; it also returns the URL the uploads are redirected to
+func newRedirectingFesterVia(t *testing.T, method, authorization *string, hostname string) (*httptest.Server,
+	string) {
+	landing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*method, *authorization = r.Method, r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(landing.Close)
+
+	location := landing.URL
+	if hostname != "" {
+		location = "http://" + hostname + landing.URL[strings.LastIndex(landing.URL, ":"):]
+	}
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, location+r.URL.Path, http.StatusTemporaryRedirect)
+	}))
+	t.Cleanup(redirecting.Close)
+	return redirecting, location
+}
+
+// TestPostCSVFollowsRedirects tests that a redirected upload is still a POST with its credentials
+func TestPostCSVFollowsRedirects(t *testing.T) {
+	logger, sink := createLogger()
+	Logger = logger
+	defer func() { followRedirects, authToken = true, "" }()
+	followRedirects, authToken = true, "abc123"
+
+	var method, authorization string
+	ts := newRedirectingFester(t, &method, &authorization)
+
+	response, _, err := postCSV(context.Background(), "test.csv", strings.NewReader("Item ARK\n"), ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Equal(t, http.MethodPost, method)
+	assert.Equal(t, "Bearer abc123", authorization)
+	assert.Contains(t, sink.String(), "Following redirect")
+}
+
+// TestPostCSVRedirectToAnotherHost tests that the credentials don't follow a redirect to another host unless it's
+// one of the configured servers
+func TestPostCSVRedirectToAnotherHost(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+	defer func(configured []string) { followRedirects, authToken, servers = true, "", configured }(servers)
+	followRedirects, authToken, servers = true, "abc123", []string{defaultServer}
+
+	var method, authorization string
+	ts, location := newRedirectingFesterVia(t, &method, &authorization, "localhost")
+
+	response, _, err := postCSV(context.Background(), "test.csv", strings.NewReader("Item ARK\n"), ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Equal(t, http.MethodPost, method)
+	assert.Empty(t, authorization)
+
+	servers = append(servers, location)
+	_, _, err = postCSV(context.Background(), "test.csv", strings.NewReader("Item ARK\n"), ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer abc123", authorization)
+}
+
+// TestPostCSVWithoutFollowingRedirects tests that redirects are returned as they are when following is disabled
+func TestPostCSVWithoutFollowingRedirects(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+	defer func() { followRedirects = true }()
+	followRedirects = false
+
+	var method, authorization string
+	ts := newRedirectingFester(t, &method, &authorization)
+
+	response, _, err := postCSV(context.Background(), "test.csv", strings.NewReader("Item ARK\n"), ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, response.StatusCode)
+	assert.Empty(t, method)
+}
+
+// newPartRecordingFester starts a Fester stub that records the Content-Disposition and Content-Type headers of
+// the uploaded file part
+func newPartRecordingFester(t *testing.T, disposition, contentType *string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			if part.FormName() == "file" {
+				*disposition, *contentType = part.Header.Get("Content-Disposition"), part.Header.Get("Content-Type")
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// TestUploadCSVMultipartFilename tests that only the base name of the file, or the --upload-name, is sent to Fester
+func TestUploadCSVMultipartFilename(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	absPath, err := filepath.Abs(TestDirGzipped + "/chase.csv.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var disposition, contentType string
+	ts := newPartRecordingFester(t, &disposition, &contentType)
+
+	_, _, err = uploadCSV(context.Background(), absPath, ts.URL+"/collections", UploadOptions{IIIFVersion: "2"})
+	assert.Nil(t, err)
+	assert.Equal(t, `form-data; name="file"; filename="chase.csv"`, disposition)
+
+	_, _, err = uploadCSV(context.Background(), absPath, ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", UploadName: "chase-2024.csv"})
+	assert.Nil(t, err)
+	assert.Equal(t, `form-data; name="file"; filename="chase-2024.csv"`, disposition)
+}
+
+// TestValidateUploadName tests that the upload name is a filename and only given for a single file
+func TestValidateUploadName(t *testing.T) {
+	assert.Nil(t, ValidateUploadName("", []string{"one.csv", "two.csv"}))
+	assert.Nil(t, ValidateUploadName("batch.csv", []string{"one.csv"}))
+	assert.NotNil(t, ValidateUploadName("uploads/batch.csv", []string{"one.csv"}))
+	assert.NotNil(t, ValidateUploadName("batch.csv", []string{"one.csv", "two.csv"}))
+}
+
+// TestUploadCSVPartContentType tests that the file part is sent as text/csv, or as the --part-content-type
+func TestUploadCSVPartContentType(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	var disposition, contentType string
+	ts := newPartRecordingFester(t, &disposition, &contentType)
+
+	_, _, err := uploadCSV(context.Background(), TestDirUnFester+"/ballin.csv", ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2"})
+	assert.Nil(t, err)
+	assert.Equal(t, "text/csv", contentType)
+
+	_, _, err = uploadCSV(context.Background(), TestDirUnFester+"/ballin.csv", ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", PartContentType: "text/csv; charset=utf-8"})
+	assert.Nil(t, err)
+	assert.Equal(t, "text/csv; charset=utf-8", contentType)
+}
+
+// TestValidatePartContentType tests that the part content type must be a media type
+func TestValidatePartContentType(t *testing.T) {
+	assert.Nil(t, ValidatePartContentType("text/csv"))
+	assert.Nil(t, ValidatePartContentType("application/octet-stream"))
+	assert.NotNil(t, ValidatePartContentType(""))
+	assert.NotNil(t, ValidatePartContentType("text/csv\r\nX-Injected: yes"))
+}
+
+// TestFilePartHeader tests that quotes in the filename are escaped
+func TestFilePartHeader(t *testing.T) {
+	header := filePartHeader(`my "best" batch.csv`, "text/csv")
+	assert.Equal(t, `form-data; name="file"; filename="my \"best\" batch.csv"`, header.Get("Content-Disposition"))
+}
+
+// TestNewUploadTransport tests that the transport's connection pool is configured as requested
+func TestNewUploadTransport(t *testing.T) {
+	transport := newUploadTransport(20, 8, true)
+
+	assert.Equal(t, 20, transport.MaxIdleConns)
+	assert.Equal(t, 8, transport.MaxConnsPerHost)
+	assert.Equal(t, 8, transport.MaxIdleConnsPerHost)
+	assert.True(t, transport.DisableKeepAlives)
+}
+
+// TestNewUploadTransportDefaults tests that the default flags leave Go's default transport as it is
+func TestNewUploadTransportDefaults(t *testing.T) {
+	defaults := http.DefaultTransport.(*http.Transport)
+	transport := newUploadTransport(defaultMaxIdleConns, 0, false)
+
+	assert.Equal(t, defaults.MaxIdleConns, transport.MaxIdleConns)
+	assert.Equal(t, defaults.MaxConnsPerHost, transport.MaxConnsPerHost)
+	assert.Equal(t, defaults.MaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	assert.False(t, transport.DisableKeepAlives)
+}
+
+// TestNewUploadClientSharesTransport tests that every upload client uses the shared transport
+func TestNewUploadClientSharesTransport(t *testing.T) {
+	uploadTransport = newUploadTransport(defaultMaxIdleConns, 4, false)
+	defer func() { uploadTransport = nil }()
+
+	assert.Same(t, uploadTransport, newUploadClient().Transport)
+	assert.Same(t, newUploadClient().Transport, newUploadClient().Transport)
+}
+
+// TestValidateConnectionPool tests that negative connection limits are rejected
+func TestValidateConnectionPool(t *testing.T) {
+	assert.NoError(t, ValidateConnectionPool(defaultMaxIdleConns, 0))
+	assert.Error(t, ValidateConnectionPool(-1, 0))
+	assert.Error(t, ValidateConnectionPool(defaultMaxIdleConns, -1))
+}
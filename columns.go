@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// ColumnSummary is a column of a CSV's header along with how many of its rows have a value in it
+type ColumnSummary struct {
+	Index    int
+	Name     string
+	NonEmpty int
+}
+
+// Lists the columns of a CSV
+var columnsCmd = &cobra.Command{
+	Use:   "columns <file.csv>",
+	Short: "List the columns of a CSV and how many rows have a value in each, without uploading it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		if delimiter, err = ParseDelimiter(delimiterValue); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		summaries, rows, err := SummarizeColumns(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not read %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		if err := writeColumns(os.Stdout, summaries, rows); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	},
+}
+
+// SummarizeColumns reads a CSV and returns its header's columns, numbered from 1, with a count of the rows
+// that have a value in each, along with the number of rows below the header; cells holding only whitespace
+// count as empty
+func SummarizeColumns(path string) ([]ColumnSummary, int, error) {
+	records, err := readCSVRecords(path)
+	if err != nil {
+		return nil, 0, err
+	} else if len(records) == 0 {
+		return nil, 0, fmt.Errorf("%s is empty", csvName(path))
+	}
+
+	summaries := make([]ColumnSummary, len(records[0]))
+	for index, column := range records[0] {
+		summaries[index] = ColumnSummary{Index: index + 1, Name: strings.TrimPrefix(column, "\ufeff")}
+	}
+	for _, record := range records[1:] {
+		for index, value := range record {
+			if index < len(summaries) && strings.TrimSpace(value) != "" {
+				summaries[index].NonEmpty++
+			}
+		}
+	}
+	return summaries, len(records) - 1, nil
+}
+
+// writeColumns writes the columns as a table, followed by the number of rows
+func writeColumns(w io.Writer, summaries []ColumnSummary, rows int) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "INDEX\tCOLUMN\tNON-EMPTY")
+	for _, summary := range summaries {
+		// Quote names that would otherwise hide stray whitespace or be blank
+		name := summary.Name
+		if name == "" || strings.TrimSpace(name) != name {
+			name = fmt.Sprintf("%q", name)
+		}
+		fmt.Fprintf(table, "%d\t%s\t%d\n", summary.Index, name, summary.NonEmpty)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d rows\n", rows)
+	return err
+}
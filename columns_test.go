@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSummarizeColumns tests listing the columns of a fixture with a known header
+func TestSummarizeColumns(t *testing.T) {
+	summaries, rows, err := SummarizeColumns(TestDirUnFester + "/ballin.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 4, rows)
+	if assert.Len(t, summaries, 34) {
+		assert.Equal(t, ColumnSummary{Index: 1, Name: "Project Name", NonEmpty: 4}, summaries[0])
+		assert.Equal(t, ColumnSummary{Index: 3, Name: "Parent ARK", NonEmpty: 3}, summaries[2])
+		assert.Equal(t, ColumnSummary{Index: 8, Name: "Item Sequence", NonEmpty: 0}, summaries[7])
+		assert.Equal(t, ColumnSummary{Index: 34, Name: "IIIF Manifest URL", NonEmpty: 4}, summaries[33])
+	}
+}
+
+// TestSummarizeColumnsRagged tests that blank cells and cells past the end of the header aren't counted
+func TestSummarizeColumnsRagged(t *testing.T) {
+	path := writeTestCSV(t, "ragged.csv", "\ufeffItem ARK,Title\nark:/1,  \nark:/2,Map,extra\n")
+
+	summaries, rows, err := SummarizeColumns(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, rows)
+	assert.Equal(t, []ColumnSummary{{1, "Item ARK", 2}, {2, "Title", 1}}, summaries)
+
+	_, _, err = SummarizeColumns(writeTestCSV(t, "empty.csv", ""))
+	assert.Error(t, err)
+}
+
+// TestWriteColumns tests that the columns are written as a table, quoting names with stray whitespace
+func TestWriteColumns(t *testing.T) {
+	var buffer bytes.Buffer
+	assert.NoError(t, writeColumns(&buffer, []ColumnSummary{{1, "Item ARK", 2}, {2, "Title ", 0}}, 2))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Equal(t, []string{
+		"INDEX  COLUMN    NON-EMPTY",
+		"1      Item ARK  2",
+		"2      \"Title \"  0",
+		"2 rows",
+	}, lines)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// compareTimeout bounds how long fetching a collection for comparison waits for the server
+const compareTimeout = 30 * time.Second
+
+// RemoteWork is a work listed in a collection that Fester holds
+type RemoteWork struct {
+	ItemARK     string
+	ManifestURL string
+	Label       string
+}
+
+// ChangedWork is a work that's both in a local CSV and in Fester's collection, but differs between them
+type ChangedWork struct {
+	ItemARK string
+	Column  string
+	Local   string
+	Remote  string
+}
+
+// CollectionDiff is how a local CSV's works differ from those in Fester's collection
+type CollectionDiff struct {
+	// Item ARKs of the works only in the local CSV
+	Added []string
+	// Item ARKs of the works only in Fester's collection
+	Removed []string
+	Changed []ChangedWork
+}
+
+// Empty checks whether the local CSV and Fester's collection hold the same works
+func (diff CollectionDiff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
+
+var compareServer string
+var compareCollection string
+
+// Compares a CSV with the collection Fester holds
+var compareCmd = &cobra.Command{
+	Use:   "compare <file.csv>",
+	Short: "Compare a CSV's works with those in the collection Fester holds, without uploading it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		if delimiter, err = ParseDelimiter(delimiterValue); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := LoadEnvFile(envFile, cmd.Flags().Changed("env-file")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if authToken == "" {
+			authToken = os.Getenv(tokenEnvVar)
+		}
+
+		records, err := readCSVRecords(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not read %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		collectionARK := compareCollection
+		if collectionARK == "" {
+			if collectionARK, err = CollectionARK(records); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v; give the collection's ARK with --collection\n", args[0], err)
+				os.Exit(1)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), compareTimeout)
+		defer cancel()
+		works, err := FetchCollection(ctx, newUploadClient(), compareServer, collectionARK, authToken)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not fetch collection %s: %v\n", collectionARK, err)
+			os.Exit(1)
+		}
+
+		if err := writeCollectionDiff(os.Stdout, collectionARK, CompareCollection(records, works)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	},
+}
+
+// CollectionARK returns the Item ARK of the one collection row in a CSV
+func CollectionARK(records [][]string) (string, error) {
+	if len(records) == 0 {
+		return "", errors.New("CSV is empty")
+	}
+	itemIndex := columnIndex(records[0], itemARKColumn)
+	typeIndex := columnIndex(records[0], objectTypeColumn)
+
+	var arks []string
+	for _, row := range records[1:] {
+		if normalizeObjectType(cell(row, typeIndex)) == objectTypeCollection && cell(row, itemIndex) != "" {
+			arks = append(arks, cell(row, itemIndex))
+		}
+	}
+	switch len(arks) {
+	case 0:
+		return "", errors.New("CSV has no collection row")
+	case 1:
+		return arks[0], nil
+	default:
+		return "", fmt.Errorf("CSV has %d collection rows", len(arks))
+	}
+}
+
+// collectionManifest holds the parts of a IIIF collection that list its works, in either version of the
+// Presentation API: version 2 lists them under manifests and version 3 under items
+type collectionManifest struct {
+	Manifests []struct {
+		ID    string          `json:"@id"`
+		Label json.RawMessage `json:"label"`
+	} `json:"manifests"`
+	Items []struct {
+		ID    string          `json:"id"`
+		Type  string          `json:"type"`
+		Label json.RawMessage `json:"label"`
+	} `json:"items"`
+}
+
+// FetchCollection gets the collection Fester holds for an ARK and returns the works it lists
+func FetchCollection(ctx context.Context, client *http.Client, server, collectionARK, token string) ([]RemoteWork,
+	error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		endpointURL(server, "/collections/"+url.PathEscape(collectionARK)), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", userAgent())
+	request.Header.Set("Accept", "application/json")
+	if err := authenticate(request, token); err != nil {
+		return nil, err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	} else if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", response.Status)
+	}
+
+	var manifest collectionManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("could not read collection: %w", err)
+	}
+
+	var works []RemoteWork
+	for _, listed := range manifest.Manifests {
+		works = append(works, RemoteWork{manifestARK(listed.ID), listed.ID, manifestLabel(listed.Label)})
+	}
+	for _, item := range manifest.Items {
+		if item.Type == "Manifest" {
+			works = append(works, RemoteWork{manifestARK(item.ID), item.ID, manifestLabel(item.Label)})
+		}
+	}
+	return works, nil
+}
+
+// manifestARK returns the ARK a Fester manifest URL is made from, which is the path segment before /manifest,
+// or the URL itself if it isn't in that form
+func manifestARK(manifestURL string) string {
+	parsedURL, err := url.Parse(manifestURL)
+	if err != nil {
+		return manifestURL
+	}
+	segments := strings.Split(strings.TrimSuffix(parsedURL.EscapedPath(), "/"), "/")
+	if len(segments) < 2 || segments[len(segments)-1] != "manifest" {
+		return manifestURL
+	}
+	ark, err := url.PathUnescape(segments[len(segments)-2])
+	if err != nil {
+		return manifestURL
+	}
+	return ark
+}
+
+// manifestLabel reads a manifest's label, which is a string in version 2 of the Presentation API and a map of
+// languages to strings in version 3
+func manifestLabel(raw json.RawMessage) string {
+	var label string
+	if json.Unmarshal(raw, &label) == nil {
+		return label
+	}
+
+	var languages map[string][]string
+	if json.Unmarshal(raw, &languages) != nil || len(languages) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(languages))
+	for key := range languages {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return strings.Join(languages[keys[0]], " ")
+}
+
+// CompareCollection compares the work rows of a CSV with the works in Fester's collection, matching them on
+// their Item ARKs; a work's title and manifest URL are only compared when the CSV has them
+func CompareCollection(records [][]string, works []RemoteWork) CollectionDiff {
+	var diff CollectionDiff
+	remote := make(map[string]RemoteWork, len(works))
+	for _, work := range works {
+		remote[work.ItemARK] = work
+	}
+	if len(records) == 0 {
+		records = [][]string{nil}
+	}
+
+	header := records[0]
+	itemIndex := columnIndex(header, itemARKColumn)
+	typeIndex := columnIndex(header, objectTypeColumn)
+	titleIndex := columnIndex(header, titleColumn)
+	urlIndex := columnIndex(header, manifestURLColumn)
+
+	local := map[string]bool{}
+	for _, row := range records[1:] {
+		itemARK := cell(row, itemIndex)
+		if normalizeObjectType(cell(row, typeIndex)) != objectTypeWork || itemARK == "" || local[itemARK] {
+			continue
+		}
+		local[itemARK] = true
+
+		work, found := remote[itemARK]
+		if !found {
+			diff.Added = append(diff.Added, itemARK)
+			continue
+		}
+		if title := cell(row, titleIndex); titleIndex != -1 && title != work.Label {
+			diff.Changed = append(diff.Changed, ChangedWork{itemARK, titleColumn, title, work.Label})
+		}
+		if manifestURL := cell(row, urlIndex); manifestURL != "" && manifestURL != work.ManifestURL {
+			diff.Changed = append(diff.Changed, ChangedWork{itemARK, manifestURLColumn, manifestURL, work.ManifestURL})
+		}
+	}
+
+	for _, work := range works {
+		if !local[work.ItemARK] {
+			diff.Removed = append(diff.Removed, work.ItemARK)
+		}
+	}
+	return diff
+}
+
+// writeCollectionDiff writes how a CSV differs from Fester's collection, with added works marked +, removed
+// works -, and changed works ~
+func writeCollectionDiff(w io.Writer, collectionARK string, diff CollectionDiff) error {
+	if diff.Empty() {
+		_, err := fmt.Fprintf(w, "The CSV's works match collection %s\n", collectionARK)
+		return err
+	}
+
+	fmt.Fprintf(w, "Comparing with collection %s: %d added, %d removed, %d changed\n", collectionARK,
+		len(diff.Added), len(diff.Removed), len(diff.Changed))
+	for _, itemARK := range diff.Added {
+		fmt.Fprintf(w, "+ %s\n", itemARK)
+	}
+	for _, itemARK := range diff.Removed {
+		fmt.Fprintf(w, "- %s\n", itemARK)
+	}
+	for _, changed := range diff.Changed {
+		fmt.Fprintf(w, "~ %s %s: %q on the server, %q in the CSV\n", changed.ItemARK, changed.Column, changed.Remote,
+			changed.Local)
+	}
+	return nil
+}
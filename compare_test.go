@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCompareCSV is a local CSV of a collection of three works
+const testCompareCSV = `Item ARK,Parent ARK,Object Type,Title
+ark:/21198/zz0001,,Collection,Papers
+ark:/21198/zz0002,ark:/21198/zz0001,Work,Letter
+ark:/21198/zz0003,ark:/21198/zz0001,Work,Map
+ark:/21198/zz0004,ark:/21198/zz0001,Work,Diary
+ark:/21198/zz0005,ark:/21198/zz0004,Page,Page 1
+`
+
+// testCompareCollection is the collection Fester holds for testCompareCSV, in which the map has another title,
+// the diary is missing, and a photograph has been added
+const testCompareCollection = `{
+  "@id": "https://iiif.example.org/collections/ark%3A%2F21198%2Fzz0001",
+  "@type": "sc:Collection",
+  "manifests": [
+    {"@id": "https://iiif.example.org/ark%3A%2F21198%2Fzz0002/manifest", "label": "Letter"},
+    {"@id": "https://iiif.example.org/ark%3A%2F21198%2Fzz0003/manifest", "label": "Map of Los Angeles"},
+    {"@id": "https://iiif.example.org/ark%3A%2F21198%2Fzz0006/manifest", "label": "Photograph"}
+  ]
+}`
+
+// TestFetchCollection tests fetching the works of a collection with credentials
+func TestFetchCollection(t *testing.T) {
+	var path, authorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, authorization = r.URL.EscapedPath(), r.Header.Get("Authorization")
+		w.Write([]byte(testCompareCollection))
+	}))
+	defer ts.Close()
+
+	works, err := FetchCollection(context.Background(), ts.Client(), ts.URL, "ark:/21198/zz0001", "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "/collections/"+url.PathEscape("ark:/21198/zz0001"), path)
+	assert.Equal(t, "Bearer secret", authorization)
+	assert.Equal(t, []RemoteWork{
+		{"ark:/21198/zz0002", "https://iiif.example.org/ark%3A%2F21198%2Fzz0002/manifest", "Letter"},
+		{"ark:/21198/zz0003", "https://iiif.example.org/ark%3A%2F21198%2Fzz0003/manifest", "Map of Los Angeles"},
+		{"ark:/21198/zz0006", "https://iiif.example.org/ark%3A%2F21198%2Fzz0006/manifest", "Photograph"},
+	}, works)
+}
+
+// TestFetchCollectionV3 tests reading the works of a collection in version 3 of the Presentation API
+func TestFetchCollectionV3(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type": "Collection", "items": [
+			{"id": "https://iiif.example.org/ark%3A%2F21198%2Fzz0002/manifest", "type": "Manifest",
+			 "label": {"none": ["Letter"]}}]}`))
+	}))
+	defer ts.Close()
+
+	works, err := FetchCollection(context.Background(), ts.Client(), ts.URL, "ark:/21198/zz0001", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []RemoteWork{
+		{"ark:/21198/zz0002", "https://iiif.example.org/ark%3A%2F21198%2Fzz0002/manifest", "Letter"},
+	}, works)
+}
+
+// TestFetchCollectionMissing tests that a collection the server doesn't have is an error
+func TestFetchCollectionMissing(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	_, err := FetchCollection(context.Background(), ts.Client(), ts.URL, "ark:/21198/zz0001", "")
+	assert.ErrorContains(t, err, "404")
+}
+
+// TestCompareCollection tests reporting the works added to, removed from, and changed in a local CSV
+func TestCompareCollection(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testCompareCollection))
+	}))
+	defer ts.Close()
+
+	records := readCSVString(t, testCompareCSV)
+	collectionARK, err := CollectionARK(records)
+	assert.NoError(t, err)
+	works, err := FetchCollection(context.Background(), ts.Client(), ts.URL, collectionARK, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := CompareCollection(records, works)
+	assert.Equal(t, CollectionDiff{
+		Added:   []string{"ark:/21198/zz0004"},
+		Removed: []string{"ark:/21198/zz0006"},
+		Changed: []ChangedWork{{"ark:/21198/zz0003", titleColumn, "Map", "Map of Los Angeles"}},
+	}, diff)
+
+	var buffer bytes.Buffer
+	assert.NoError(t, writeCollectionDiff(&buffer, collectionARK, diff))
+	assert.Equal(t, []string{
+		"Comparing with collection ark:/21198/zz0001: 1 added, 1 removed, 1 changed",
+		"+ ark:/21198/zz0004",
+		"- ark:/21198/zz0006",
+		`~ ark:/21198/zz0003 Title: "Map of Los Angeles" on the server, "Map" in the CSV`,
+	}, strings.Split(strings.TrimSpace(buffer.String()), "\n"))
+}
+
+// TestCompareCollectionMatching tests that a CSV matching the collection has no differences
+func TestCompareCollectionMatching(t *testing.T) {
+	records := readCSVString(t, "Item ARK,Object Type,IIIF Manifest URL\nark:/1,Work,https://iiif.example.org/ark%3A%2F1/manifest\n")
+	diff := CompareCollection(records, []RemoteWork{{"ark:/1", "https://iiif.example.org/ark%3A%2F1/manifest", "Letter"}})
+	assert.True(t, diff.Empty())
+
+	var buffer bytes.Buffer
+	assert.NoError(t, writeCollectionDiff(&buffer, "ark:/0", diff))
+	assert.Equal(t, "The CSV's works match collection ark:/0\n", buffer.String())
+}
+
+// TestCollectionARK tests finding the collection row of a CSV
+func TestCollectionARK(t *testing.T) {
+	_, err := CollectionARK(readCSVString(t, testWorksCSV))
+	assert.ErrorContains(t, err, "no collection row")
+
+	_, err = CollectionARK(readCSVString(t, testCompareCSV+"ark:/21198/zz0009,,Collection,Other\n"))
+	assert.ErrorContains(t, err, "2 collection rows")
+}
+
+// TestManifestARK tests reading the ARK from a Fester manifest URL
+func TestManifestARK(t *testing.T) {
+	assert.Equal(t, "ark:/21198/zz0002", manifestARK("https://iiif.example.org/ark%3A%2F21198%2Fzz0002/manifest"))
+	assert.Equal(t, "https://iiif.example.org/other", manifestARK("https://iiif.example.org/other"))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// versionRange is a range of Fester versions, from Min up to but not including Max
+type versionRange struct {
+	Min string
+	Max string
+}
+
+// compatibleFesterVersions are the Fester versions known to work with each IIIF Presentation API version
+var compatibleFesterVersions = map[string]versionRange{
+	"2": {Min: "1.0.0", Max: "2.0.0"},
+	"3": {Min: "1.1.0", Max: "2.0.0"},
+}
+
+// festerVersions caches the version of each server for the rest of the run
+var festerVersions = map[string]string{}
+
+// parseVersion parses a major.minor.patch version, ignoring a leading v and any pre-release or build suffix
+func parseVersion(version string) ([3]int, error) {
+	var parsed [3]int
+	trimmed := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if index := strings.IndexAny(trimmed, "-+"); index != -1 {
+		trimmed = trimmed[:index]
+	}
+
+	parts := strings.Split(trimmed, ".")
+	if len(parts) > 3 || parts[0] == "" {
+		return parsed, fmt.Errorf("invalid version %q", version)
+	}
+	for index, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return parsed, fmt.Errorf("invalid version %q", version)
+		}
+		parsed[index] = number
+	}
+	return parsed, nil
+}
+
+// compareVersions returns -1, 0, or 1 as version a is older than, the same as, or newer than version b
+func compareVersions(a, b [3]int) int {
+	for index := range a {
+		if a[index] != b[index] {
+			if a[index] < b[index] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// CheckCompatibility checks that a Fester version is known to work with the IIIF Presentation API version
+func CheckCompatibility(festerVersion, iiifAPIVersion string) error {
+	supported, found := compatibleFesterVersions[iiifAPIVersion]
+	if !found {
+		return nil
+	}
+
+	version, err := parseVersion(festerVersion)
+	if err != nil {
+		return err
+	}
+	minimum, _ := parseVersion(supported.Min)
+	maximum, _ := parseVersion(supported.Max)
+
+	if compareVersions(version, minimum) < 0 {
+		return fmt.Errorf("Fester %s is too old for IIIF Presentation API %s (needs %s or later)",
+			festerVersion, iiifAPIVersion, supported.Min)
+	}
+	if compareVersions(version, maximum) >= 0 {
+		return fmt.Errorf("Fester %s is newer than festerize supports for IIIF Presentation API %s (needs one before %s)",
+			festerVersion, iiifAPIVersion, supported.Max)
+	}
+	return nil
+}
+
+// FesterVersion returns the version that a server reports on its status endpoint, fetching it once per run
+// with the same credentials and headers as the uploads
+func FesterVersion(ctx context.Context, server string, headers map[string]string) (string, error) {
+	if version, found := festerVersions[server]; found {
+		return version, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL(server), nil)
+	if err != nil {
+		return "", err
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	if err := authenticate(request, authToken); err != nil {
+		return "", err
+	}
+
+	response, err := newUploadClient().Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if !IsSuccessStatus(response.StatusCode) {
+		return "", fmt.Errorf("Fester's status returned %s", response.Status)
+	}
+
+	var status struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&status); err != nil || status.Version == "" {
+		return "", errors.New("Fester's status doesn't include its version")
+	}
+
+	festerVersions[server] = status.Version
+	return status.Version, nil
+}
+
+// checkServerVersion checks that a server's Fester version works with the IIIF Presentation API version
+func checkServerVersion(ctx context.Context, server, iiifAPIVersion string, headers map[string]string) error {
+	version, err := FesterVersion(ctx, server, headers)
+	if err != nil {
+		return err
+	}
+	Logger.Info("Fester version",
+		zap.String("server", server),
+		zap.String("version", version))
+	return CheckCompatibility(version, iiifAPIVersion)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newVersionedFester starts a Fester stub whose status reports the supplied version
+func newVersionedFester(t *testing.T, version string, requests *int32) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok","version":"` + version + `"}`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// TestCheckCompatibility tests Fester versions against the known-compatible ranges
+func TestCheckCompatibility(t *testing.T) {
+	tests := []struct {
+		festerVersion string
+		iiifVersion   string
+		compatible    bool
+	}{
+		{"1.0.0", "2", true},
+		{"v1.4.2-SNAPSHOT", "2", true},
+		{"0.9.9", "2", false},
+		{"1.0.5", "3", false},
+		{"1.1", "3", true},
+		{"2.0.0", "3", false},
+		{"banana", "2", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.festerVersion+" with IIIF "+tc.iiifVersion, func(t *testing.T) {
+			err := CheckCompatibility(tc.festerVersion, tc.iiifVersion)
+			assert.Equal(t, tc.compatible, err == nil, err)
+		})
+	}
+}
+
+// TestFesterVersion tests that a server's version is read from its status and cached
+func TestFesterVersion(t *testing.T) {
+	var requests int32
+	ts := newVersionedFester(t, "1.2.0", &requests)
+	defer delete(festerVersions, ts.URL)
+
+	for range 2 {
+		version, err := FesterVersion(context.Background(), ts.URL, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "1.2.0", version)
+	}
+	assert.Equal(t, int32(1), requests)
+
+	missing := newRunStub(t, http.StatusOK, http.StatusCreated, "")
+	_, err := FesterVersion(context.Background(), missing.URL, nil)
+	assert.NotNil(t, err)
+}
+
+// TestFesterVersionRequest tests that the status is fetched with the run's credentials and headers, and that an
+// error status is reported as such
+func TestFesterVersionRequest(t *testing.T) {
+	defer func() { authToken = "" }()
+	authToken = "abc123"
+
+	var authorization, apiKey string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization, apiKey = r.Header.Get("Authorization"), r.Header.Get("X-Api-Key")
+		if apiKey == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"status":"ok","version":"1.2.0"}`))
+	}))
+	defer ts.Close()
+	defer delete(festerVersions, ts.URL)
+
+	_, err := FesterVersion(context.Background(), ts.URL, nil)
+	assert.ErrorContains(t, err, "403 Forbidden")
+
+	version, err := FesterVersion(context.Background(), ts.URL, map[string]string{"X-Api-Key": "secret"})
+	assert.Nil(t, err)
+	assert.Equal(t, "1.2.0", version)
+	assert.Equal(t, "Bearer abc123", authorization)
+	assert.Equal(t, "secret", apiKey)
+}
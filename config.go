@@ -0,0 +1,158 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// MarshalLogObject logs the run's configuration, with credentials hidden; the request headers can hold API keys
+// under any name, so only the User-Agent is shown
+func (cfg Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	servers := redactAll(cfg.Servers)
+	headers := make(map[string]string, len(cfg.Headers))
+	for key, value := range cfg.Headers {
+		if key != "User-Agent" {
+			value = redactedValue
+		}
+		headers[key] = value
+	}
+
+	zap.Strings("sources", cfg.Sources).AddTo(enc)
+	zap.Strings("servers", servers).AddTo(enc)
+	enc.AddString("output_dir", cfg.OutputDir)
+	enc.AddString("iiif_version", cfg.IIIFVersion)
+	enc.AddString("iiif_host", cfg.IIIFHost)
+	enc.AddBool("metadata_update", cfg.MetadataUpdate)
+	enc.AddBool("strict_mode", cfg.StrictMode)
+	enc.AddBool("strict_validation", cfg.StrictCSV)
+	enc.AddBool("strict_server", cfg.StrictServer)
+	enc.AddBool("strict_compat", cfg.StrictCompat)
+	enc.AddInt("max_failures", cfg.MaxFailures)
+	enc.AddString("failure_mode", cfg.FailureMode)
+	enc.AddString("mirror_policy", cfg.MirrorPolicy)
+	enc.AddString("output_template", cfg.OutputTemplate)
+	enc.AddBool("mirror_tree", cfg.MirrorTree)
+	enc.AddString("collision_policy", cfg.CollisionPolicy)
+	if !cfg.Since.IsZero() {
+		enc.AddTime("since", cfg.Since)
+	}
+	enc.AddBool("auto_order", cfg.AutoOrder)
+	enc.AddBool("ask_to_confirm", cfg.AskToConfirm)
+	enc.AddBool("stdin_is_terminal", cfg.StdinIsTerminal)
+	enc.AddString("stdin_name", cfg.StdinName)
+	enc.AddBool("no_output", cfg.NoOutput)
+	enc.AddBool("diff_summary", cfg.DiffSummary)
+	enc.AddBool("summary_table", cfg.SummaryTable)
+	enc.AddInt("preview", cfg.Preview)
+	enc.AddBool("quiet", cfg.Quiet)
+	enc.AddString("save_errors_dir", cfg.SaveErrorsDir)
+	enc.AddDuration("max_runtime", cfg.MaxRuntime)
+	enc.AddString("on_conflict", cfg.OnConflict)
+	zap.Strings("pre_hook", cfg.PreHook).AddTo(enc)
+	zap.Strings("post_hook", cfg.PostHook).AddTo(enc)
+	enc.AddBool("lock", cfg.Lock)
+	enc.AddBool("count_only", cfg.CountOnly)
+	enc.AddBool("merge", cfg.Merge)
+	enc.AddString("merge_name", cfg.MergeName)
+	if cfg.Production != nil {
+		enc.AddString("production_pattern", cfg.Production.String())
+	}
+	enc.AddString("confirm_server", cfg.ConfirmServer)
+	enc.AddBool("assume_yes", cfg.AssumeYes)
+	enc.AddBool("verify_output", cfg.VerifyOutput)
+	enc.AddInt("verify_concurrency", cfg.VerifyWorkers)
+	enc.AddBool("dedupe_output", cfg.DedupeOutput)
+	enc.AddBool("pretty_errors", cfg.PrettyErrors)
+	enc.AddInt("limit", cfg.Limit)
+	enc.AddBool("shuffle", cfg.Shuffle)
+	enc.AddInt64("seed", cfg.Seed)
+	enc.AddString("delimiter", string(cfg.Input.Delimiter))
+	enc.AddBool("detect_encoding", cfg.Input.DetectEncoding)
+	enc.AddInt("column_renames", len(cfg.Input.Renames))
+	enc.AddString("ark_prefix", cfg.ARKPrefix)
+	enc.AddBool("keep_collection_rows", cfg.KeepCollectionRows)
+	enc.AddBool("fix_object_types", cfg.FixObjectTypes)
+	enc.AddBool("sort_rows", cfg.SortRows)
+	enc.AddString("sort_key", cfg.SortKey)
+	enc.AddString("collection_name", cfg.CollectionName)
+	enc.AddBool("normalize_delimiter", cfg.NormalizeDelimiter)
+	enc.AddBool("canonical_header", cfg.CanonicalHeader)
+	enc.AddBool("schema", cfg.Schema != nil)
+	enc.AddBool("validate_csv", cfg.ValidateCSV)
+	enc.AddBool("validate_arks", cfg.ValidateARKs)
+	zap.Strings("multivalue_columns", cfg.MultiValueColumns).AddTo(enc)
+	enc.AddString("multivalue_separator", cfg.MultiValueSeparator)
+	enc.AddInt("max_cell_length", cfg.MaxCellLength)
+	enc.AddBool("warn_unknown_object_types", cfg.WarnUnknownObjectTypes)
+	enc.AddBool("split_by_type", cfg.Upload.SplitByType)
+	enc.AddInt("chunk_rows", cfg.Upload.ChunkRows)
+	enc.AddBool("compress_upload", cfg.Upload.Compress)
+	enc.AddBool("idempotency", cfg.Upload.Idempotency)
+	enc.AddString("upload_name", cfg.Upload.UploadName)
+	enc.AddString("part_content_type", cfg.Upload.PartContentType)
+	enc.AddString("record_requests_dir", cfg.Upload.RecordRequestsDir)
+	zap.Strings("log_response_headers", cfg.Upload.LogHeaders).AddTo(enc)
+	enc.AddInt64("max_upload_rate", cfg.Upload.MaxRate)
+	enc.AddBool("trace", cfg.Upload.Trace)
+	zap.Any("headers", headers).AddTo(enc)
+	enc.AddString("report_file", cfg.ReportFile)
+	enc.AddString("metrics_file", cfg.MetricsFile)
+	enc.AddTime("start_time", cfg.StartTime)
+	return nil
+}
+
+// flagValues returns the value of every flag, whether it was given or left at its default, with credentials
+// hidden; the values of --header are hidden since any of them can be an API key
+func flagValues(flags *pflag.FlagSet) map[string]any {
+	values := map[string]any{}
+	flags.VisitAll(func(flag *pflag.Flag) {
+		slice, isSlice := flag.Value.(pflag.SliceValue)
+		switch {
+		case isSensitiveKey(flag.Name):
+			if flag.Value.String() != "" {
+				values[flag.Name] = redactedValue
+			} else {
+				values[flag.Name] = ""
+			}
+		case flag.Name == "header" && isSlice:
+			var headers []string
+			for _, header := range slice.GetSlice() {
+				name, _, _ := strings.Cut(header, ":")
+				headers = append(headers, name+": "+redactedValue)
+			}
+			values[flag.Name] = headers
+		case isSlice:
+			var redacted []string
+			for _, value := range slice.GetSlice() {
+				redacted = append(redacted, Redact(value))
+			}
+			values[flag.Name] = redacted
+		default:
+			values[flag.Name] = Redact(flag.Value.String())
+		}
+	})
+	return values
+}
+
+// changedFlags returns the names of the flags given on the command line
+func changedFlags(flags *pflag.FlagSet) []string {
+	var changed []string
+	flags.Visit(func(flag *pflag.Flag) {
+		changed = append(changed, flag.Name)
+	})
+	return changed
+}
+
+// logEffectiveConfig logs how the run was configured, once every flag has been resolved, so that it's clear
+// from the log how a run was parameterized
+func logEffectiveConfig(cfg Config, flags *pflag.FlagSet) {
+	Logger.Info("Effective configuration",
+		zap.String("version", festerizeVersion),
+		zap.Object("config", cfg),
+		zap.Any("flags", flagValues(flags)),
+		zap.Strings("flags_set", changedFlags(flags)),
+		zap.Bool("bearer_auth", authToken != ""))
+}
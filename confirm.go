@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// UploadSummary describes what a run is about to upload
+type UploadSummary struct {
+	Files          int
+	TotalBytes     int64
+	Servers        []string
+	IIIFVersion    string
+	IIIFHost       string
+	MetadataUpdate bool
+}
+
+// NewUploadSummary summarizes the upload of the supplied files with the run's settings
+func NewUploadSummary(cfg Config, paths []string) UploadSummary {
+	summary := UploadSummary{
+		Servers:        cfg.Servers,
+		IIIFVersion:    cfg.IIIFVersion,
+		IIIFHost:       cfg.IIIFHost,
+		MetadataUpdate: cfg.MetadataUpdate,
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && IsCSVFile(path) {
+			summary.Files++
+			summary.TotalBytes += info.Size()
+		}
+	}
+	return summary
+}
+
+// String formats the summary for display before the confirmation prompt
+func (s UploadSummary) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Files:            %d (%d bytes)\n", s.Files, s.TotalBytes)
+	fmt.Fprintf(&builder, "Servers:          %s\n", strings.Join(redactAll(s.Servers), ", "))
+	fmt.Fprintf(&builder, "IIIF API version: %s\n", s.IIIFVersion)
+	if s.IIIFHost != "" {
+		fmt.Fprintf(&builder, "IIIF host:        %s\n", s.IIIFHost)
+	}
+	fmt.Fprintf(&builder, "Metadata update:  %t\n", s.MetadataUpdate)
+	return builder.String()
+}
+
+// ConfirmUpload prints the summary and asks whether to proceed; anything but y or yes aborts. Without a
+// terminal to answer the prompt, the upload is aborted.
+func ConfirmUpload(summary UploadSummary, in io.Reader, out io.Writer, interactive bool) error {
+	fmt.Fprint(out, summary)
+	if !interactive {
+		return errors.New("can't ask for confirmation without a terminal; pass --yes to upload anyway")
+	}
+
+	fmt.Fprint(out, "Proceed? (y/N): ")
+	response, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "y", "yes":
+		return nil
+	default:
+		return errors.New("aborted")
+	}
+}
+
+// isTerminal checks whether the supplied file is a terminal a user can answer prompts on
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// What to do with a file that Fester rejects because it was uploaded before the file with its parents
+const (
+	ConflictDefer string = "defer"
+	ConflictFail  string = "fail"
+	ConflictSkip  string = "skip"
+)
+
+// orderingErrorPattern matches the whole of Fester's error message for a work whose collection, or a page whose
+// work, hasn't been festerized yet; other errors that mention a missing collection or work are data errors
+var orderingErrorPattern = regexp.MustCompile(
+	`^(Work|Page) ark:/\S+ has a parent (collection|work) that has not been festerized$`)
+
+// ValidateOnConflict validates the policy for files uploaded before their parents
+func ValidateOnConflict() error {
+	switch onConflict {
+	case ConflictDefer, ConflictFail, ConflictSkip:
+		return nil
+	default:
+		return errors.New("invalid conflict policy. Allowed values are defer, fail, or skip")
+	}
+}
+
+// IsOrderingError checks whether Fester rejected a file because it was uploaded before the collection or work
+// its rows belong to, as described in the help; Fester answers those with 400 Bad Request
+func IsOrderingError(statusCode int, festerError FesterError) bool {
+	return statusCode == http.StatusBadRequest && orderingErrorPattern.MatchString(strings.TrimSpace(festerError.Detail))
+}
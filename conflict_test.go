@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestIsOrderingError tests that only errors about missing parents are taken for ordering errors
+func TestIsOrderingError(t *testing.T) {
+	tests := []struct {
+		statusCode  int
+		festerError FesterError
+		ordering    bool
+	}{
+		{400, FesterError{Detail: "Work ark:/21198/zz0002 has a parent collection that has not been festerized"}, true},
+		{400, FesterError{Detail: " Page ark:/21198/zz0003 has a parent work that has not been festerized\n"}, true},
+		{500, FesterError{Detail: "Work ark:/21198/zz0002 has a parent collection that has not been festerized"}, false},
+		{400, FesterError{Detail: "Collection ark:/21198/zz0001 is missing the Title column"}, false},
+		{400, FesterError{Detail: "Collection not found"}, false},
+		{400, FesterError{Title: "400 Bad Request", Detail: "Parent ARK ark:/21198/zz0001 was not found"}, false},
+		{400, FesterError{Detail: "CSV is missing the required Item ARK column"}, false},
+		{502, FesterError{Title: "502 Bad Gateway"}, false},
+		{500, FesterError{Detail: "Internal server error"}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.festerError.Error(), func(t *testing.T) {
+			assert.Equal(t, tc.ordering, IsOrderingError(tc.statusCode, tc.festerError))
+		})
+	}
+}
+
+// orderingUploads returns an upload handler that rejects works until their collection has been uploaded, recording
+// the order files were uploaded in
+func orderingUploads(uploads *[]string) http.HandlerFunc {
+	var mutex sync.Mutex
+	collectionUploaded := false
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		*uploads = append(*uploads, header.Filename)
+		switch {
+		case header.Filename == "collection.csv":
+			collectionUploaded = true
+		case !collectionUploaded:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<html><body><p id="error-message">Work ark:/21198/zz0002 has a parent ` +
+				`collection that has not been festerized</p></body></html>`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("Festerized CSV"))
+	}
+}
+
+// TestRunOnConflictDefer tests that a file uploaded before its parents is tried again after the file with them
+func TestRunOnConflictDefer(t *testing.T) {
+	var uploads []string
+	h := newRunHarness(t, orderingUploads(&uploads))
+	works := writeTestCSV(t, "works.csv", testWorksCSV)
+	collection := writeTestCSV(t, "collection.csv", testCollectionCSV)
+
+	cfg := testConfig(t, h.server.URL, works, collection)
+	cfg.OnConflict, cfg.StrictMode = ConflictDefer, true
+
+	exitCode, results, err := run(context.Background(), cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, []string{"works.csv", "collection.csv", "works.csv"}, uploads)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "collection.csv", results[0].Filename)
+		assert.Equal(t, "works.csv", results[1].Filename)
+		assert.Equal(t, StatusSuccess, results[1].Status)
+	}
+	assert.FileExists(t, filepath.Join(cfg.OutputDir, "works.csv"))
+	assert.Contains(t, h.log.String(), "Deferring file uploaded before its parents")
+}
+
+// TestRunOnConflictDeferOnce tests that a deferred file that still comes before its parents fails
+func TestRunOnConflictDeferOnce(t *testing.T) {
+	var uploads []string
+	h := newRunHarness(t, orderingUploads(&uploads))
+	cfg := testConfig(t, h.server.URL, writeTestCSV(t, "works.csv", testWorksCSV))
+	cfg.OnConflict = ConflictDefer
+
+	exitCode, results, err := run(context.Background(), cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, []string{"works.csv", "works.csv"}, uploads)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, StatusFailure, results[0].Status)
+	}
+}
+
+// TestRunOnConflictSkip tests that a skipped file doesn't stop a strict run or count as failed
+func TestRunOnConflictSkip(t *testing.T) {
+	var uploads []string
+	h := newRunHarness(t, orderingUploads(&uploads))
+	works := writeTestCSV(t, "works.csv", testWorksCSV)
+	collection := writeTestCSV(t, "collection.csv", testCollectionCSV)
+
+	cfg := testConfig(t, h.server.URL, works, collection)
+	cfg.OnConflict, cfg.StrictMode, cfg.MaxFailures = ConflictSkip, true, 1
+
+	exitCode, results, err := run(context.Background(), cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, []string{"works.csv", "collection.csv"}, uploads)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, StatusSkipped, results[0].Status)
+		assert.Equal(t, StatusSuccess, results[1].Status)
+	}
+}
+
+// TestRunOnConflictFail tests that an ordering error stops a strict run by default
+func TestRunOnConflictFail(t *testing.T) {
+	var uploads []string
+	h := newRunHarness(t, orderingUploads(&uploads))
+	works := writeTestCSV(t, "works.csv", testWorksCSV)
+	collection := writeTestCSV(t, "collection.csv", testCollectionCSV)
+
+	cfg := testConfig(t, h.server.URL, works, collection)
+	cfg.OnConflict, cfg.StrictMode = ConflictFail, true
+
+	exitCode, _, err := run(context.Background(), cfg)
+
+	assert.Error(t, err)
+	assert.Equal(t, int(FESTER_ERROR_RESPONSE), exitCode)
+	assert.Equal(t, []string{"works.csv"}, uploads)
+}
+
+// TestRunOnConflictSkipDataError tests that a data error that mentions a missing collection isn't taken for an
+// ordering error, so it still fails the file and stops a strict run
+func TestRunOnConflictSkipDataError(t *testing.T) {
+	h := newRunHarness(t, respondWith(http.StatusBadRequest, `<html><body><p id="error-message">Collection `+
+		`ark:/21198/zz0001 is missing the Title column</p></body></html>`))
+
+	cfg := testConfig(t, h.server.URL, writeTestCSV(t, "collection.csv", testCollectionCSV))
+	cfg.OnConflict, cfg.StrictMode = ConflictSkip, true
+
+	exitCode, results, err := run(context.Background(), cfg)
+
+	assert.Error(t, err)
+	assert.Equal(t, int(FESTER_ERROR_RESPONSE), exitCode)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, StatusFailure, results[0].Status)
+	}
+}
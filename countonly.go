@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FileCount is what --count-only reports: the files a run would process, and the inputs it would pass over
+type FileCount struct {
+	Count   int      `json:"count"`
+	Files   []string `json:"files"`
+	Ignored []string `json:"ignored,omitempty"`
+}
+
+// CountFiles picks out the files a run would upload, without opening them: stdin, and the CSVs that exist and,
+// with a --since threshold, were modified after it; inputs that are missing or aren't CSV files are ignored
+func CountFiles(sources []string, since time.Time) FileCount {
+	count := FileCount{Files: []string{}}
+	var candidates []string
+	for _, source := range sources {
+		if source == stdinSource {
+			candidates = append(candidates, source)
+			continue
+		}
+		info, err := os.Stat(source)
+		if err != nil || info.IsDir() || !IsCSVFile(filepath.Base(source)) {
+			count.Ignored = append(count.Ignored, source)
+			continue
+		}
+		candidates = append(candidates, source)
+	}
+
+	if !since.IsZero() {
+		candidates = FilterSince(candidates, since)
+	}
+	count.Files = append(count.Files, candidates...)
+	count.Count = len(count.Files)
+	return count
+}
+
+// writeFileCount prints the count and the files to be processed, or, with JSON output, the count as a JSON object
+func writeFileCount(w io.Writer, count FileCount) error {
+	if outputFormat == OutputFormatJSON {
+		return json.NewEncoder(w).Encode(count)
+	}
+
+	fmt.Fprintf(w, "%d files would be processed\n", count.Count)
+	for _, file := range count.Files {
+		fmt.Fprintln(w, file)
+	}
+	if len(count.Ignored) > 0 {
+		fmt.Fprintf(w, "%d inputs would be ignored, since they don't exist or aren't CSV files\n", len(count.Ignored))
+		for _, file := range count.Ignored {
+			fmt.Fprintln(w, file)
+		}
+	}
+	return nil
+}
+
+// countOnly reports what a run would process, without contacting Fester or creating the output directory
+func countOnly(cfg Config) (int, error) {
+	count := CountFiles(cfg.Sources, cfg.Since)
+	Logger.Info("Counted files that would be processed",
+		zap.Int("count", count.Count),
+		zap.Strings("files", count.Files),
+		zap.Strings("ignored", count.Ignored))
+
+	if err := writeFileCount(os.Stdout, count); err != nil {
+		return int(FILE_IO_ERROR), err
+	}
+	return 0, nil
+}
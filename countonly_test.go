@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCountFiles tests that only the existing CSVs are counted from a mix of globbed, missing, and non-CSV
+// inputs
+func TestCountFiles(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	notCSV := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(notCSV, []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing.csv")
+	sources := append(ExpandGlobs([]string{TestDirUnFester + "/*.csv"}), missing, notCSV, TestDirUnFester,
+		TestDirGzipped+"/chase.csv.gz", stdinSource)
+
+	count := CountFiles(sources, time.Time{})
+	assert.Equal(t, 7, count.Count)
+	assert.Len(t, count.Files, 7)
+	assert.Contains(t, count.Files, TestDirGzipped+"/chase.csv.gz")
+	assert.Contains(t, count.Files, stdinSource)
+	assert.Equal(t, []string{missing, notCSV, TestDirUnFester}, count.Ignored)
+}
+
+// TestCountFilesSince tests that files that haven't changed since the --since threshold aren't counted
+func TestCountFilesSince(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	path := writeTestCSV(t, "old.csv", testCollectionCSV)
+	count := CountFiles([]string{path}, time.Now().Add(time.Hour))
+
+	assert.Equal(t, 0, count.Count)
+	assert.Empty(t, count.Files)
+	assert.Empty(t, count.Ignored)
+}
+
+// TestRunCountOnly tests that counting files neither contacts Fester nor creates the output directory
+func TestRunCountOnly(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer ts.Close()
+
+	missing := filepath.Join(t.TempDir(), "missing.csv")
+	cfg := testConfig(t, ts.URL, TestDirUnFester+"/ballin.csv", TestDirUnFester+"/chase.csv", missing)
+	cfg.CountOnly = true
+
+	var exitCode int
+	var err error
+	output := captureStdout(t, func() { exitCode, _, err = run(context.Background(), cfg) })
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, 0, requests)
+	assert.NoDirExists(t, cfg.OutputDir)
+	assert.True(t, strings.HasPrefix(output, "2 files would be processed\n"), output)
+	assert.Contains(t, output, "1 inputs would be ignored")
+}
+
+// TestWriteFileCountJSON tests that the count is written as a JSON object with JSON output
+func TestWriteFileCountJSON(t *testing.T) {
+	defer func() { outputFormat = OutputFormatText }()
+	outputFormat = OutputFormatJSON
+
+	var buffer strings.Builder
+	assert.Nil(t, writeFileCount(&buffer, FileCount{Count: 1, Files: []string{"ballin.csv"}}))
+	assert.Equal(t, `{"count":1,"files":["ballin.csv"]}`+"\n", buffer.String())
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Columns of the CSVs that Fester reads and writes
+const (
+	itemARKColumn     string = "Item ARK"
+	parentARKColumn   string = "Parent ARK"
+	objectTypeColumn  string = "Object Type"
+	manifestURLColumn string = "IIIF Manifest URL"
+	titleColumn       string = "Title"
+	iiifHostColumn    string = "IIIF Host"
+)
+
+// Filename extensions of the files that can be uploaded
+const (
+	csvExtension  string = ".csv"
+	gzipExtension string = ".gz"
+)
+
+// ParseDelimiter parses a --delimiter value: a single character, or "tab" (or \t) for tab-separated files
+func ParseDelimiter(value string) (rune, error) {
+	switch value {
+	case "tab", `\t`:
+		return '\t', nil
+	}
+
+	runes := []rune(value)
+	if len(runes) != 1 || runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' || runes[0] == utf8.RuneError {
+		return 0, fmt.Errorf("invalid delimiter %q: expected a single character other than a quote or newline", value)
+	}
+	return runes[0], nil
+}
+
+// CSVInput describes how input CSVs are read: the character their fields are split on, whether their encoding
+// is detected, and the --map renames applied to their header when it's checked before upload
+type CSVInput struct {
+	Delimiter      rune
+	DetectEncoding bool
+	Renames        []ColumnRename
+}
+
+// inputFromFlags returns how the command line says input CSVs are read
+func inputFromFlags() CSVInput {
+	return CSVInput{Delimiter: delimiter, DetectEncoding: detectEncoding, Renames: fieldMap}
+}
+
+// newReader creates a reader for an input CSV that splits fields on the delimiter, or on commas if none is set
+func (input CSVInput) newReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	if input.Delimiter != 0 {
+		reader.Comma = input.Delimiter
+	}
+	return reader
+}
+
+// gzipBytes compresses data with gzip
+func gzipBytes(data []byte) (*bytes.Buffer, error) {
+	buffer := &bytes.Buffer{}
+	writer := gzip.NewWriter(buffer)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer, nil
+}
+
+// isGzipped checks whether a file has a gzip filename extension
+func isGzipped(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), gzipExtension)
+}
+
+// csvName returns the name of a CSV file without any compression extension
+func csvName(filename string) string {
+	if isGzipped(filename) {
+		return filename[:len(filename)-len(gzipExtension)]
+	}
+	return filename
+}
+
+// IsCSVFile checks whether a file has a .csv or .csv.gz filename extension
+func IsCSVFile(filename string) bool {
+	return strings.EqualFold(filepath.Ext(csvName(filename)), csvExtension)
+}
+
+// gzipFile closes both a gzip stream and the file it reads from
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+// Close closes the gzip stream and the underlying file
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+// open opens a CSV file for reading, decompressing it on the fly if it's gzipped, and when its encoding is to
+// be detected converting it to UTF-8
+func (input CSVInput) open(path string) (io.ReadCloser, error) {
+	file, err := openRawCSV(path)
+	if err != nil || !input.DetectEncoding {
+		return file, err
+	}
+	defer file.Close()
+
+	data, _, _, err := decodeCSV(file)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// openRawCSV opens a CSV file for reading, decompressing it on the fly if it's gzipped
+func openRawCSV(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isGzipped(path) {
+		return file, nil
+	}
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &gzipFile{reader, file}, nil
+}
+
+// readRecords reads all the records of a CSV file, header first
+func (input CSVInput) readRecords(path string) ([][]string, error) {
+	file, err := input.open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := input.newReader(file)
+	reader.FieldsPerRecord = -1
+	return reader.ReadAll()
+}
+
+// readRenamedRecords reads all the records of a CSV file with the renames applied to its header, so that it's
+// checked under the column names Fester will get
+func (input CSVInput) readRenamedRecords(path string) ([][]string, error) {
+	records, err := input.readRecords(path)
+	if err == nil && len(records) > 0 {
+		renameHeader(records[0], input.Renames)
+	}
+	return records, err
+}
+
+// readCSVRecords reads all the records of a CSV file, header first, as the command line says input CSVs are read
+func readCSVRecords(path string) ([][]string, error) {
+	return inputFromFlags().readRecords(path)
+}
+
+// columnIndex returns the index of the named column in a CSV header, or -1 if it's missing
+func columnIndex(header []string, name string) int {
+	for index, column := range header {
+		if strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")) == name {
+			return index
+		}
+	}
+	return -1
+}
+
+// ManifestURLs returns the IIIF manifest and collection URLs found in a CSV returned by Fester
+func ManifestURLs(body []byte) ([]string, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	urlIndex := columnIndex(header, manifestURLColumn)
+	if urlIndex == -1 {
+		return nil, nil
+	}
+
+	var urls []string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return urls, err
+		}
+		if urlIndex < len(row) && row[urlIndex] != "" {
+			urls = append(urls, row[urlIndex])
+		}
+	}
+	return urls, nil
+}
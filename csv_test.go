@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestManifestURLs tests that manifest URLs are read from a festerized CSV
+func TestManifestURLs(t *testing.T) {
+	body, err := os.ReadFile(TestDirFester + "/chase.csv")
+	assert.Nil(t, err)
+
+	urls, err := ManifestURLs(body)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"https://test.iiif.library.ucla.edu/collections/ark%3A%2F21198%2Fzz00093mjc",
+		"https://test.iiif.library.ucla.edu/ark%3A%2F21198%2Fzz00093zr9/manifest",
+	}, urls)
+}
+
+// TestManifestURLsMissingColumn tests that a CSV without a manifest URL column yields no URLs
+func TestManifestURLsMissingColumn(t *testing.T) {
+	urls, err := ManifestURLs([]byte("Item ARK,Object Type\nark:/21198/zz00093mjc,Collection\n"))
+	assert.Nil(t, err)
+	assert.Empty(t, urls)
+
+	urls, err = ManifestURLs([]byte(""))
+	assert.Nil(t, err)
+	assert.Empty(t, urls)
+}
+
+// TestIsCSVFile tests that plain and gzipped CSVs are recognized by their extensions
+func TestIsCSVFile(t *testing.T) {
+	tests := []struct {
+		filename string
+		expected bool
+	}{
+		{"ballin.csv", true},
+		{"ballin.CSV", true},
+		{"ballin.csv.gz", true},
+		{"ballin.CSV.GZ", true},
+		{"ballin.gz", false},
+		{"ballin.txt", false},
+		{"ballin.tar.gz", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.filename, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsCSVFile(tc.filename))
+		})
+	}
+}
+
+// TestParseDelimiter tests that delimiters are parsed and ones csv can't use are rejected
+func TestParseDelimiter(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected rune
+	}{
+		{",", ','},
+		{";", ';'},
+		{"tab", '\t'},
+		{`\t`, '\t'},
+		{"\t", '\t'},
+		{"|", '|'},
+	}
+
+	for _, tc := range tests {
+		delimiter, err := ParseDelimiter(tc.value)
+		assert.Nil(t, err, tc.value)
+		assert.Equal(t, tc.expected, delimiter, tc.value)
+	}
+
+	for _, value := range []string{"", ";;", `"`, "\n", "\r"} {
+		_, err := ParseDelimiter(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+// TestReadCSVRecordsDelimiter tests that input CSVs are split on the delimiter
+func TestReadCSVRecordsDelimiter(t *testing.T) {
+	defer func() { delimiter = ',' }()
+	delimiter = '\t'
+
+	records, err := readCSVRecords(TestDirDelimited + "/tabs.csv")
+	assert.Nil(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, []string{"Item ARK", "Parent ARK", "Object Type", "Title"}, records[0])
+	assert.Equal(t, "Ballin papers, 1890-1956", records[1][3])
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// outputUnchanged checks whether an output CSV already holds exactly the supplied content, comparing hashes so
+// the existing file is streamed rather than read into memory; a missing file counts as changed
+func outputUnchanged(path string, content []byte) (bool, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err != nil {
+		return false, err
+	} else if !info.Mode().IsRegular() || info.Size() != int64(len(content)) {
+		return false, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return false, err
+	}
+	expected := sha256.Sum256(content)
+	return bytes.Equal(hash.Sum(nil), expected[:]), nil
+}
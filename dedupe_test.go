@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOutputUnchanged tests comparing content with an existing output CSV
+func TestOutputUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output.csv")
+
+	unchanged, err := outputUnchanged(path, []byte("a,b\n"))
+	assert.NoError(t, err)
+	assert.False(t, unchanged)
+
+	if err := os.WriteFile(path, []byte("a,b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unchanged, err = outputUnchanged(path, []byte("a,b\n"))
+	assert.NoError(t, err)
+	assert.True(t, unchanged)
+
+	unchanged, err = outputUnchanged(path, []byte("a,c\n"))
+	assert.NoError(t, err)
+	assert.False(t, unchanged)
+}
+
+// TestRunDedupeOutput tests that an identical output CSV isn't rewritten, and a different one is
+func TestRunDedupeOutput(t *testing.T) {
+	newRunHarness(t, nil)
+
+	source := writeTestCSV(t, "dedupe.csv", testCollectionCSV)
+	cfg := testConfig(t, newRunStub(t, 200, 201, testCollectionCSV).URL, source)
+	cfg.DedupeOutput = true
+	outputPath := filepath.Join(cfg.OutputDir, "dedupe.csv")
+
+	// Fester's first response is written out
+	exitCode, _, err := run(context.Background(), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	earlier := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(outputPath, earlier, earlier); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same response again leaves the file alone
+	cfg.In = strings.NewReader("yes\n")
+	exitCode, _, err = run(context.Background(), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	info, err := os.Stat(outputPath)
+	if assert.NoError(t, err) {
+		assert.True(t, info.ModTime().Equal(earlier))
+	}
+
+	// A different response is written over it
+	cfg.Servers = []string{newRunStub(t, 200, 201, testWorksCSV).URL}
+	cfg.In = strings.NewReader("yes\n")
+	exitCode, _, err = run(context.Background(), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	content, err := os.ReadFile(outputPath)
+	if assert.NoError(t, err) {
+		assert.Equal(t, testWorksCSV, string(content))
+	}
+
+}
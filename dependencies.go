@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Values of the Object Type column
+const (
+	objectTypeCollection string = "Collection"
+	objectTypeWork       string = "Work"
+	objectTypePage       string = "Page"
+)
+
+// BatchFile is a CSV file in the batch along with its records, header first
+type BatchFile struct {
+	Path    string
+	Records [][]string
+}
+
+// Orphan is a work or page row whose parent isn't found anywhere in the batch
+type Orphan struct {
+	Path       string
+	Row        int
+	ObjectType string
+	ItemARK    string
+	ParentARK  string
+}
+
+// String formats the orphan for people to read
+func (o Orphan) String() string {
+	return fmt.Sprintf("%s row %d: %s %s has parent %s, which is not in any of the provided files",
+		o.Path, o.Row, o.ObjectType, o.ItemARK, o.ParentARK)
+}
+
+// normalizeObjectType returns the canonical spelling of an object type, or the trimmed value if it's unknown
+func normalizeObjectType(value string) string {
+	value = strings.TrimSpace(value)
+	for _, objectType := range []string{objectTypeCollection, objectTypeWork, objectTypePage} {
+		if strings.EqualFold(value, objectType) {
+			return objectType
+		}
+	}
+	return value
+}
+
+// readBatch reads the records of every CSV in the batch for the checks made before uploading. Files that are
+// empty or can't be read are left out; the upload loop fails the unreadable ones when it reaches them.
+func readBatch(paths []string, input CSVInput) []BatchFile {
+	var batch []BatchFile
+	for _, path := range paths {
+		if !IsCSVFile(path) {
+			continue
+		}
+		records, err := input.readRenamedRecords(path)
+		if err != nil || len(records) == 0 {
+			continue
+		}
+		batch = append(batch, BatchFile{path, records})
+	}
+	return batch
+}
+
+// selectBatch returns the files of the batch that are among the supplied paths, in the order of the paths, so a
+// batch read once can be checked again after files were reordered or left out
+func selectBatch(batch []BatchFile, paths []string) []BatchFile {
+	var selected []BatchFile
+	for _, path := range paths {
+		index := slices.IndexFunc(batch, func(file BatchFile) bool { return file.Path == path })
+		if index != -1 {
+			selected = append(selected, batch[index])
+		}
+	}
+	return selected
+}
+
+// FindOrphans finds the works whose collection and the pages whose work aren't in any file of the batch;
+// pages are skipped when they're ignored by a metadata update
+func FindOrphans(batch []BatchFile, checkPages bool) []Orphan {
+	arks := map[string]map[string]bool{
+		objectTypeCollection: {},
+		objectTypeWork:       {},
+	}
+
+	// Collect the ARKs of every collection and work in the batch
+	for _, file := range batch {
+		itemIndex := columnIndex(file.Records[0], itemARKColumn)
+		typeIndex := columnIndex(file.Records[0], objectTypeColumn)
+		for _, row := range file.Records[1:] {
+			if known, found := arks[normalizeObjectType(cell(row, typeIndex))]; found {
+				known[cell(row, itemIndex)] = true
+			}
+		}
+	}
+
+	// Then check every work and page against them
+	var orphans []Orphan
+	for _, file := range batch {
+		itemIndex := columnIndex(file.Records[0], itemARKColumn)
+		parentIndex := columnIndex(file.Records[0], parentARKColumn)
+		typeIndex := columnIndex(file.Records[0], objectTypeColumn)
+		if typeIndex == -1 || parentIndex == -1 {
+			continue
+		}
+
+		for rowIndex, row := range file.Records[1:] {
+			objectType := normalizeObjectType(cell(row, typeIndex))
+			parentARK := cell(row, parentIndex)
+
+			var parentType string
+			switch {
+			case objectType == objectTypeWork:
+				parentType = objectTypeCollection
+			case objectType == objectTypePage && checkPages:
+				parentType = objectTypeWork
+			default:
+				continue
+			}
+
+			if parentARK != "" && !arks[parentType][parentARK] {
+				orphans = append(orphans, Orphan{file.Path, rowIndex + 2, objectType, cell(row, itemIndex), parentARK})
+			}
+		}
+	}
+	return orphans
+}
+
+// OrderByDependencies orders the files so that each one comes after the files defining the parents of its
+// rows (collections before works before pages). Ordering is only across files and is best effort: files
+// that depend on each other keep their original order, as do files that aren't in the batch, which come last.
+func OrderByDependencies(paths []string, batch []BatchFile) []string {
+	// Find the file that defines each ARK
+	definedIn := map[string]int{}
+	for index, file := range batch {
+		itemIndex := columnIndex(file.Records[0], itemARKColumn)
+		for _, row := range file.Records[1:] {
+			if ark := cell(row, itemIndex); ark != "" {
+				if _, found := definedIn[ark]; !found {
+					definedIn[ark] = index
+				}
+			}
+		}
+	}
+
+	// A file depends on the other files that define the parents of its rows
+	dependents := make([]map[int]bool, len(batch))
+	waitingOn := make([]int, len(batch))
+	for index, file := range batch {
+		parentIndex := columnIndex(file.Records[0], parentARKColumn)
+		dependencies := map[int]bool{}
+		for _, row := range file.Records[1:] {
+			if definer, found := definedIn[cell(row, parentIndex)]; found && definer != index {
+				dependencies[definer] = true
+			}
+		}
+		for definer := range dependencies {
+			if dependents[definer] == nil {
+				dependents[definer] = map[int]bool{}
+			}
+			dependents[definer][index] = true
+			waitingOn[index]++
+		}
+	}
+
+	// Repeatedly take the earliest file that isn't waiting on any other
+	ordered := make([]string, 0, len(paths))
+	done := make([]bool, len(batch))
+	for len(ordered) < len(batch) {
+		next := -1
+		for index := range batch {
+			if !done[index] && waitingOn[index] == 0 {
+				next = index
+				break
+			}
+		}
+
+		// Files that depend on each other can't be ordered, so they keep their original order
+		if next == -1 {
+			Logger.Warn("Files depend on each other and can't be ordered")
+			for index := range batch {
+				if !done[index] {
+					next = index
+					break
+				}
+			}
+		}
+
+		done[next] = true
+		ordered = append(ordered, batch[next].Path)
+		for dependent := range dependents[next] {
+			waitingOn[dependent]--
+		}
+	}
+
+	// Files that couldn't be read aren't in the batch, so they go last, in their original order
+	for _, path := range paths {
+		if !slices.Contains(ordered, path) {
+			ordered = append(ordered, path)
+		}
+	}
+	return ordered
+}
+
+// warnAboutOrphans logs the works and pages whose parents aren't in the batch, since Fester rejects them
+// unless their parents were festerized by an earlier run; pages are skipped when they're ignored by a
+// metadata update
+func warnAboutOrphans(batch []BatchFile, metadataUpdate bool) {
+	orphans := FindOrphans(batch, !metadataUpdate)
+	if len(orphans) == 0 {
+		return
+	}
+
+	for _, orphan := range orphans {
+		Logger.Warn("Parent not found in the provided files",
+			zap.String("path", orphan.Path),
+			zap.Int("row", orphan.Row),
+			zap.String("object_type", orphan.ObjectType),
+			zap.String("item_ark", orphan.ItemARK),
+			zap.String("parent_ark", orphan.ParentARK))
+	}
+	fmt.Fprintf(humanOutput(), "Warning: %d works or pages have parents that are not in the provided files; "+
+		"uploads will fail unless those parents have already been festerized (see log)\n", len(orphans))
+}
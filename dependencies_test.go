@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestCSV writes CSV content to a file in a temporary directory and returns its path
+func writeTestCSV(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const (
+	testCollectionCSV = `Item ARK,Parent ARK,Object Type
+ark:/21198/zz0001,,Collection
+`
+	testWorksCSV = `Item ARK,Parent ARK,Object Type
+ark:/21198/zz0002,ark:/21198/zz0001,Work
+ark:/21198/zz0003,ark:/21198/zz0002,Page
+ark:/21198/zz0004,ark:/21198/zz0009,page
+`
+)
+
+// TestFindOrphansAcrossFiles tests that a collection in one file satisfies works in another
+func TestFindOrphansAcrossFiles(t *testing.T) {
+	collection := writeTestCSV(t, "collection.csv", testCollectionCSV)
+	works := writeTestCSV(t, "works.csv", testWorksCSV)
+
+	orphans := FindOrphans(readBatch([]string{works, collection}, CSVInput{}), true)
+	assert.Equal(t, []Orphan{
+		{works, 4, objectTypePage, "ark:/21198/zz0004", "ark:/21198/zz0009"},
+	}, orphans)
+}
+
+// TestFindOrphansMissingCollection tests that works are flagged when their collection isn't in the batch
+func TestFindOrphansMissingCollection(t *testing.T) {
+	works := writeTestCSV(t, "works.csv", testWorksCSV)
+
+	orphans := FindOrphans(readBatch([]string{works}, CSVInput{}), true)
+	assert.Len(t, orphans, 2)
+	assert.Equal(t, objectTypeWork, orphans[0].ObjectType)
+	assert.Equal(t, "ark:/21198/zz0001", orphans[0].ParentARK)
+	assert.Equal(t, 2, orphans[0].Row)
+
+	// Pages are ignored by metadata updates, so only the work is an orphan
+	orphans = FindOrphans(readBatch([]string{works}, CSVInput{}), false)
+	assert.Len(t, orphans, 1)
+}
+
+// TestFindOrphansFixtures tests that the test CSVs, which each contain their own collection, have no orphans
+func TestFindOrphansFixtures(t *testing.T) {
+	paths, err := filepath.Glob(TestDirUnFester + "/*.csv")
+	assert.Nil(t, err)
+
+	for _, path := range paths {
+		assert.Empty(t, FindOrphans(readBatch([]string{path}, CSVInput{}), true), path)
+	}
+}
+
+// TestOrderByDependencies tests that a collection → work → page chain is uploaded in dependency order
+func TestOrderByDependencies(t *testing.T) {
+	pages := writeTestCSV(t, "pages.csv", "Item ARK,Parent ARK,Object Type\nark:/21198/zz0003,ark:/21198/zz0002,Page\n")
+	works := writeTestCSV(t, "works.csv", "Item ARK,Parent ARK,Object Type\nark:/21198/zz0002,ark:/21198/zz0001,Work\n")
+	collection := writeTestCSV(t, "collection.csv", testCollectionCSV)
+	unrelated := writeTestCSV(t, "unrelated.csv", "Item ARK,Parent ARK,Object Type\nark:/21198/zz0100,,Collection\n")
+	missing := filepath.Join(t.TempDir(), "missing.csv")
+
+	paths := []string{pages, missing, works, unrelated, collection}
+	ordered := OrderByDependencies(paths, readBatch(paths, CSVInput{}))
+	assert.Equal(t, []string{unrelated, collection, works, pages, missing}, ordered)
+}
+
+// TestOrderByDependenciesCycle tests that files depending on each other keep their original order
+func TestOrderByDependenciesCycle(t *testing.T) {
+	first := writeTestCSV(t, "first.csv", "Item ARK,Parent ARK,Object Type\nark:/21198/zz0001,ark:/21198/zz0002,Work\n")
+	second := writeTestCSV(t, "second.csv", "Item ARK,Parent ARK,Object Type\nark:/21198/zz0002,ark:/21198/zz0001,Work\n")
+
+	paths := []string{first, second}
+	assert.Equal(t, paths, OrderByDependencies(paths, readBatch(paths, CSVInput{})))
+}
+
+// TestSelectBatch tests that a batch read once follows the files as they're reordered and left out
+func TestSelectBatch(t *testing.T) {
+	works := writeTestCSV(t, "works.csv", testWorksCSV)
+	collection := writeTestCSV(t, "collection.csv", testCollectionCSV)
+	missing := filepath.Join(t.TempDir(), "missing.csv")
+	batch := readBatch([]string{works, missing, collection}, CSVInput{})
+
+	selected := selectBatch(batch, []string{collection, missing, works})
+	if assert.Len(t, selected, 2) {
+		assert.Equal(t, collection, selected[0].Path)
+		assert.Equal(t, works, selected[1].Path)
+	}
+	assert.Empty(t, selectBatch(batch, []string{missing}))
+}
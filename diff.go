@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// CSVDiff summarizes how the CSV Fester returned differs from the one that was uploaded
+type CSVDiff struct {
+	Rows               int
+	ChangedRows        int
+	ChangedCells       int
+	GainedManifestURLs int
+	// Item ARKs of the collection and work rows that still have no manifest URL
+	MissingManifestURLs []string
+}
+
+// DiffCSVs compares an uploaded CSV with the one Fester returned, matching rows on their Item ARK and cells on
+// their column name
+func DiffCSVs(uploaded, returned [][]string) CSVDiff {
+	var diff CSVDiff
+	if len(uploaded) == 0 {
+		return diff
+	}
+
+	var returnedHeader []string
+	returnedRows := map[string][]string{}
+	if len(returned) > 0 {
+		returnedHeader = returned[0]
+		itemIndex := columnIndex(returnedHeader, itemARKColumn)
+		for _, row := range returned[1:] {
+			returnedRows[cell(row, itemIndex)] = row
+		}
+	}
+
+	// Compare every column found in either CSV
+	columns := append([]string{}, uploaded[0]...)
+	for _, column := range returnedHeader {
+		if columnIndex(columns, column) == -1 {
+			columns = append(columns, column)
+		}
+	}
+
+	itemIndex := columnIndex(uploaded[0], itemARKColumn)
+	typeIndex := columnIndex(uploaded[0], objectTypeColumn)
+	for _, row := range uploaded[1:] {
+		diff.Rows++
+		itemARK := cell(row, itemIndex)
+		returnedRow := returnedRows[itemARK]
+
+		changedCells := 0
+		for _, column := range columns {
+			before := cell(row, columnIndex(uploaded[0], column))
+			after := cell(returnedRow, columnIndex(returnedHeader, column))
+			if before != after {
+				changedCells++
+			}
+		}
+		if changedCells > 0 {
+			diff.ChangedRows++
+			diff.ChangedCells += changedCells
+		}
+
+		before := cell(row, columnIndex(uploaded[0], manifestURLColumn))
+		after := cell(returnedRow, columnIndex(returnedHeader, manifestURLColumn))
+		if before == "" && after != "" {
+			diff.GainedManifestURLs++
+		}
+
+		// Fester creates manifests for collections and works, but not for pages
+		objectType := normalizeObjectType(cell(row, typeIndex))
+		if after == "" && (objectType == objectTypeCollection || objectType == objectTypeWork) {
+			diff.MissingManifestURLs = append(diff.MissingManifestURLs, itemARK)
+		}
+	}
+	return diff
+}
+
+// logDiffSummary compares a file, as it was uploaded after the transforms, with the CSV Fester returned for it
+// and reports how many rows gained manifest URLs
+func logDiffSummary(path string, body []byte, input CSVInput, transforms []CSVTransform) {
+	filename := filepath.Base(path)
+
+	uploaded, err := input.readRecords(path)
+	if err == nil {
+		uploaded, err = applyTransforms(uploaded, transforms)
+	}
+	if err != nil {
+		Logger.Warn("Could not read CSV to compare with Fester's response",
+			zap.String("filename", filename),
+			zap.Error(err))
+		return
+	}
+
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+	returned, err := reader.ReadAll()
+	if err != nil {
+		Logger.Warn("Could not read Fester's response to compare with the CSV",
+			zap.String("filename", filename),
+			zap.Error(err))
+		return
+	}
+
+	diff := DiffCSVs(uploaded, returned)
+	Logger.Info("Compared uploaded CSV with Fester's response",
+		zap.String("filename", filename),
+		zap.Int("rows", diff.Rows),
+		zap.Int("changed_rows", diff.ChangedRows),
+		zap.Int("changed_cells", diff.ChangedCells),
+		zap.Int("gained_manifest_urls", diff.GainedManifestURLs))
+	fmt.Fprintf(humanOutput(), "%s: %d of %d rows gained manifest URLs\n", filename, diff.GainedManifestURLs,
+		diff.Rows)
+
+	for _, itemARK := range diff.MissingManifestURLs {
+		Logger.Warn("Row has no manifest URL after upload",
+			zap.String("filename", filename),
+			zap.String("item_ark", itemARK))
+		fmt.Fprintf(humanOutput(), "%s: %s has no manifest URL\n", filename, itemARK)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDiffCSVs tests that changed cells and gained manifest URLs are counted and rows left without one listed
+func TestDiffCSVs(t *testing.T) {
+	uploaded := [][]string{
+		{"Item ARK", "Object Type", "Title"},
+		{"ark:/21198/zz00091vxj", "Collection", "Ballin papers"},
+		{"ark:/21198/zz00093cw5", "Work", "Sight Taste mural"},
+		{"ark:/21198/zz00093cxp", "Work", "Getz House"},
+		{"ark:/21198/zz00093d0x", "Page", "Page 1"},
+	}
+	returned := [][]string{
+		{"Item ARK", "Object Type", "Title", "IIIF Manifest URL"},
+		{"ark:/21198/zz00091vxj", "Collection", "Ballin papers", "https://iiif.library.ucla.edu/collections/1"},
+		{"ark:/21198/zz00093cw5", "Work", "Sight Taste mural", "https://iiif.library.ucla.edu/2/manifest"},
+		{"ark:/21198/zz00093cxp", "Work", "Getz House", ""},
+		{"ark:/21198/zz00093d0x", "Page", "Page 1", ""},
+	}
+
+	assert.Equal(t, CSVDiff{
+		Rows:                4,
+		ChangedRows:         2,
+		ChangedCells:        2,
+		GainedManifestURLs:  2,
+		MissingManifestURLs: []string{"ark:/21198/zz00093cxp"},
+	}, DiffCSVs(uploaded, returned))
+}
+
+// TestDiffCSVsFixtures tests a before and after pair of test CSVs that already had their manifest URLs
+func TestDiffCSVsFixtures(t *testing.T) {
+	uploaded, err := readCSVRecords(TestDirUnFester + "/ballin.csv")
+	assert.Nil(t, err)
+	returned, err := readCSVRecords(TestDirFester + "/ballin.csv")
+	assert.Nil(t, err)
+
+	diff := DiffCSVs(uploaded, returned)
+	assert.Equal(t, 4, diff.Rows)
+	assert.Equal(t, 0, diff.GainedManifestURLs)
+	assert.Empty(t, diff.MissingManifestURLs)
+}
+
+// TestLogDiffSummary tests that the summary is printed and rows without manifest URLs are logged
+func TestLogDiffSummary(t *testing.T) {
+	logger, sink := createLogger()
+	Logger = logger
+
+	body := []byte("Item ARK,Object Type,IIIF Manifest URL\n" +
+		"ark:/21198/zz00093cw5,Work,https://iiif.library.ucla.edu/2/manifest\n")
+	path := t.TempDir() + "/works.csv"
+	if err := os.WriteFile(path, []byte("Item ARK,Object Type\nark:/21198/zz00093cw5,Work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := captureStdout(t, func() { logDiffSummary(path, body, CSVInput{}, nil) })
+	assert.Equal(t, "works.csv: 1 of 1 rows gained manifest URLs\n", output)
+	assert.Contains(t, sink.String(), `"gained_manifest_urls":1`)
+	assert.NotContains(t, sink.String(), "Row has no manifest URL after upload")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// DuplicateARK is a row whose Item ARK was already used by an earlier row of the batch, in the same file or
+// another one; rows are numbered from the header, which is row 1
+type DuplicateARK struct {
+	ItemARK   string
+	Path      string
+	Row       int
+	FirstPath string
+	FirstRow  int
+}
+
+// String formats the duplicate for people to read
+func (d DuplicateARK) String() string {
+	return fmt.Sprintf("%s row %d: Item ARK %s is already used by %s row %d", d.Path, d.Row, d.ItemARK,
+		d.FirstPath, d.FirstRow)
+}
+
+// FindDuplicateARKs finds the rows of the batch whose Item ARK was already used by an earlier row, since
+// Fester may create conflicting manifests for them
+func FindDuplicateARKs(batch []BatchFile) []DuplicateARK {
+	type location struct {
+		path string
+		row  int
+	}
+	firstUses := map[string]location{}
+
+	var duplicates []DuplicateARK
+	for _, file := range batch {
+		itemIndex := columnIndex(file.Records[0], itemARKColumn)
+		if itemIndex == -1 {
+			continue
+		}
+
+		for rowIndex, row := range file.Records[1:] {
+			itemARK := cell(row, itemIndex)
+			if itemARK == "" {
+				continue
+			}
+			if first, found := firstUses[itemARK]; found {
+				duplicates = append(duplicates, DuplicateARK{itemARK, file.Path, rowIndex + 2, first.path, first.row})
+				continue
+			}
+			firstUses[itemARK] = location{file.Path, rowIndex + 2}
+		}
+	}
+	return duplicates
+}
+
+// checkDuplicateARKs logs the rows of the batch that reuse an Item ARK, returning an error in strict mode so
+// that nothing is uploaded
+func checkDuplicateARKs(batch []BatchFile, strict bool) error {
+	duplicates := FindDuplicateARKs(batch)
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	for _, duplicate := range duplicates {
+		Logger.Warn("Item ARK used more than once in the provided files",
+			zap.String("item_ark", duplicate.ItemARK),
+			zap.String("path", duplicate.Path),
+			zap.Int("row", duplicate.Row),
+			zap.String("first_path", duplicate.FirstPath),
+			zap.Int("first_row", duplicate.FirstRow))
+		fmt.Fprintln(humanOutput(), duplicate)
+	}
+
+	if strict {
+		return fmt.Errorf("%d rows reuse an Item ARK from earlier in the batch", len(duplicates))
+	}
+	fmt.Fprintf(humanOutput(), "Warning: %d rows reuse an Item ARK from earlier in the batch, so Fester may "+
+		"create conflicting manifests for them\n", len(duplicates))
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDuplicateWorksCSV = `Item ARK,Parent ARK,Object Type
+ark:/21198/zz0002,ark:/21198/zz0001,Work
+ark:/21198/zz0002,ark:/21198/zz0001,Work
+ark:/21198/zz0001,,Collection
+`
+
+// TestFindDuplicateARKsWithinFile tests that an ARK used twice in one file is reported against its first use
+func TestFindDuplicateARKsWithinFile(t *testing.T) {
+	works := writeTestCSV(t, "works.csv", testDuplicateWorksCSV)
+
+	duplicates := FindDuplicateARKs(readBatch([]string{works}, CSVInput{}))
+	assert.Equal(t, []DuplicateARK{
+		{"ark:/21198/zz0002", works, 3, works, 2},
+	}, duplicates)
+}
+
+// TestFindDuplicateARKsAcrossFiles tests that an ARK used in two files is reported against the earlier file
+func TestFindDuplicateARKsAcrossFiles(t *testing.T) {
+	collection := writeTestCSV(t, "collection.csv", testCollectionCSV)
+	works := writeTestCSV(t, "works.csv", testDuplicateWorksCSV)
+
+	duplicates := FindDuplicateARKs(readBatch([]string{collection, works}, CSVInput{}))
+	assert.Equal(t, []DuplicateARK{
+		{"ark:/21198/zz0002", works, 3, works, 2},
+		{"ark:/21198/zz0001", works, 4, collection, 2},
+	}, duplicates)
+	assert.Equal(t, works+" row 4: Item ARK ark:/21198/zz0001 is already used by "+collection+" row 2",
+		duplicates[1].String())
+}
+
+// TestFindDuplicateARKsFixtures tests that the test CSVs don't share any ARKs
+func TestFindDuplicateARKsFixtures(t *testing.T) {
+	paths, err := filepath.Glob(TestDirUnFester + "/*.csv")
+	assert.Nil(t, err)
+
+	assert.Empty(t, FindDuplicateARKs(readBatch(paths, CSVInput{})))
+}
+
+// TestRunDuplicateARKs tests that duplicate ARKs stop a strict run before anything is uploaded, and are only
+// warned about otherwise
+func TestRunDuplicateARKs(t *testing.T) {
+	h := newRunHarness(t, respondWith(http.StatusCreated, "Festerized CSV"))
+	works := writeTestCSV(t, "works.csv", testDuplicateWorksCSV)
+
+	cfg := testConfig(t, h.server.URL, works)
+	cfg.StrictMode = true
+	exitCode, results, err := run(context.Background(), cfg)
+	assert.Equal(t, int(INVALID_CSV_SPECIFIED), exitCode)
+	assert.Error(t, err)
+	assert.Empty(t, results)
+
+	cfg = testConfig(t, h.server.URL, works)
+	exitCode, results, err = run(context.Background(), cfg)
+	assert.Equal(t, 0, exitCode)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Contains(t, h.log.String(), "Item ARK used more than once in the provided files")
+}
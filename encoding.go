@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// Encodings that --detect-encoding recognizes
+const (
+	encodingUTF8        string = "UTF-8"
+	encodingUTF8BOM     string = "UTF-8 with BOM"
+	encodingUTF16LE     string = "UTF-16LE"
+	encodingUTF16BE     string = "UTF-16BE"
+	encodingLatin1      string = "ISO-8859-1"
+	encodingWindows1252 string = "Windows-1252"
+)
+
+// Byte order marks that identify a CSV's encoding
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// windows1252 maps the bytes Windows-1252 uses for printable characters where ISO-8859-1 has control codes;
+// the five bytes it leaves undefined are read as ISO-8859-1
+var windows1252 = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡', 0x88: 'ˆ', 0x89: '‰',
+	0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•',
+	0x96: '–', 0x97: '—', 0x98: '˜', 0x99: '™', 0x9A: 'š', 0x9B: '›', 0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+// DetectEncoding guesses the encoding of a CSV from its byte order mark, or failing that from its bytes:
+// valid UTF-8 is taken as UTF-8, and anything else without NUL bytes as a single-byte Latin encoding. It
+// reports whether it's sure; when it isn't, the CSV should be read as UTF-8.
+func DetectEncoding(data []byte) (string, bool) {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return encodingUTF8BOM, true
+	case bytes.HasPrefix(data, utf16LEBOM):
+		return encodingUTF16LE, true
+	case bytes.HasPrefix(data, utf16BEBOM):
+		return encodingUTF16BE, true
+	case utf8.Valid(data):
+		return encodingUTF8, true
+	case bytes.IndexByte(data, 0) >= 0:
+		// Probably UTF-16 without a byte order mark, which can't be told apart from binary
+		return encodingUTF8, false
+	}
+
+	for _, b := range data {
+		if _, found := windows1252[b]; found {
+			return encodingWindows1252, true
+		}
+	}
+	return encodingLatin1, true
+}
+
+// TranscodeToUTF8 converts a CSV in the supplied encoding to UTF-8, dropping any byte order mark
+func TranscodeToUTF8(data []byte, encoding string) []byte {
+	switch encoding {
+	case encodingUTF8BOM:
+		return bytes.TrimPrefix(data, utf8BOM)
+	case encodingUTF16LE, encodingUTF16BE:
+		return decodeUTF16(data[len(utf16LEBOM):], encoding == encodingUTF16BE)
+	case encodingLatin1, encodingWindows1252:
+		buffer := make([]byte, 0, len(data)+len(data)/8)
+		for _, b := range data {
+			char, found := windows1252[b]
+			if !found || encoding == encodingLatin1 {
+				char = rune(b)
+			}
+			buffer = utf8.AppendRune(buffer, char)
+		}
+		return buffer
+	default:
+		return data
+	}
+}
+
+// decodeUTF16 decodes UTF-16 text, without its byte order mark, to UTF-8
+func decodeUTF16(data []byte, bigEndian bool) []byte {
+	units := make([]uint16, len(data)/2)
+	for index := range units {
+		if bigEndian {
+			units[index] = uint16(data[2*index])<<8 | uint16(data[2*index+1])
+		} else {
+			units[index] = uint16(data[2*index+1])<<8 | uint16(data[2*index])
+		}
+	}
+
+	buffer := make([]byte, 0, len(data))
+	for _, char := range utf16.Decode(units) {
+		buffer = utf8.AppendRune(buffer, char)
+	}
+	return buffer
+}
+
+// decodeCSV reads a whole CSV and returns it as UTF-8, along with the encoding it was detected as
+func decodeCSV(reader io.Reader) ([]byte, string, bool, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", false, err
+	}
+	encoding, certain := DetectEncoding(data)
+	return TranscodeToUTF8(data, encoding), encoding, certain, nil
+}
+
+// logEncoding logs the encoding a CSV was detected as, warning when it's read as UTF-8 for want of a better guess
+func logEncoding(path, filename string) {
+	file, err := openRawCSV(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	_, encoding, certain, err := decodeCSV(file)
+	if err != nil {
+		return
+	} else if !certain {
+		Logger.Warn("Could not detect CSV encoding, reading it as UTF-8", zap.String("filename", filename))
+		return
+	}
+	Logger.Info("Detected CSV encoding", zap.String("filename", filename), zap.String("encoding", encoding))
+}
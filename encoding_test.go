@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDirEncoding holds the same CSV saved in different encodings
+var TestDirEncoding string = "test/test-resources/encoding"
+
+// encodingTitles are the titles of the CSVs in TestDirEncoding
+var encodingTitles = []string{"Title", "Café Société", "Élégie à Zoë"}
+
+// TestDetectEncoding tests detecting the encoding of each fixture
+func TestDetectEncoding(t *testing.T) {
+	for filename, expected := range map[string]string{
+		"utf8.csv":     encodingUTF8,
+		"utf8-bom.csv": encodingUTF8BOM,
+		"latin1.csv":   encodingLatin1,
+	} {
+		t.Run(filename, func(t *testing.T) {
+			data, err := os.ReadFile(TestDirEncoding + "/" + filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			encoding, certain := DetectEncoding(data)
+			assert.Equal(t, expected, encoding)
+			assert.True(t, certain)
+		})
+	}
+}
+
+// TestDetectEncodingGuesses tests the encodings told apart by their bytes alone, and the fallback to UTF-8
+func TestDetectEncodingGuesses(t *testing.T) {
+	encoding, certain := DetectEncoding([]byte("Title\n\x93Quoted\x94\n"))
+	assert.Equal(t, encodingWindows1252, encoding)
+	assert.True(t, certain)
+
+	encoding, _ = DetectEncoding([]byte{0xFF, 0xFE, 'A', 0})
+	assert.Equal(t, encodingUTF16LE, encoding)
+
+	encoding, certain = DetectEncoding([]byte{'A', 0, 0xE9, 0})
+	assert.Equal(t, encodingUTF8, encoding)
+	assert.False(t, certain)
+}
+
+// TestTranscodeToUTF8 tests converting text in each encoding to UTF-8
+func TestTranscodeToUTF8(t *testing.T) {
+	assert.Equal(t, "Café", string(TranscodeToUTF8([]byte("\ufeffCafé"), encodingUTF8BOM)))
+	assert.Equal(t, "Café", string(TranscodeToUTF8([]byte("Caf\xe9"), encodingLatin1)))
+	assert.Equal(t, "“Café”", string(TranscodeToUTF8([]byte("\x93Caf\xe9\x94"), encodingWindows1252)))
+	assert.Equal(t, "Hé", string(TranscodeToUTF8([]byte{0xFF, 0xFE, 'H', 0, 0xE9, 0}, encodingUTF16LE)))
+	assert.Equal(t, "Hé", string(TranscodeToUTF8([]byte{0xFE, 0xFF, 0, 'H', 0, 0xE9}, encodingUTF16BE)))
+}
+
+// TestOpenCSVDetectEncoding tests that every fixture reads the same once --detect-encoding is on
+func TestOpenCSVDetectEncoding(t *testing.T) {
+	input := CSVInput{DetectEncoding: true}
+
+	// Without detection, Latin-1 is read as invalid UTF-8
+	raw, err := CSVInput{}.readRecords(TestDirEncoding + "/latin1.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, encodingTitles[1], raw[1][3])
+
+	for _, filename := range []string{"utf8.csv", "utf8-bom.csv", "latin1.csv"} {
+		records, err := input.readRecords(TestDirEncoding + "/" + filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "Item ARK", records[0][0], filename)
+		assert.Equal(t, encodingTitles, []string{records[0][3], records[1][3], records[2][3]}, filename)
+
+		file, err := input.open(TestDirEncoding + "/" + filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(file)
+		file.Close()
+		assert.NotContains(t, string(content), "\ufeff", filename)
+	}
+}
+
+// TestRunDetectEncoding tests that a run that detects encodings logs the encoding of each file it uploads
+func TestRunDetectEncoding(t *testing.T) {
+	h := newRunHarness(t, respondWith(http.StatusCreated, "Festerized CSV"))
+	cfg := testConfig(t, h.server.URL, TestDirEncoding+"/latin1.csv")
+	cfg.Input.DetectEncoding = true
+
+	exitCode, _, err := run(context.Background(), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, h.log.String(), "Detected CSV encoding")
+	assert.Contains(t, h.log.String(), "latin1.csv")
+}
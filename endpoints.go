@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultServer is the Fester server festerize talks to unless told otherwise
+const defaultServer string = "https://test.ingest.iiif.library.ucla.edu"
+
+// endpointProbeTimeout bounds how long listing the endpoints waits for the server
+const endpointProbeTimeout = 10 * time.Second
+
+// Endpoint is a Fester endpoint that festerize knows about
+type Endpoint struct {
+	Path        string
+	Method      string
+	Description string
+}
+
+// knownEndpoints are the Fester endpoints festerize knows about
+var knownEndpoints = []Endpoint{
+	{"/collections", http.MethodPost, "Upload a CSV of collection, work, and page rows (used by festerize)"},
+	{"/fester/status", http.MethodGet, "Report whether Fester is up (checked before every run)"},
+	{"/thumbnails", http.MethodPost, "Add thumbnail URLs to a CSV (not used by festerize)"},
+}
+
+// Availability of an endpoint on the server
+const (
+	EndpointAvailable string = "available"
+	EndpointMissing   string = "not found"
+	EndpointUnknown   string = "unknown"
+)
+
+// EndpointResult is a known endpoint along with whether the server has it
+type EndpointResult struct {
+	Endpoint
+	Availability string
+}
+
+var endpointsServer string
+
+// Lists the Fester endpoints festerize knows about
+var endpointsCmd = &cobra.Command{
+	Use:   "endpoints",
+	Short: "List the Fester endpoints festerize knows about, and whether the server has them",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithTimeout(context.Background(), endpointProbeTimeout)
+		defer cancel()
+
+		results, err := ProbeEndpoints(ctx, http.DefaultClient, endpointsServer)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not reach %s (%v); listing the known endpoints only\n", endpointsServer, err)
+		}
+		if err := writeEndpoints(os.Stdout, results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	},
+}
+
+// ProbeEndpoints checks which of the known endpoints the server has; a GET to an upload endpoint is refused,
+// but only one that's missing gets a 404. If the server can't be reached, the known endpoints are returned
+// with their availability unknown, along with the error
+func ProbeEndpoints(ctx context.Context, client *http.Client, server string) ([]EndpointResult, error) {
+	results := make([]EndpointResult, 0, len(knownEndpoints))
+	for _, endpoint := range knownEndpoints {
+		results = append(results, EndpointResult{endpoint, EndpointUnknown})
+	}
+
+	for index, result := range results {
+		url := endpointURL(server, result.Path)
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return results, err
+		}
+		request.Header.Set("User-Agent", userAgent())
+
+		response, err := client.Do(request)
+		if err != nil {
+			return results, err
+		}
+		response.Body.Close()
+
+		if response.StatusCode == http.StatusNotFound {
+			results[index].Availability = EndpointMissing
+		} else {
+			results[index].Availability = EndpointAvailable
+		}
+	}
+	return results, nil
+}
+
+// writeEndpoints writes the endpoints as a table
+func writeEndpoints(w io.Writer, results []EndpointResult) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "PATH\tMETHOD\tSERVER\tDESCRIPTION")
+	for _, result := range results {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", result.Path, result.Method, result.Availability, result.Description)
+	}
+	return table.Flush()
+}
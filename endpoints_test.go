@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestProbeEndpoints tests that endpoints answering 404 are reported missing and any other answer available
+func TestProbeEndpoints(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fester/status":
+			w.WriteHeader(http.StatusOK)
+		case "/collections":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	results, err := ProbeEndpoints(context.Background(), ts.Client(), ts.URL+"/")
+	assert.Nil(t, err)
+
+	availability := map[string]string{}
+	for _, result := range results {
+		availability[result.Path] = result.Availability
+	}
+	assert.Equal(t, map[string]string{
+		"/collections":   EndpointAvailable,
+		"/fester/status": EndpointAvailable,
+		"/thumbnails":    EndpointMissing,
+	}, availability)
+}
+
+// TestProbeEndpointsUnreachable tests that the known endpoints are still listed when the server can't be reached
+func TestProbeEndpointsUnreachable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+
+	results, err := ProbeEndpoints(context.Background(), http.DefaultClient, ts.URL)
+	assert.NotNil(t, err)
+	assert.Len(t, results, len(knownEndpoints))
+	for _, result := range results {
+		assert.Equal(t, EndpointUnknown, result.Availability)
+	}
+}
+
+// TestWriteEndpoints tests that the endpoints are written as a table with a header
+func TestWriteEndpoints(t *testing.T) {
+	var buffer bytes.Buffer
+	assert.Nil(t, writeEndpoints(&buffer, []EndpointResult{{knownEndpoints[0], EndpointAvailable}}))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "PATH"))
+	assert.Equal(t, strings.Index(lines[0], "SERVER"), strings.Index(lines[1], EndpointAvailable))
+	assert.Contains(t, lines[1], "/collections")
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+)
+
+// defaultEnvFile is the env file loaded when --env-file isn't given
+const defaultEnvFile string = ".env"
+
+// LoadEnvFile loads environment variables, such as credentials, from an env file. Variables that are already set
+// aren't overridden. A missing file is only an error when its path was given explicitly.
+func LoadEnvFile(path string, explicit bool) error {
+	if err := godotenv.Load(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			Logger.Debug("No env file found", zap.String("path", path))
+			return nil
+		}
+		return fmt.Errorf("can't load env file %s: %w", path, err)
+	}
+	Logger.Debug("Loaded env file", zap.String("path", path))
+	return nil
+}
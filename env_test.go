@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLoadEnvFile tests that credentials are loaded from a supplied env file
+func TestLoadEnvFile(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	envPath := filepath.Join(t.TempDir(), "festerize.env")
+	if err := os.WriteFile(envPath, []byte(tokenEnvVar+"=abc123\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(tokenEnvVar, "")
+	os.Unsetenv(tokenEnvVar)
+
+	assert.Nil(t, LoadEnvFile(envPath, true))
+	assert.Equal(t, "abc123", os.Getenv(tokenEnvVar))
+}
+
+// TestLoadEnvFileMissing tests that a missing env file is only an error when it was asked for
+func TestLoadEnvFileMissing(t *testing.T) {
+	logger, sink := createLogger()
+	Logger = logger
+
+	missing := filepath.Join(t.TempDir(), ".env")
+
+	assert.Nil(t, LoadEnvFile(missing, false))
+	assert.Contains(t, sink.String(), "No env file found")
+
+	assert.NotNil(t, LoadEnvFile(missing, true))
+}
+
+// TestCredentialsResolvedOnce tests that credentials are resolved once at startup, rather than for each file,
+// so changing the env file or the environment partway through a run doesn't affect the remaining uploads
+func TestCredentialsResolvedOnce(t *testing.T) {
+	var mutex sync.Mutex
+	var authorizations []string
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		mutex.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	envPath := filepath.Join(t.TempDir(), "festerize.env")
+	if err := os.WriteFile(envPath, []byte(tokenEnvVar+"=abc123\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(tokenEnvVar, "")
+	os.Unsetenv(tokenEnvVar)
+	defer func() { authToken = "" }()
+
+	assert.Nil(t, LoadEnvFile(envPath, true))
+	assert.Nil(t, ValidateAuth())
+
+	// Neither a changed env file nor a changed environment should be picked up by the run
+	if err := os.WriteFile(envPath, []byte(tokenEnvVar+"=changed\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(tokenEnvVar, "changed")
+
+	cfg := testConfig(t, h.server.URL, TestDirUnFester+"/ballin.csv", TestDirUnFester+"/chandler.csv",
+		TestDirUnFester+"/chase.csv")
+	exitCode, _, err := run(context.Background(), cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, []string{"Bearer abc123", "Bearer abc123", "Bearer abc123"}, authorizations)
+	assert.Equal(t, 1, strings.Count(h.log.String(), "Loaded env file"))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// errorBodyExtension returns the filename extension for a saved error response, going by its content type
+func errorBodyExtension(response *http.Response) string {
+	mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil {
+		return ".txt"
+	}
+
+	switch mediaType {
+	case "text/html":
+		return ".html"
+	case "application/json":
+		return ".json"
+	case "text/csv":
+		return ".csv"
+	default:
+		return ".txt"
+	}
+}
+
+// SaveErrorBody writes the full body of a failed upload's response to the directory, in a file named after
+// the input, and returns the file's path
+func SaveErrorBody(dir, inputPath string, response *http.Response, body []byte) (string, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, filepath.Base(inputPath)+".error"+errorBodyExtension(response))
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// saveErrorBody saves a failed upload's response body, when --save-errors is set, logging where it went
+func saveErrorBody(dir, inputPath string, response *http.Response, body []byte) {
+	if dir == "" {
+		return
+	}
+
+	path, err := SaveErrorBody(dir, inputPath, response, body)
+	if err != nil {
+		Logger.Warn("Could not save Fester's error response",
+			zap.String("filename", filepath.Base(inputPath)),
+			zap.Error(err))
+		return
+	}
+	Logger.Info("Saved Fester's error response",
+		zap.String("filename", filepath.Base(inputPath)),
+		zap.String("path", path))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestErrorBodyExtension tests that saved error responses are named for their content type
+func TestErrorBodyExtension(t *testing.T) {
+	tests := []struct {
+		contentType string
+		extension   string
+	}{
+		{"text/html; charset=utf-8", ".html"},
+		{"application/json", ".json"},
+		{"text/plain", ".txt"},
+		{"", ".txt"},
+	}
+
+	for _, tc := range tests {
+		response := &http.Response{Header: http.Header{"Content-Type": []string{tc.contentType}}}
+		assert.Equal(t, tc.extension, errorBodyExtension(response), tc.contentType)
+	}
+}
+
+// TestRunSaveErrors tests that the full response of a failed upload is saved, and nothing is for a success
+func TestRunSaveErrors(t *testing.T) {
+	newRunHarness(t, nil)
+
+	errorPage := `<html><body><p id="error-message">Collection not found</p><pre>stack trace</pre></body></html>`
+	tests := []struct {
+		name       string
+		uploadCode int
+		saved      bool
+	}{
+		{"Failure", http.StatusInternalServerError, true},
+		{"Success", http.StatusCreated, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newRunStub(t, http.StatusOK, tc.uploadCode, errorPage)
+			cfg := testConfig(t, ts.URL, TestDirUnFester+"/ballin.csv")
+			cfg.SaveErrorsDir = filepath.Join(t.TempDir(), "errors")
+
+			_, _, err := run(context.Background(), cfg)
+			assert.Nil(t, err)
+
+			saved, err := os.ReadFile(filepath.Join(cfg.SaveErrorsDir, "ballin.csv.error.html"))
+			if tc.saved {
+				assert.Nil(t, err)
+				assert.Equal(t, errorPage, string(saved))
+			} else {
+				assert.True(t, os.IsNotExist(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envReference matches a ${VAR} reference to an environment variable; a bare $VAR isn't expanded, so that a
+// dollar sign in a password or path is left alone
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandEnv replaces the ${VAR} references in a flag's value with the values of the environment variables.
+// A reference to a variable that isn't set is an error, as is a value that expands to nothing, rather than
+// silently running against an empty server or path.
+func ExpandEnv(flag, value string) (string, error) {
+	var unset []string
+	expanded := envReference.ReplaceAllStringFunc(value, func(reference string) string {
+		name := envReference.FindStringSubmatch(reference)[1]
+		variable, found := os.LookupEnv(name)
+		if !found {
+			unset = append(unset, name)
+		}
+		return variable
+	})
+
+	switch {
+	case len(unset) > 0:
+		return "", fmt.Errorf("--%s refers to environment variables that aren't set: %s", flag,
+			strings.Join(unset, ", "))
+	case value != "" && expanded == "":
+		return "", fmt.Errorf("--%s %s expands to nothing", flag, value)
+	default:
+		return expanded, nil
+	}
+}
+
+// ValidateServerURL checks that a server is an absolute HTTP or HTTPS URL, such as after it's been expanded
+func ValidateServerURL(server string) error {
+	parsedURL, err := url.Parse(server)
+	if err != nil {
+		return fmt.Errorf("invalid server %q: %w", server, err)
+	}
+	if (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return fmt.Errorf("invalid server %q: expected an http or https URL", server)
+	}
+	return nil
+}
+
+// loadEnvAndExpandFlags loads the env file and then expands the other flags, so that they can refer to the
+// variables it sets; only --env-file itself is expanded against the process environment alone
+func loadEnvAndExpandFlags(explicitEnvFile bool) error {
+	expanded, err := ExpandEnv("env-file", envFile)
+	if err != nil {
+		return err
+	}
+	envFile = expanded
+
+	if err := LoadEnvFile(envFile, explicitEnvFile); err != nil {
+		return err
+	}
+	return expandFlags()
+}
+
+// expandFlags expands the environment variables referred to by the flags that hold URLs and paths, and
+// checks the servers they expand to
+func expandFlags() error {
+	paths := []struct {
+		flag  string
+		value *string
+	}{
+		{"out", &out},
+		{"base-path", &basePath},
+		{"iiifhost", &iiifhost},
+		{"report", &reportFile},
+		{"metrics-file", &metricsFile},
+		{"retry-report", &retryReportFile},
+		{"save-errors", &saveErrorsDir},
+		{"schema-file", &schemaFile},
+	}
+
+	var errs []error
+	for _, path := range paths {
+		expanded, err := ExpandEnv(path.flag, *path.value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		*path.value = expanded
+	}
+
+	for index, server := range servers {
+		expanded, err := ExpandEnv("server", server)
+		if err == nil {
+			err = ValidateServerURL(expanded)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		servers[index] = expanded
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// How failures are counted towards --max-failures
+const (
+	FailureModeTotal       string = "total"
+	FailureModeConsecutive string = "consecutive"
+)
+
+// ValidateFailureMode validates the failure counting mode
+func ValidateFailureMode() error {
+	switch failureMode {
+	case FailureModeTotal, FailureModeConsecutive:
+		return nil
+	default:
+		return errors.New("invalid failure mode. Allowed values are total or consecutive")
+	}
+}
+
+// countFailures counts the failed results, either all of them or only those since the last success; skipped
+// files don't count either way
+func countFailures(results []FileResult, mode string) int {
+	failures := 0
+	for index := len(results) - 1; index >= 0; index-- {
+		switch results[index].Status {
+		case StatusSuccess:
+			if mode == FailureModeConsecutive {
+				return failures
+			}
+		case StatusFailure:
+			failures++
+		}
+	}
+	return failures
+}
+
+// FailureLimitReached checks whether the run has failed often enough to stop; a limit of zero never stops it
+func FailureLimitReached(results []FileResult, limit int, mode string) error {
+	if limit <= 0 {
+		return nil
+	}
+	if failures := countFailures(results, mode); failures >= limit {
+		if mode == FailureModeConsecutive {
+			return fmt.Errorf("%d files failed in a row", failures)
+		}
+		return fmt.Errorf("%d files failed", failures)
+	}
+	return nil
+}
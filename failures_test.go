@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFailureLimitReached tests counting total and consecutive failures against the limit
+func TestFailureLimitReached(t *testing.T) {
+	results := []FileResult{
+		{Status: StatusFailure},
+		{Status: StatusFailure},
+		{Status: StatusSuccess},
+		{Status: StatusFailure},
+	}
+
+	assert.Equal(t, 3, countFailures(results, FailureModeTotal))
+	assert.Equal(t, 1, countFailures(results, FailureModeConsecutive))
+
+	assert.NotNil(t, FailureLimitReached(results, 3, FailureModeTotal))
+	assert.Nil(t, FailureLimitReached(results, 4, FailureModeTotal))
+	assert.Nil(t, FailureLimitReached(results, 2, FailureModeConsecutive))
+	assert.NotNil(t, FailureLimitReached(append(results, FileResult{Status: StatusFailure}), 2, FailureModeConsecutive))
+	assert.Nil(t, FailureLimitReached(results, 0, FailureModeTotal))
+}
+
+// TestCountFailuresSkipped tests that skipped files neither count as failures nor end a run of them
+func TestCountFailuresSkipped(t *testing.T) {
+	results := []FileResult{
+		{Status: StatusSuccess},
+		{Status: StatusFailure},
+		{Status: StatusSkipped},
+		{Status: StatusFailure},
+	}
+
+	assert.Equal(t, 2, countFailures(results, FailureModeTotal))
+	assert.Equal(t, 2, countFailures(results, FailureModeConsecutive))
+}
+
+// TestRunStopsAtMaxFailures tests that a run stops once the failure limit is reached
+func TestRunStopsAtMaxFailures(t *testing.T) {
+	h := newRunHarness(t, respondWith(http.StatusInternalServerError, ""))
+	cfg := testConfig(t, h.server.URL, TestDirUnFester+"/ballin.csv", TestDirUnFester+"/chase.csv",
+		TestDirUnFester+"/edson.csv", TestDirUnFester+"/horsley.csv")
+	cfg.MaxFailures, cfg.FailureMode = 2, FailureModeTotal
+
+	exitCode, results, err := run(context.Background(), cfg)
+
+	assert.Equal(t, int(TOO_MANY_FAILURES), exitCode)
+	assert.NotNil(t, err)
+	assert.Len(t, results, 2)
+	assert.Contains(t, h.log.String(), `"unprocessed":2`)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"go.uber.org/zap"
+)
+
+// maxErrorSnippetLength is the number of characters of an unrecognized error page used as its error
+const maxErrorSnippetLength int = 200
+
+// The elements of Fester's error pages that each part of the error is read from, in order of preference
+var (
+	errorTitleSelectors     = []string{"#error-title", ".error-title", "h1", "title"}
+	errorDetailSelectors    = []string{"#error-message", ".error-message", "#error-detail", ".error-detail"}
+	errorRequestIDSelectors = []string{"#request-id", ".request-id"}
+	errorTraceSelectors     = []string{"#stack-trace", ".stack-trace", "pre"}
+)
+
+// FesterError is the error Fester describes on the page it returns for a failed upload
+type FesterError struct {
+	Title     string
+	Detail    string
+	RequestID string
+	Trace     string
+}
+
+// Error returns the detail of the error, or its title if it has no detail
+func (festerError FesterError) Error() string {
+	if festerError.Detail != "" {
+		return festerError.Detail
+	}
+	return festerError.Title
+}
+
+// ParseFesterError reads the parts of an error from Fester's error page; a page with neither a title nor a
+// detail, such as a plain text response from a proxy, is described by the start of its text instead
+func ParseFesterError(body []byte) (FesterError, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return FesterError{}, err
+	}
+
+	festerError := FesterError{
+		Title:     firstText(doc, errorTitleSelectors),
+		Detail:    firstText(doc, errorDetailSelectors),
+		RequestID: firstText(doc, errorRequestIDSelectors),
+		Trace:     firstText(doc, errorTraceSelectors),
+	}
+	if festerError.RequestID == "" {
+		festerError.RequestID, _ = doc.Find("[data-request-id]").First().Attr("data-request-id")
+	}
+
+	if festerError.Title == "" && festerError.Detail == "" {
+		text := strings.Join(strings.Fields(doc.Text()), " ")
+		festerError.Detail = truncate(text, maxErrorSnippetLength)
+	}
+	return festerError, nil
+}
+
+// firstText returns the trimmed text of the first of the selectors to match a non-empty element
+func firstText(doc *goquery.Document, selectors []string) string {
+	for _, selector := range selectors {
+		if text := strings.TrimSpace(doc.Find(selector).First().Text()); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// zapFields returns the parts of the error as log fields, leaving out the ones the page didn't have
+func (festerError FesterError) zapFields() []zap.Field {
+	fields := []zap.Field{zap.String("error", festerError.Error())}
+	if festerError.Title != "" {
+		fields = append(fields, zap.String("error_title", festerError.Title))
+	}
+	if festerError.RequestID != "" {
+		fields = append(fields, zap.String("request_id", festerError.RequestID))
+	}
+	if festerError.Trace != "" {
+		fields = append(fields, zap.String("trace", truncate(festerError.Trace, maxLoggedBodyLength)))
+	}
+	return fields
+}
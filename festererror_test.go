@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDirErrorPages is the directory holding the error page fixtures
+var TestDirErrorPages = "test/test-resources/error-pages"
+
+// TestParseFesterErrorFesterPage tests that every part of Fester's own error page is read
+func TestParseFesterErrorFesterPage(t *testing.T) {
+	body, err := os.ReadFile(TestDirErrorPages + "/fester-error.html")
+	assert.Nil(t, err)
+
+	festerError, err := ParseFesterError(body)
+	assert.Nil(t, err)
+	assert.Equal(t, "400 Bad Request", festerError.Title)
+	assert.Equal(t, "Work ark:/21198/zz00093cw5 has a parent collection that has not been festerized",
+		festerError.Detail)
+	assert.Equal(t, "7f3c2a91-5d1e-4c8b-9a0e-2b6f4d8e1c37", festerError.RequestID)
+	assert.True(t, strings.HasPrefix(festerError.Trace, "edu.ucla.library.iiif.fester.FesterException"))
+	assert.Equal(t, festerError.Detail, festerError.Error())
+}
+
+// TestParseFesterErrorProxyPage tests that a proxy's error page, which has only a title, is described by it
+func TestParseFesterErrorProxyPage(t *testing.T) {
+	body, err := os.ReadFile(TestDirErrorPages + "/proxy-error.html")
+	assert.Nil(t, err)
+
+	festerError, err := ParseFesterError(body)
+	assert.Nil(t, err)
+	assert.Equal(t, FesterError{Title: "502 Bad Gateway"}, festerError)
+	assert.Equal(t, "502 Bad Gateway", festerError.Error())
+}
+
+// TestParseFesterErrorFallback tests that a response without any known elements falls back to its text
+func TestParseFesterErrorFallback(t *testing.T) {
+	festerError, err := ParseFesterError([]byte("upstream connect error or disconnect/reset before headers"))
+	assert.Nil(t, err)
+	assert.Equal(t, "upstream connect error or disconnect/reset before headers", festerError.Error())
+
+	festerError, err = ParseFesterError([]byte(strings.Repeat("overflow ", 100)))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(festerError.Detail, "...(truncated)"))
+}
+
+// TestParseFesterErrorRequestIDAttribute tests that a request ID can be read from a data attribute
+func TestParseFesterErrorRequestIDAttribute(t *testing.T) {
+	page := `<html><body data-request-id="abc-123"><p id="error-message">Collection not found</p></body></html>`
+
+	festerError, err := ParseFesterError([]byte(page))
+	assert.Nil(t, err)
+	assert.Equal(t, "abc-123", festerError.RequestID)
+	assert.Equal(t, "Collection not found", festerError.Error())
+}
+
+// TestFesterErrorLogFields tests that the parts of the error are logged as separate fields
+func TestFesterErrorLogFields(t *testing.T) {
+	logger, sink := createLogger()
+	Logger = logger
+
+	body, err := os.ReadFile(TestDirErrorPages + "/fester-error.html")
+	assert.Nil(t, err)
+	festerError, err := ParseFesterError(body)
+	assert.Nil(t, err)
+
+	Logger.Error("Failed to upload file to Fester", festerError.zapFields()...)
+	assert.Contains(t, sink.String(), `"error_title":"400 Bad Request"`)
+	assert.Contains(t, sink.String(), `"request_id":"7f3c2a91-5d1e-4c8b-9a0e-2b6f4d8e1c37"`)
+	assert.Contains(t, sink.String(), `"trace":"edu.ucla.library.iiif.fester.FesterException`)
+}
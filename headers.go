@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// headerNamePattern matches a valid HTTP header name (an RFC 7230 token)
+var headerNamePattern = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// managedHeaders are set by festerize itself and can't be overridden with --header; use --compress-upload and
+// --idempotency for the Content-Encoding and Idempotency-Key headers
+var managedHeaders = []string{"Authorization", "Content-Type", "Content-Encoding", idempotencyKeyHeader}
+
+// ParseHeaders parses --header values given as key:value into a map of canonical header names to values
+func ParseHeaders(values []string) (map[string]string, error) {
+	headers := make(map[string]string, len(values))
+	for _, value := range values {
+		key, headerValue, found := strings.Cut(value, ":")
+		key = strings.TrimSpace(key)
+		if !found || !headerNamePattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid header %q: expected key:value", value)
+		}
+		if strings.ContainsAny(headerValue, "\r\n") {
+			return nil, fmt.Errorf("invalid header %q: the value can't contain line breaks", key)
+		}
+
+		key = http.CanonicalHeaderKey(key)
+		for _, managed := range managedHeaders {
+			if key == managed {
+				return nil, fmt.Errorf("the %s header is set by festerize and can't be changed with --header", key)
+			}
+		}
+		headers[key] = strings.TrimSpace(headerValue)
+	}
+	return headers, nil
+}
+
+// selectHeaders picks the named headers that are present in a response, joining repeated values
+func selectHeaders(headers http.Header, names []string) map[string]string {
+	selected := map[string]string{}
+	for _, name := range names {
+		if values := headers.Values(name); len(values) > 0 {
+			selected[http.CanonicalHeaderKey(name)] = strings.Join(values, ", ")
+		}
+	}
+	return selected
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseHeaders tests that --header values are parsed and invalid ones rejected
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders([]string{"x-api-key: abc123", "X-Route:blue:green"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"X-Api-Key": "abc123", "X-Route": "blue:green"}, headers)
+
+	invalid := []string{
+		"X-Api-Key",
+		": value",
+		"Bad Name: value",
+		"X-Api-Key: abc\r\nX-Injected: 1",
+		"content-type: text/plain",
+		"Authorization: Bearer abc",
+		"Content-Encoding: gzip",
+		"idempotency-key: batch-7",
+	}
+	for _, value := range invalid {
+		_, err := ParseHeaders([]string{value})
+		assert.NotNil(t, err, value)
+	}
+}
+
+// TestPostCSVCustomHeaders tests that custom headers reach the server
+func TestPostCSVCustomHeaders(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		received = request.Header.Clone()
+		writer.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	headers, err := ParseHeaders([]string{"X-Api-Key: abc123"})
+	assert.Nil(t, err)
+	headers["User-Agent"] = userAgent()
+
+	_, _, err = postCSV(context.Background(), "test.csv", strings.NewReader("Item ARK\n"), server.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", Headers: headers})
+	assert.Nil(t, err)
+	assert.Equal(t, "abc123", received.Get("X-Api-Key"))
+	assert.Equal(t, userAgent(), received.Get("User-Agent"))
+	assert.True(t, strings.HasPrefix(received.Get("Content-Type"), "multipart/form-data"))
+}
+
+// TestPostCSVLogsResponseHeaders tests that the configured response headers are logged
+func TestPostCSVLogsResponseHeaders(t *testing.T) {
+	logger, sink := createLogger()
+	Logger = logger
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("X-Request-Id", "req-8f14e45f")
+		writer.Header().Set("X-Other", "not logged")
+		writer.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	_, _, err := postCSV(context.Background(), "test.csv", strings.NewReader("Item ARK\n"), server.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", LogHeaders: []string{"x-request-id", "X-Missing"}})
+	assert.Nil(t, err)
+	assert.Contains(t, sink.String(), "Fester response headers")
+	assert.Contains(t, sink.String(), `"X-Request-Id":"req-8f14e45f"`)
+	assert.NotContains(t, sink.String(), "X-Missing")
+	assert.NotContains(t, sink.String(), "not logged")
+}
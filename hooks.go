@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ParseHook splits a hook command on spaces; hooks aren't run through a shell, so anything more involved
+// belongs in a script
+func ParseHook(command string) []string {
+	return strings.Fields(command)
+}
+
+// hookEnv returns the environment variables that describe an upload to a hook
+func hookEnv(filename, inputPath, status, server string, statusCode int) []string {
+	return []string{
+		"FESTERIZE_FILENAME=" + filename,
+		"FESTERIZE_INPUT=" + inputPath,
+		"FESTERIZE_STATUS=" + status,
+		"FESTERIZE_STATUS_CODE=" + strconv.Itoa(statusCode),
+		"FESTERIZE_SERVER=" + server,
+		"FESTERIZE_BATCH_ID=" + batchID,
+	}
+}
+
+// runHook runs a hook command with the path as its last argument and the supplied variables added to its
+// environment, returning what it wrote to stdout and stderr
+func runHook(ctx context.Context, command []string, path string, vars []string) ([]byte, []byte, error) {
+	cmd := exec.CommandContext(ctx, command[0], append(command[1:], path)...)
+	cmd.Env = append(os.Environ(), vars...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.Bytes(), stderr.Bytes(), fmt.Errorf("hook %s failed: %w", command[0], err)
+	}
+	return stdout.Bytes(), stderr.Bytes(), nil
+}
+
+// RunPostHook runs the post-hook on a saved output CSV, logging what it printed
+func RunPostHook(ctx context.Context, command []string, path string, vars []string) error {
+	stdout, stderr, err := runHook(ctx, command, path, vars)
+	if err != nil {
+		Logger.Error("Post-hook failed",
+			zap.String("output", path),
+			zap.ByteString("stdout", stdout),
+			zap.ByteString("stderr", stderr),
+			zap.Error(err))
+		return err
+	}
+
+	Logger.Info("Post-hook finished",
+		zap.String("output", path),
+		zap.ByteString("stdout", stdout),
+		zap.ByteString("stderr", stderr))
+	return nil
+}
+
+// RunPreHook runs the pre-hook on an input CSV and returns the path of the CSV to upload instead: if the hook
+// wrote to stdout, a temporary file holding what it wrote, and otherwise the input itself, which the hook may
+// have rewritten in place; the returned func removes any temporary file
+func RunPreHook(ctx context.Context, command []string, path string, vars []string) (string, func(), error) {
+	stdout, stderr, err := runHook(ctx, command, path, vars)
+	if err != nil {
+		Logger.Error("Pre-hook failed",
+			zap.String("input", path),
+			zap.ByteString("stderr", stderr),
+			zap.Error(err))
+		return "", nil, err
+	}
+
+	Logger.Info("Pre-hook finished",
+		zap.String("input", path),
+		zap.Int("stdout_bytes", len(stdout)),
+		zap.ByteString("stderr", stderr))
+	if len(stdout) == 0 {
+		return path, func() {}, nil
+	}
+
+	// Keep the input's name, less any compression extension, since it's uploaded under it
+	dir, err := os.MkdirTemp("", "festerize-pre-hook-")
+	if err != nil {
+		return "", nil, err
+	}
+	remove := func() { os.RemoveAll(dir) }
+
+	hookedPath := filepath.Join(dir, csvName(filepath.Base(path)))
+	if err := os.WriteFile(hookedPath, stdout, 0644); err != nil {
+		remove()
+		return "", nil, err
+	}
+	return hookedPath, remove, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeScript writes an executable shell script for a hook to run
+func writeScript(t *testing.T, body string) string {
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestParseHook tests that hook commands are split on spaces
+func TestParseHook(t *testing.T) {
+	assert.Empty(t, ParseHook(""))
+	assert.Equal(t, []string{"push-csv", "--to", "catalog"}, ParseHook(" push-csv  --to catalog "))
+}
+
+// TestRunPostHook tests that the post-hook is run on each saved CSV with the upload described in its environment
+func TestRunPostHook(t *testing.T) {
+	h := newRunHarness(t, respondWith(http.StatusCreated, "Festerized CSV"))
+
+	invocations := filepath.Join(t.TempDir(), "invocations")
+	script := writeScript(t, `echo "$1 $FESTERIZE_FILENAME $FESTERIZE_STATUS $FESTERIZE_STATUS_CODE" >> `+invocations+`
+echo pushed`)
+
+	cfg := testConfig(t, h.server.URL, TestDirUnFester+"/ballin.csv", TestDirUnFester+"/chase.csv")
+	cfg.PostHook = ParseHook(script)
+
+	exitCode, _, err := run(context.Background(), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+
+	content, err := os.ReadFile(invocations)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(cfg.OutputDir, "ballin.csv") + " ballin.csv success 201",
+		filepath.Join(cfg.OutputDir, "chase.csv") + " chase.csv success 201",
+	}, strings.Split(strings.TrimSpace(string(content)), "\n"))
+	assert.Contains(t, h.log.String(), "Post-hook finished")
+	assert.Contains(t, h.log.String(), `"stdout":"pushed\n"`)
+}
+
+// TestRunPostHookFailure tests that a failing post-hook fails the file, and stops the run in strict mode
+func TestRunPostHookFailure(t *testing.T) {
+	h := newRunHarness(t, respondWith(http.StatusCreated, "Festerized CSV"))
+	script := writeScript(t, "echo rejected >&2; exit 3")
+
+	for _, strict := range []bool{false, true} {
+		cfg := testConfig(t, h.server.URL, TestDirUnFester+"/ballin.csv", TestDirUnFester+"/chase.csv")
+		cfg.PostHook, cfg.StrictMode = ParseHook(script), strict
+
+		exitCode, results, err := run(context.Background(), cfg)
+		if strict {
+			assert.NotNil(t, err)
+			assert.Equal(t, int(HOOK_FAILED), exitCode)
+			assert.Len(t, results, 1)
+		} else {
+			assert.Nil(t, err)
+			assert.Len(t, results, 2)
+		}
+		assert.Equal(t, StatusFailure, results[0].Status)
+		assert.Contains(t, results[0].Error, "exit status 3")
+	}
+}
+
+// TestRunPreHook tests that what the pre-hook writes to stdout is uploaded in place of the file
+func TestRunPreHook(t *testing.T) {
+	var received string
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		content, _ := io.ReadAll(file)
+		received = header.Filename + "\n" + string(content)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	input := filepath.Join(t.TempDir(), "titles.csv")
+	content := "Item ARK,Object Type,Title\nark:/21198/zz00091vxj,Collection,Ballin papers\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := writeScript(t, `awk -F, 'BEGIN { OFS = "," } NR > 1 { $3 = toupper($3) } { print }' "$1"`)
+
+	cfg := testConfig(t, h.server.URL, input)
+	cfg.PreHook = ParseHook(script)
+
+	exitCode, _, err := run(context.Background(), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "titles.csv\nItem ARK,Object Type,Title\nark:/21198/zz00091vxj,Collection,BALLIN PAPERS\n", received)
+
+	// The input itself is left as it was
+	unchanged, err := os.ReadFile(input)
+	assert.Nil(t, err)
+	assert.Equal(t, content, string(unchanged))
+}
+
+// TestRunPreHookRemovesOutput tests that the pre-hook's output for each file is removed once the file is done
+// with, rather than when the whole run ends
+func TestRunPreHookRemovesOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+	hookedDirs := func() []string {
+		dirs, _ := filepath.Glob(filepath.Join(tempDir, "festerize-pre-hook-*"))
+		return dirs
+	}
+
+	var counts []int
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		counts = append(counts, len(hookedDirs()))
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	cfg := testConfig(t, h.server.URL, TestDirUnFester+"/ballin.csv", TestDirUnFester+"/chase.csv")
+	cfg.PreHook = ParseHook(writeScript(t, `cat "$1"`))
+
+	exitCode, _, err := run(context.Background(), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, []int{1, 1}, counts)
+	assert.Empty(t, hookedDirs())
+}
+
+// TestRunPreHookFailure tests that a file isn't uploaded when its pre-hook fails
+func TestRunPreHookFailure(t *testing.T) {
+	uploads := 0
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		uploads++
+	})
+
+	cfg := testConfig(t, h.server.URL, TestDirUnFester+"/ballin.csv")
+	cfg.PreHook, cfg.StrictMode = ParseHook(writeScript(t, "exit 1")), true
+
+	exitCode, _, err := run(context.Background(), cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, int(HOOK_FAILED), exitCode)
+	assert.Equal(t, 0, uploads)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"hash"
+	"strconv"
+)
+
+// idempotencyKeyHeader is the header an upload's idempotency key is sent in
+const idempotencyKeyHeader string = "Idempotency-Key"
+
+// idempotencyNamespace is the UUID namespace festerize's idempotency keys are derived in
+var idempotencyNamespace = [16]byte{
+	0x3b, 0x5e, 0x0a, 0x52, 0x8d, 0x1f, 0x4c, 0x7a, 0x9e, 0x26, 0x41, 0xd3, 0x6f, 0x80, 0xb2, 0x17,
+}
+
+// idempotencyHash hashes an upload into its idempotency key: the name it's uploaded under, its CSV content,
+// and the form fields that decide how Fester processes it
+type idempotencyHash struct {
+	hash hash.Hash
+}
+
+// newIdempotencyHash starts hashing an upload of the named file; its content is written to the hash as it's read
+func newIdempotencyHash(uploadName string) *idempotencyHash {
+	keyHash := &idempotencyHash{hash: sha1.New()}
+	keyHash.hash.Write(idempotencyNamespace[:])
+	keyHash.field(uploadName)
+	return keyHash
+}
+
+// Write adds upload content to the hash
+func (keyHash *idempotencyHash) Write(p []byte) (int, error) {
+	return keyHash.hash.Write(p)
+}
+
+// field adds a length-prefixed value to the hash, so that values can't run into each other or the content
+func (keyHash *idempotencyHash) field(value string) {
+	keyHash.hash.Write([]byte(strconv.Itoa(len(value)) + ":" + value))
+}
+
+// Key returns the upload's key as a name-based (version 5) UUID, which is the same every time the same file
+// is uploaded the same way, and differs for any other file
+func (keyHash *idempotencyHash) Key(iiifAPIVersion, iiifHost string, metadataUpdate bool) string {
+	keyHash.field(iiifAPIVersion)
+	keyHash.field(iiifHost)
+	keyHash.field(strconv.FormatBool(metadataUpdate))
+
+	sum := keyHash.hash.Sum(nil)
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
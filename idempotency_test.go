@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// uuidV5Pattern matches a version 5 UUID
+var uuidV5Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// newKeyRecordingFester starts a server that records each upload's idempotency key, answering the first
+// upload with 429 and the rest with 201
+func newKeyRecordingFester(t *testing.T, keys *[]string) *httptest.Server {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*keys = append(*keys, r.Header.Get(idempotencyKeyHeader))
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// TestPostCSVIdempotencyKeyOnRetry tests that an upload retried after a 429 is sent with the same key
+func TestPostCSVIdempotencyKeyOnRetry(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+	defer func() { maxRetryWait = time.Minute }()
+	maxRetryWait = time.Minute
+
+	var keys []string
+	ts := newKeyRecordingFester(t, &keys)
+
+	response, _, err := postCSV(context.Background(), "test.csv", strings.NewReader("Item ARK\n"), ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", Idempotency: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	if assert.Len(t, keys, 2) {
+		assert.Regexp(t, uuidV5Pattern, keys[0])
+		assert.Equal(t, keys[0], keys[1])
+	}
+}
+
+// TestPostCSVIdempotencyKeyPerFile tests that a file gets the same key each time it's uploaded, and that
+// other files, or the same file uploaded another way, get other keys
+func TestPostCSVIdempotencyKeyPerFile(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	var keys []string
+	ts := newKeyRecordingFester(t, &keys)
+	upload := func(name, content string, metadataUpdate bool) {
+		_, _, err := postCSV(context.Background(), name, strings.NewReader(content), ts.URL+"/collections",
+			UploadOptions{IIIFVersion: "2", MetadataUpdate: metadataUpdate, Idempotency: true})
+		assert.Nil(t, err)
+	}
+
+	upload("ballin.csv", "Item ARK\nark:/21198/zz0001\n", false)
+	upload("ballin.csv", "Item ARK\nark:/21198/zz0001\n", false)
+	upload("chase.csv", "Item ARK\nark:/21198/zz0001\n", false)
+	upload("ballin.csv", "Item ARK\nark:/21198/zz0002\n", false)
+	upload("ballin.csv", "Item ARK\nark:/21198/zz0001\n", true)
+
+	if assert.Len(t, keys, 6) {
+		assert.Equal(t, keys[1], keys[2])
+		assert.Len(t, map[string]bool{keys[2]: true, keys[3]: true, keys[4]: true, keys[5]: true}, 4)
+	}
+}
+
+// TestPostCSVWithoutIdempotency tests that no key is sent unless --idempotency is passed
+func TestPostCSVWithoutIdempotency(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	var keys []string
+	ts := newKeyRecordingFester(t, &keys)
+
+	_, _, err := postCSV(context.Background(), "test.csv", strings.NewReader("Item ARK\n"), ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"", ""}, keys)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+)
+
+// CSVIIIFHost returns the IIIF host a CSV names for its images in its IIIF Host column, or an empty string
+// if it doesn't name one; since Fester takes one host per upload, every row that names a host must name
+// the same one
+func CSVIIIFHost(records [][]string) (string, error) {
+	if len(records) == 0 {
+		return "", nil
+	}
+	index := columnIndex(records[0], iiifHostColumn)
+	if index == -1 {
+		return "", nil
+	}
+
+	var host string
+	var hostRow int
+	for rowIndex, row := range records[1:] {
+		value := cell(row, index)
+		switch {
+		case value == "":
+			continue
+		case host == "":
+			host, hostRow = value, rowIndex+2
+		case value != host:
+			return "", fmt.Errorf("conflicting %s values %q (row %d) and %q (row %d)", iiifHostColumn, host, hostRow,
+				value, rowIndex+2)
+		}
+	}
+	return host, nil
+}
+
+// fileIIIFHost returns the IIIF host to upload a file with: the one its IIIF Host column names, or the
+// --iiifhost default if it doesn't name one
+func fileIIIFHost(path, defaultHost string, input CSVInput) (string, error) {
+	records, err := input.readRenamedRecords(path)
+	if err != nil {
+		return "", err
+	}
+
+	host, err := CSVIIIFHost(records)
+	if err != nil || host == "" {
+		return defaultHost, err
+	}
+	return host, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCSVIIIFHost tests reading the IIIF host a CSV names, and rejecting a CSV that names more than one
+func TestCSVIIIFHost(t *testing.T) {
+	tests := map[string]struct {
+		records [][]string
+		host    string
+		fail    bool
+	}{
+		"no column": {[][]string{{"Item ARK"}, {"ark:/21198/zz0001"}}, "", false},
+		"empty":     {[][]string{{"Item ARK", "IIIF Host"}, {"ark:/21198/zz0001", ""}}, "", false},
+		"collection row": {[][]string{
+			{"Item ARK", "IIIF Host"},
+			{"ark:/21198/zz0001", " https://iiif.example.edu "},
+			{"ark:/21198/zz0002", ""},
+		}, "https://iiif.example.edu", false},
+		"every row": {[][]string{
+			{"IIIF Host"},
+			{"https://iiif.example.edu"},
+			{"https://iiif.example.edu"},
+		}, "https://iiif.example.edu", false},
+		"conflicting": {[][]string{
+			{"IIIF Host"},
+			{"https://iiif.example.edu"},
+			{"https://images.example.edu"},
+		}, "", true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			host, err := CSVIIIFHost(tc.records)
+			if tc.fail {
+				assert.ErrorContains(t, err, "(row 3)")
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.host, host)
+		})
+	}
+}
+
+// TestRunIIIFHostOverride tests that a CSV's IIIF Host is sent in place of --iiifhost, which is sent for the
+// CSVs that don't name one, and that a CSV naming two hosts isn't uploaded
+func TestRunIIIFHostOverride(t *testing.T) {
+	var mutex sync.Mutex
+	hosts := map[string]string{}
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, header, err := r.FormFile("file"); err == nil {
+			mutex.Lock()
+			hosts[header.Filename] = r.FormValue("iiif-host")
+			mutex.Unlock()
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	own := writeTestCSV(t, "own.csv", "Item ARK,Object Type,IIIF Host\nark:/21198/zz0001,Collection,https://iiif.example.edu\n")
+	fallback := writeTestCSV(t, "fallback.csv", testCollectionCSV)
+	conflicting := writeTestCSV(t, "conflicting.csv",
+		"Item ARK,IIIF Host\nark:/21198/zz0001,https://iiif.example.edu\nark:/21198/zz0002,https://images.example.edu\n")
+
+	cfg := testConfig(t, h.server.URL, own, fallback, conflicting)
+	cfg.IIIFHost = "https://default.example.edu"
+	_, results, _ := run(context.Background(), cfg)
+
+	assert.Equal(t, map[string]string{
+		"own.csv":      "https://iiif.example.edu",
+		"fallback.csv": "https://default.example.edu",
+	}, hosts)
+	if assert.Len(t, results, 3) {
+		assert.Equal(t, filepath.Base(conflicting), filepath.Base(results[2].Path))
+		assert.Equal(t, StatusFailure, results[2].Status)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+)
+
+// ValidateLimit validates the maximum number of CSVs to upload; zero means no limit
+func ValidateLimit(limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("invalid --limit %d: expected 0 (no limit) or more", limit)
+	}
+	return nil
+}
+
+// LimitFiles keeps the first limit CSVs, in order, and returns the CSVs left out. Inputs that aren't CSVs
+// don't count towards the limit; they're passed through so the run still flags them as not being CSVs.
+func LimitFiles(paths []string, limit int) ([]string, []string) {
+	if limit <= 0 {
+		return paths, nil
+	}
+
+	var kept, dropped []string
+	count := 0
+	for _, path := range paths {
+		switch {
+		case !IsCSVFile(path):
+			kept = append(kept, path)
+		case count < limit:
+			kept = append(kept, path)
+			count++
+		default:
+			dropped = append(dropped, path)
+		}
+	}
+	return kept, dropped
+}
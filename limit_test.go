@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLimitFiles tests that only the first CSVs are kept, with other files left alone
+func TestLimitFiles(t *testing.T) {
+	paths := []string{"a.csv", "notes.txt", "b.csv.gz", "c.csv", "d.csv"}
+
+	kept, dropped := LimitFiles(paths, 2)
+	assert.Equal(t, []string{"a.csv", "notes.txt", "b.csv.gz"}, kept)
+	assert.Equal(t, []string{"c.csv", "d.csv"}, dropped)
+
+	kept, dropped = LimitFiles(paths, 0)
+	assert.Equal(t, paths, kept)
+	assert.Empty(t, dropped)
+
+	kept, dropped = LimitFiles(paths, 10)
+	assert.Equal(t, paths, kept)
+	assert.Empty(t, dropped)
+}
+
+// TestValidateLimit tests the bounds of --limit
+func TestValidateLimit(t *testing.T) {
+	assert.NoError(t, ValidateLimit(0))
+	assert.NoError(t, ValidateLimit(3))
+	assert.Error(t, ValidateLimit(-1))
+}
+
+// TestRunLimit tests that only the first files of a larger batch are uploaded
+func TestRunLimit(t *testing.T) {
+	var uploaded []string
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, header, err := r.FormFile("file"); err == nil {
+			uploaded = append(uploaded, header.Filename)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	var sources []string
+	for _, name := range []string{"one.csv", "two.csv", "three.csv", "four.csv"} {
+		sources = append(sources, writeTestCSV(t, name, testCollectionCSV))
+	}
+	cfg := testConfig(t, h.server.URL, sources...)
+	cfg.Limit = 2
+	exitCode, results, err := run(context.Background(), cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, []string{"one.csv", "two.csv"}, uploaded)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "one.csv", filepath.Base(results[0].Path))
+		assert.Equal(t, "two.csv", filepath.Base(results[1].Path))
+	}
+	assert.Contains(t, h.stdout.String(), "Uploading the first 2 files; skipping 2 more")
+}
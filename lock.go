@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// lockFilename is the name of the lockfile festerize keeps in the output directory while it runs
+const lockFilename string = ".festerize.lock"
+
+// AcquireLock creates a lockfile holding this process's PID in the supplied directory; it fails if another run
+// already holds the lock. The returned function removes the lockfile and is safe to call more than once.
+func AcquireLock(dir string) (func(), error) {
+	lockPath := filepath.Join(dir, lockFilename)
+
+	file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("another festerize run (PID %s) is using %s; if it is no longer running, "+
+				"delete %s or use --no-lock", lockOwner(lockPath), dir, lockPath)
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(strconv.Itoa(os.Getpid()) + "\n"); err != nil {
+		os.Remove(lockPath)
+		return nil, err
+	}
+
+	released := false
+	return func() {
+		if released {
+			return
+		}
+		released = true
+		if err := os.Remove(lockPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			Logger.Warn("Unable to remove lockfile", zap.String("path", lockPath), zap.Error(err))
+		}
+	}, nil
+}
+
+// lockOwner returns the PID recorded in a lockfile, or "unknown" if it can't be read
+func lockOwner(lockPath string) string {
+	data, err := os.ReadFile(lockPath)
+	if err != nil || strings.TrimSpace(string(data)) == "" {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAcquireLock tests that a second run can't take the lock while the first holds it
+func TestAcquireLock(t *testing.T) {
+	dir := t.TempDir()
+
+	release, err := AcquireLock(dir)
+	assert.Nil(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, lockFilename))
+	assert.Nil(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid()), strings.TrimSpace(string(data)))
+
+	_, err = AcquireLock(dir)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), strconv.Itoa(os.Getpid()))
+		assert.Contains(t, err.Error(), "--no-lock")
+	}
+
+	release()
+	release()
+	assert.NoFileExists(t, filepath.Join(dir, lockFilename))
+
+	release, err = AcquireLock(dir)
+	assert.Nil(t, err)
+	release()
+}
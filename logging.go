@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Formats of the log file
+const (
+	LogFormatJSON    string = "json"
+	LogFormatConsole string = "console"
+)
+
+// Default limits for rotating the log file
+const (
+	defaultLogMaxSize    = 100
+	defaultLogMaxBackups = 5
+	defaultLogMaxAge     = 30
+)
+
+// logSamplingTick is the interval over which zap counts repeated log messages when sampling
+const logSamplingTick = time.Second
+
+// LogSampling configures sampling of repeated log messages; the zero value logs everything
+type LogSampling struct {
+	First      int
+	Thereafter int
+}
+
+// Enabled checks whether repeated messages are sampled
+func (s LogSampling) Enabled() bool {
+	return s.First > 0
+}
+
+// logOutput is the rotating log file, shared by every logger built during the run
+var logOutput *lumberjack.Logger
+
+// ValidateLogFormat validates the log format
+func ValidateLogFormat() error {
+	switch logFormat {
+	case LogFormatJSON, LogFormatConsole:
+		return nil
+	default:
+		return errors.New("invalid log format. Allowed values are json or console")
+	}
+}
+
+// ValidateLogRotation validates the log rotation limits
+func ValidateLogRotation() error {
+	switch {
+	case logMaxSize < 1:
+		return errors.New("invalid log max size. It must be at least 1 (megabyte)")
+	case logMaxBackups < 0:
+		return errors.New("invalid log max backups. It can't be negative")
+	case logMaxAge < 0:
+		return errors.New("invalid log max age. It can't be negative")
+	default:
+		return nil
+	}
+}
+
+// ParseLogSampling parses a --log-sampling value of the form first:thereafter; an empty value turns
+// sampling off
+func ParseLogSampling(value string) (LogSampling, error) {
+	if value == "" {
+		return LogSampling{}, nil
+	}
+
+	first, thereafter, found := strings.Cut(value, ":")
+	firstCount, firstErr := strconv.Atoi(strings.TrimSpace(first))
+	thereafterCount, thereafterErr := strconv.Atoi(strings.TrimSpace(thereafter))
+	if !found || firstErr != nil || thereafterErr != nil || firstCount < 1 || thereafterCount < 1 {
+		return LogSampling{}, fmt.Errorf("invalid log sampling %q: expected first:thereafter, e.g. 10:100", value)
+	}
+	return LogSampling{First: firstCount, Thereafter: thereafterCount}, nil
+}
+
+// newLogCore creates a core that writes entries at or above the level, scrubbing credentials from them; with
+// sampling, repeated messages below ERROR are thinned out while every error is still written. The redacting
+// core writes without consulting the core it wraps, so it goes inside the sampler rather than around it
+func newLogCore(encoder zapcore.Encoder, output zapcore.WriteSyncer, level zapcore.Level,
+	sampling LogSampling) zapcore.Core {
+	if !sampling.Enabled() {
+		return newRedactingCore(zapcore.NewCore(encoder, output, level))
+	}
+
+	belowError := zap.LevelEnablerFunc(func(entryLevel zapcore.Level) bool {
+		return entryLevel >= level && entryLevel < zapcore.ErrorLevel
+	})
+	errorAndAbove := zap.LevelEnablerFunc(func(entryLevel zapcore.Level) bool {
+		return entryLevel >= level && entryLevel >= zapcore.ErrorLevel
+	})
+	sampled := zapcore.NewSamplerWithOptions(newRedactingCore(zapcore.NewCore(encoder, output, belowError)),
+		logSamplingTick, sampling.First, sampling.Thereafter)
+	return zapcore.NewTee(sampled, newRedactingCore(zapcore.NewCore(encoder.Clone(), output, errorAndAbove)))
+}
+
+// parseLogLevel converts a --loglevel value to a zap level; anything unknown logs at INFO
+func parseLogLevel(level string) zapcore.Level {
+	switch level {
+	case "DEBUG":
+		return zapcore.DebugLevel
+	case "ERROR":
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// logEncoder creates the encoder for the supplied log format
+func logEncoder(format string) zapcore.Encoder {
+	config := zap.NewDevelopmentEncoderConfig()
+	if format == LogFormatConsole {
+		config.EncodeTime = zapcore.ISO8601TimeEncoder
+		return zapcore.NewConsoleEncoder(config)
+	}
+	return zapcore.NewJSONEncoder(config)
+}
+
+// openLogFile opens the log file the first time a logger needs it, appending to what's there. The rotation
+// limits are parsed after the first logger is built, so a file opened with other limits is closed and opened
+// again rather than changed, since lumberjack reads them from its cleanup goroutine
+func openLogFile() (*lumberjack.Logger, error) {
+	if logOutput != nil {
+		if logOutput.Filename == logFile && logOutput.MaxSize == logMaxSize && logOutput.MaxBackups == logMaxBackups &&
+			logOutput.MaxAge == logMaxAge {
+			return logOutput, nil
+		}
+		if err := logOutput.Close(); err != nil {
+			return nil, err
+		}
+	}
+	logOutput = &lumberjack.Logger{Filename: logFile, MaxSize: logMaxSize, MaxBackups: logMaxBackups, MaxAge: logMaxAge}
+	return logOutput, nil
+}
+
+// truncateLogFile empties the log file, discarding what earlier runs left in it
+func truncateLogFile() error {
+	if logOutput != nil {
+		if err := logOutput.Close(); err != nil {
+			return err
+		}
+	}
+	if err := os.Truncate(logFile, 0); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// newBatchID generates an ID for a run from its start time and a random suffix, so that runs started in the
+// same second still get different IDs
+func newBatchID(start time.Time) string {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return start.UTC().Format("20060102T150405Z")
+	}
+	return start.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// withBatchID attaches the run's batch ID to every entry the logger writes
+func withBatchID(log *zap.Logger, id string) *zap.Logger {
+	return log.WithOptions(zap.Fields(zap.String("batch_id", id)))
+}
+
+// sessionLogger creates a logger that writes to the log file whatever the log level, for marking where a
+// run starts and ends
+func sessionLogger(format string) (*zap.Logger, error) {
+	file, err := openLogFile()
+	if err != nil {
+		return nil, err
+	}
+
+	core := newRedactingCore(zapcore.NewCore(logEncoder(format), zapcore.AddSync(file), zapcore.DebugLevel))
+	return withTags(withBatchID(zap.New(core), batchID), tags), nil
+}
+
+// logSessionStart marks the start of a run in the log file so that runs appended to the same file can be told
+// apart
+func logSessionStart(format string) error {
+	session, err := sessionLogger(format)
+	if err != nil {
+		return err
+	}
+
+	session.Info("Festerize session started",
+		zap.String("version", festerizeVersion),
+		zap.Int("pid", os.Getpid()),
+		zap.Strings("args", redactArgs(os.Args[1:])))
+	return session.Sync()
+}
+
+// logSessionEnd marks the end of a run in the log file, with the code festerize exits with
+func logSessionEnd(format string, exitCode int) error {
+	session, err := sessionLogger(format)
+	if err != nil {
+		return err
+	}
+
+	session.Info("Festerize session finished",
+		zap.Int("exit_code", exitCode),
+		zap.Duration("duration", time.Since(startTime)))
+	return session.Sync()
+}
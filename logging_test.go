@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// useTestLogFile points the log file at a temporary path for the rest of the test
+func useTestLogFile(t *testing.T) string {
+	originalFile, originalOutput := logFile, logOutput
+	t.Cleanup(func() {
+		if logOutput != nil && logOutput != originalOutput {
+			logOutput.Close()
+		}
+		logFile, logOutput = originalFile, originalOutput
+	})
+
+	logFile, logOutput = filepath.Join(t.TempDir(), "logs.log"), nil
+	return logFile
+}
+
+// TestLoggerFormats tests that the JSON format writes JSON lines and the console format doesn't
+func TestLoggerFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		isJSON bool
+	}{
+		{LogFormatJSON, true},
+		{LogFormatConsole, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			path := useTestLogFile(t)
+
+			log := logger(tc.format, false, zapcore.DebugLevel, LogSampling{})
+			log.Info("Uploading file to Fester")
+			log.Sync()
+
+			content, err := os.ReadFile(path)
+			assert.Nil(t, err)
+			line := strings.TrimSpace(string(content))
+			assert.Contains(t, line, "Uploading file to Fester")
+			assert.Equal(t, tc.isJSON, json.Valid([]byte(line)))
+		})
+	}
+}
+
+// TestValidateLogFormat tests log formats are properly validated
+func TestValidateLogFormat(t *testing.T) {
+	defer func() { logFormat = LogFormatJSON }()
+
+	for _, format := range []string{LogFormatJSON, LogFormatConsole} {
+		logFormat = format
+		assert.Nil(t, ValidateLogFormat(), format)
+	}
+	logFormat = "xml"
+	assert.NotNil(t, ValidateLogFormat())
+}
+
+// captureStderr runs the supplied function and returns what it printed to stderr
+func captureStderr(t *testing.T, fn func()) string {
+	oldStderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+
+	fn()
+
+	w.Close()
+	os.Stderr = oldStderr
+
+	var buffer bytes.Buffer
+	io.Copy(&buffer, r)
+	r.Close()
+	return buffer.String()
+}
+
+// TestLoggerStderr tests that the log is teed to stderr, filtered by the log level, only when asked
+func TestLoggerStderr(t *testing.T) {
+	path := useTestLogFile(t)
+
+	output := captureStderr(t, func() {
+		log := logger(LogFormatJSON, true, zapcore.InfoLevel, LogSampling{})
+		log.Debug("Sending request to Fester")
+		log.Info("Uploading file to Fester")
+		log.Sync()
+	})
+	assert.Contains(t, output, "Uploading file to Fester")
+	assert.NotContains(t, output, "Sending request to Fester")
+
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "Uploading file to Fester")
+
+	output = captureStderr(t, func() {
+		log := logger(LogFormatJSON, false, zapcore.InfoLevel, LogSampling{})
+		log.Info("Uploading file to Fester")
+		log.Sync()
+	})
+	assert.Empty(t, output)
+}
+
+// TestLoggerLevels tests that debug lines are only logged at DEBUG and info lines are hidden at ERROR
+func TestLoggerLevels(t *testing.T) {
+	tests := []struct {
+		loglevel string
+		debug    bool
+		info     bool
+	}{
+		{"DEBUG", true, true},
+		{"INFO", false, true},
+		{"ERROR", false, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.loglevel, func(t *testing.T) {
+			path := useTestLogFile(t)
+
+			log := logger(LogFormatJSON, false, parseLogLevel(tc.loglevel), LogSampling{})
+			log.Debug("Sending request to Fester")
+			log.Info("Uploading file to Fester")
+			log.Error("Failed to upload file to Fester")
+			log.Sync()
+
+			content, err := os.ReadFile(path)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.debug, strings.Contains(string(content), "Sending request to Fester"))
+			assert.Equal(t, tc.info, strings.Contains(string(content), "Uploading file to Fester"))
+			assert.Contains(t, string(content), "Failed to upload file to Fester")
+		})
+	}
+}
+
+// TestParseLogSampling tests that sampling settings are parsed and malformed ones rejected
+func TestParseLogSampling(t *testing.T) {
+	sampling, err := ParseLogSampling("")
+	assert.Nil(t, err)
+	assert.False(t, sampling.Enabled())
+
+	sampling, err = ParseLogSampling("10:100")
+	assert.Nil(t, err)
+	assert.Equal(t, LogSampling{First: 10, Thereafter: 100}, sampling)
+
+	for _, value := range []string{"10", "10:", ":100", "0:100", "10:0", "ten:100"} {
+		_, err := ParseLogSampling(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+// TestLoggerSampling tests that repeated messages are thinned out when sampling but errors never are
+func TestLoggerSampling(t *testing.T) {
+	tests := []struct {
+		name     string
+		sampling LogSampling
+		infos    int
+	}{
+		{"Off", LogSampling{}, 50},
+		{"On", LogSampling{First: 5, Thereafter: 10}, 9},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := useTestLogFile(t)
+
+			log := logger(LogFormatJSON, false, zapcore.InfoLevel, tc.sampling)
+			for range 50 {
+				log.Info("Uploading file to Fester")
+				log.Error("Failed to upload file to Fester")
+			}
+			log.Sync()
+
+			content, err := os.ReadFile(path)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.infos, strings.Count(string(content), "Uploading file to Fester"))
+			assert.Equal(t, 50, strings.Count(string(content), "Failed to upload file to Fester"))
+		})
+	}
+}
+
+// TestValidateLogRotation tests that log rotation limits are properly validated
+func TestValidateLogRotation(t *testing.T) {
+	defer func() {
+		logMaxSize, logMaxBackups, logMaxAge = defaultLogMaxSize, defaultLogMaxBackups, defaultLogMaxAge
+	}()
+
+	assert.Nil(t, ValidateLogRotation())
+
+	logMaxSize = 0
+	assert.NotNil(t, ValidateLogRotation())
+
+	logMaxSize, logMaxBackups = 1, -1
+	assert.NotNil(t, ValidateLogRotation())
+
+	logMaxBackups, logMaxAge = 0, -1
+	assert.NotNil(t, ValidateLogRotation())
+}
+
+// TestLoggerRotation tests that writing past the size limit rotates the log file into a backup
+func TestLoggerRotation(t *testing.T) {
+	defer func() { logMaxSize = defaultLogMaxSize }()
+	path := useTestLogFile(t)
+	logMaxSize = 1
+
+	log := logger(LogFormatJSON, false, zapcore.InfoLevel, LogSampling{})
+	padding := strings.Repeat("x", 1024)
+	for range 1100 {
+		log.Info("Uploading file to Fester", zap.String("padding", padding))
+	}
+	log.Sync()
+
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(path), "logs-*.log"))
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Less(t, info.Size(), int64(1024*1024))
+}
+
+// TestLogSessions tests that a second run appends to the log when asked and otherwise truncates it
+func TestLogSessions(t *testing.T) {
+	tests := []struct {
+		name      string
+		appendLog bool
+		sessions  int
+	}{
+		{"Append", true, 2},
+		{"Truncate", false, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := useTestLogFile(t)
+
+			for _, message := range []string{"First run", "Second run"} {
+				if !tc.appendLog {
+					assert.Nil(t, truncateLogFile())
+				}
+				assert.Nil(t, logSessionStart(LogFormatJSON))
+				log := logger(LogFormatJSON, false, zapcore.ErrorLevel, LogSampling{})
+				log.Error(message)
+				log.Sync()
+
+				// Each run is its own process with its own handle on the log file
+				assert.Nil(t, logOutput.Close())
+				logOutput = nil
+			}
+
+			content, err := os.ReadFile(path)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.sessions, strings.Count(string(content), "Festerize session started"))
+			assert.Equal(t, tc.appendLog, strings.Contains(string(content), "First run"))
+			assert.Contains(t, string(content), "Second run")
+		})
+	}
+}
+
+// TestNewBatchID tests that batch IDs start with the start time and differ between runs
+func TestNewBatchID(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+	first, second := newBatchID(start), newBatchID(start)
+	assert.True(t, strings.HasPrefix(first, "20240601T123000Z-"), first)
+	assert.NotEqual(t, first, second)
+}
+
+// TestLoggerBatchID tests that the batch ID is on every line of the log, including the session markers
+func TestLoggerBatchID(t *testing.T) {
+	defer func() { batchID = "" }()
+	path := useTestLogFile(t)
+	batchID = "ci-1234"
+
+	assert.Nil(t, logSessionStart(LogFormatJSON))
+	log := withBatchID(logger(LogFormatJSON, false, zapcore.InfoLevel, LogSampling{}), batchID)
+	log.Info("Uploading file to Fester")
+	log.Sync()
+	assert.Nil(t, logSessionEnd(LogFormatJSON, 0))
+
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 3)
+	for _, line := range lines {
+		var entry map[string]any
+		assert.Nil(t, json.Unmarshal([]byte(line), &entry))
+		assert.Equal(t, "ci-1234", entry["batch_id"], line)
+	}
+}
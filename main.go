@@ -2,17 +2,21 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"math/rand"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"strings"
+	"regexp"
+	"slices"
+	"sync/atomic"
+	"syscall"
+	"time"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -29,6 +33,24 @@ const (
 	FESTER_ERROR_RESPONSE      FesterizeError = 5
 	FILE_IO_ERROR              FesterizeError = 6
 	INVALID_OUTPUT_SPECIFIED   FesterizeError = 7
+	INVALID_REPORT_SPECIFIED   FesterizeError = 8
+	INVALID_CSV_SPECIFIED      FesterizeError = 9
+	OUTPUT_LOCKED              FesterizeError = 10
+	TOO_MANY_FAILURES          FesterizeError = 11
+	INCOMPATIBLE_SERVER        FesterizeError = 12
+	HOOK_FAILED                FesterizeError = 13
+	DEADLINE_EXCEEDED          FesterizeError = 14
+	MANIFEST_UNAVAILABLE       FesterizeError = 15
+	NOT_CONFIRMED              FesterizeError = 16
+	INTERRUPTED                FesterizeError = 130
+)
+
+const (
+	// redactedValue replaces credentials in log output
+	redactedValue string = "[REDACTED]"
+
+	// maxLoggedBodyLength is the number of bytes of a response body included in debug logs
+	maxLoggedBodyLength int = 1024
 )
 
 const (
@@ -40,11 +62,197 @@ Mirador 3.
 For all other cases, version 2 should be used, especially for any content
 intended to be viewed with Universal Viewer.`
 
+	serverHelp string = `URL of the Fester service dedicated for ingest. Repeat the flag to
+mirror each CSV to several servers; the first one is the primary server
+that the updated CSV is taken from.`
+
+	mirrorPolicyHelp string = `When mirroring to several servers, whether a file counts as uploaded
+once all of the servers (all) or any of them (any) accept it.`
+
+	preHookHelp string = `Command to run on each CSV before it's checked and uploaded, with the CSV's path as
+its last argument. If it writes to stdout, what it writes is uploaded instead of
+the file; otherwise the file, which it may have rewritten in place, is uploaded.
+If it fails, the file isn't uploaded.`
+	postHookHelp string = `Command to run on each festerized CSV once it's saved, with the CSV's path as its
+last argument and FESTERIZE_* environment variables describing the upload. The
+command is split on spaces rather than run through a shell. If it fails, the
+file counts as failed.`
+	delimiterHelp string = `Character the fields of the input CSVs are separated by, or tab, for local checks
+and --normalize-delimiter. Fester itself only reads comma-delimited CSVs.`
+	tagHelp string = `Tag every log entry and the report with key=value, such as a project or ticket
+(repeatable), so a run's entries can be found in a log shared by many runs.`
+
+	batchIDHelp string = `ID attached to every log entry of the run, such as a CI job ID, so a run's entries
+can be found in a shared log. Generated from the start time if not given.`
+	logSamplingHelp string = `Sample repeated log messages as first:thereafter, e.g. 10:100 logs the first 10
+of each message every second, then every 100th. Errors are always logged. Off by default.`
+	sinceHelp string = `Only upload files modified after this time, given as an RFC3339 timestamp
+(e.g. 2024-06-01T00:00:00Z) or as @path to use the modification time of a
+file (e.g. one touched at the end of the previous run).`
+
+	compressUploadHelp string = `Gzip the upload and send it with Content-Encoding: gzip. Only use this if
+the Fester server (or the proxy in front of it) accepts gzipped request
+bodies; otherwise every upload will fail.`
+
+	productionPatternHelp string = `Regular expression matching the hostnames of production servers. Before
+uploading to one, festerize asks for its hostname to be typed in, unless it's
+given with --confirm-server or --yes is passed, and exits with code 16 if
+it isn't confirmed. Empty to treat no server as production.`
+
+	recordRequestsHelp string = `Directory to save each upload request in, for reproducing problems: the body,
+exactly as it was sent, in <n>-<file>.body, and the method, URL, and headers,
+with credentials and --header values hidden, in <n>-<file>.headers.`
+
+	shuffleHelp string = `Upload the CSVs in a random order, for load-testing Fester. The seed is
+logged, and giving it with --seed repeats the order. Files are shuffled before
+--auto-order and --limit, so parents still come first and the files left out
+are picked at random.`
+
+	limitHelp string = `Upload only the first N CSVs, for smoke-testing a large batch against a new
+server. The limit applies after --since and --auto-order, and before --merge.
+0 uploads them all.`
+
+	prettyErrorsHelp string = `Print a short summary of each failed upload to stderr: the file, the HTTP
+status, the error Fester gave, and, for known kinds of errors, how to fix it,
+so the log doesn't need to be opened.`
+
+	detectEncodingHelp string = `Detect each CSV's encoding from its byte order mark or its bytes, and convert
+it to UTF-8 before it's checked and uploaded. UTF-8, UTF-16 with a byte order
+mark, ISO-8859-1, and Windows-1252 are recognized; a CSV whose encoding can't
+be told is read as UTF-8.`
+
+	verifyOutputHelp string = `After saving each festerized CSV, request every URL in its IIIF Manifest URL
+column and fail the file if any can't be retrieved. Credentials and --header
+values are only sent to manifests on the Fester server's own host.`
+
+	chunkRowsHelp string = `Upload CSVs of more than this many rows in chunks of at most this many rows,
+one request after another, for CSVs too large for Fester to take at once.
+Collection rows are uploaded first, then works, then pages, and the manifest
+URLs Fester returns for each chunk are saved in one output CSV. 0 uploads
+CSVs whole.`
+
+	mergeHelp string = `Combine the CSVs into one and upload it as a single file, saved as
+--merge-name, so that rows can refer to parents in any of them. The CSVs must
+have the same columns, though not necessarily in the same order.`
+
+	sortRowsHelp string = `Sort each CSV's rows before upload, so that re-runs upload the same bytes:
+collection rows first, then works, then pages, each sorted by --sort-key. The
+header stays first, and rows with the same key keep their order.`
+
+	arkPrefixHelp string = `Only upload the rows whose Item ARK starts with this prefix, such as a NAAN
+and shoulder (ark:/21198/zz0), along with the header and, unless
+--keep-collection-rows=false, the collection rows. A file without any such rows
+is skipped.`
+
+	basePathHelp string = `Path Fester is mounted at on the server, behind a reverse proxy, e.g. /fester
+for https://host/fester/collections. It goes between the server and each
+endpoint, whatever slashes either has.`
+
+	idempotencyHelp string = `Send each upload with an Idempotency-Key header derived from the file's name,
+content, and upload options, so that a Fester that supports it can recognize an
+upload it has already processed when it's retried. The key is the same every
+time the same file is uploaded, including on later runs.`
+
+	maxRetryWaitHelp string = `When Fester rate limits an upload (429 Too Many Requests), wait as long as
+its Retry-After header asks and try again, for at most this long in total
+per upload (e.g. 90s or 5m; 0 fails straight away).`
+
+	schemaFileHelp string = `JSON or YAML file listing the columns that the rows of each object type require
+(and, for reference, the ones they may have). Every row is checked before upload,
+and each missing value is reported with its row number.`
+
+	maxUploadRateHelp string = `Cap the rate each upload is sent at, so festerize doesn't crowd other traffic
+off a shared link, e.g. 5MB/s or 512KiB/s (KB, MB, and GB are powers of 1000;
+KiB, MiB, and GiB are powers of 1024). Unlimited by default.`
+
+	traceHelp string = `Log how long each upload's request spent resolving DNS, connecting, in the TLS
+handshake, and waiting for the first byte of the response, along with its total
+time. The timings are logged at DEBUG level, so also pass --loglevel DEBUG.`
+
+	maxRuntimeHelp string = `Give up once the run has taken this long in total (e.g. 30m or 2h), cancelling the
+upload in progress and leaving the rest of the files unprocessed, and exit with
+code 14 once the report is written (0 never gives up).`
+
+	followRedirectsHelp string = `Follow redirects from Fester, such as from a load balancer, sending the
+credentials on only if the new location is the same host or one of the
+--server hosts. A 307 or 308 redirect keeps the upload a POST; use
+--follow-redirects=false to treat any redirect as a failure.`
+
+	strictCompatHelp string = `Exit before uploading anything if a server's Fester version isn't known to
+work with the requested IIIF Presentation API version, or can't be found
+out. Without it, festerize only warns.`
+
+	maxFailuresHelp string = `Stop the run once this many files have failed, leaving the rest
+unprocessed (0 never stops). Unlike --strict-mode, which stops at the first
+failure, this gives up only when something is clearly wrong.`
+
+	collectionNameHelp string = `Set the title of the collection row before upload, adding a Title column if
+the CSV doesn't have one. The CSV itself is rewritten, so Fester sees the
+new title; files without exactly one collection row fail to upload.`
+
+	autoOrderHelp string = `Upload the files in dependency order, so that files with collection rows
+come before files with their works, which come before files with their
+pages. Files are reordered, but rows within a file are not, so this is
+best effort: a file that mixes rows of different levels is uploaded as is.`
+
+	splitByTypeHelp string = `Upload the collection, work, and page rows of each CSV as separate
+requests, in that order, and merge the CSVs that Fester returns into one
+output CSV.`
+
+	outputTemplateHelp string = `Name of each output CSV, built from the placeholders {name} (the input's
+name without its extension), {ext} (the input's extension, e.g. .csv),
+{date} (the date of the run, as YYYY-MM-DD), and {version} (the festerize
+version), e.g. '{name}-v{version}-{date}.csv'.`
+
+	mirrorTreeHelp string = `Recreate the directory structure of the inputs under the output directory,
+relative to the deepest directory containing all of them, instead of
+writing every output CSV directly into it.`
+
+	onConflictHelp string = `What to do when Fester rejects a file because its rows' collection or work
+hasn't been festerized yet (see above): try it again once the other files are
+done, in case one of them has the parents (defer), count it as failed (fail), or
+leave it unprocessed without counting it as failed (skip).`
+
+	multiValueColumnsHelp string = `Columns whose cells hold several values split by --multivalue-sep (comma
+separated). Each of their cells is checked before upload for a separator at
+either end or an empty value between two separators.`
+
+	countOnlyHelp string = `Print how many files would be processed, and which, then exit without
+contacting Fester or creating the output directory. Globs are expanded and
+--since and --retry-report are applied, but the CSVs aren't opened.`
+
+	canonicalHeaderHelp string = `Reorder each CSV's columns to a canonical order before upload, adding the
+optional columns it's missing with empty values. The order is the schema file's
+columns list or, without one, Item ARK, Parent ARK, and Object Type followed by
+the columns the schema lists for each object type; other columns follow in their
+original order. Values are never changed.`
+
+	collisionPolicyHelp string = `What to do when two inputs would be written to the same output file: fail
+the later one (error) or add a counter to its name (number).`
+
+	outputFormatHelp string = `Format for reporting the outcome of each file on stdout (text, json).
+
+With json, a JSON object is written to stdout on its own line as each file
+is processed and all other messages are printed to stderr.`
+
 	strictModeHelp string = `Festerize immediately exits with an error code if Fester responds
 with an error, or if a user specifies on the command line a file that does not
 exist or a file that does not have a .csv filename extension. The rest of the
 files on the command line (if any) will remain unprocessed.`
 
+	warnUnknownObjectTypesHelp string = `Warn about rows whose Object Type isn't Collection, Work, or Page, ignoring
+case and surrounding whitespace, and, without --fix-object-types, about ones
+that are but aren't written that way. The rows are still uploaded.`
+
+	strictValidationHelp string = `Like --strict-mode, but only for CSVs that fail festerize's own checks of
+their content, such as a missing required column or duplicate Item ARKs: exit
+with code 9 at the first one, while carrying on past errors from Fester.`
+
+	strictServerHelp string = `Like --strict-mode, but only for uploads that Fester rejects or can't be
+reached for, or whose manifests --verify-output can't retrieve: exit with the
+code for the error at the first one, while carrying on past CSVs that fail
+festerize's own checks.`
+
 	festerizeMessage string = `Uploads CSV files to the Fester IIIF manifest service for processing.
 
 Any rows with an 'Object Type' of 'Collection' (i.e., "collection row")
@@ -93,14 +301,113 @@ Arguments:
 )
 
 var iiifApiVersion string
-var server string
+var servers []string
+var mirrorPolicy string
 var out string
 var iiifhost string
+var basePath string
 var metadata bool
 var strictMode bool
+var strictValidation bool
+var strictServer bool
+var warnUnknownObjectTypes bool
+var fixObjectTypes bool
+var strictCompat bool
 var loglevel string
 var src []string
-var Logger *zap.Logger = logger()
+var showVersion bool
+var outputFormat string
+var colorMode string
+var logFormat string
+var logStderr bool
+var logSamplingValue string
+var appendLog bool
+var batchID string
+var tagValues []string
+var tags map[string]string
+var stdinName string
+var merge bool
+var mergeName string
+var noOutput bool
+var diffSummary bool
+var summaryTable bool
+var preview int
+var quiet bool
+var preHook string
+var postHook string
+var uploadNameOverride string
+var partContentType string
+var saveErrorsDir string
+var recordRequestsDir string
+var logMaxSize int = defaultLogMaxSize
+var logMaxBackups int = defaultLogMaxBackups
+var logMaxAge int = defaultLogMaxAge
+var logSampling LogSampling
+var outputTemplate string
+var mirrorTree bool
+var collisionPolicy string
+var onConflict string
+var startTime time.Time = time.Now()
+var validateARKs bool
+var validateCSV bool
+var schemaFile string
+var columnSchema *Schema
+var delimiterValue string
+var delimiter rune = ','
+var normalizeDelimiter bool
+var detectEncoding bool
+var canonicalHeader bool
+var countOnlyMode bool
+var multiValueColumns []string
+var maxCellLength int
+var multiValueSeparator string
+var autoOrder bool
+var since string
+var noLock bool
+var customHeaders []string
+var extraHeaders map[string]string
+var authToken string
+var envFile string
+var interactive bool
+var assumeYes bool
+var confirmServer string
+var productionPatternValue string
+var productionPattern *regexp.Regexp
+var sinceThreshold time.Time
+var splitByType bool
+var chunkRows int
+var verifyOutputMode bool
+var dedupeOutput bool
+var prettyErrors bool
+var fileLimit int
+var shuffle bool
+var seed int64
+var verifyConcurrency int
+var fieldMappings []string
+var collectionName string
+var arkPrefix string
+var keepCollectionRows bool
+var sortRows bool
+var sortKey string
+var maxFailures int
+var followRedirects bool
+var maxIdleConns int = defaultMaxIdleConns
+var maxConnsPerHost int
+var disableKeepAlives bool
+var traceUploads bool
+var maxUploadRateValue string
+var maxUploadRate int64
+var maxRetryWait time.Duration
+var maxRuntime time.Duration
+var compressUpload bool
+var idempotency bool
+var loggedResponseHeaders []string
+var failureMode string
+var fieldMap []ColumnRename
+var reportFile string
+var metricsFile string
+var retryReportFile string
+var Logger *zap.Logger = logger(LogFormatJSON, false, zapcore.DebugLevel, LogSampling{})
 var festerizeVersion string = "0.4.2"
 var logFile string = "logs.log"
 
@@ -109,40 +416,228 @@ var rootCmd = &cobra.Command{
 	Use:   "festerize [flags] [src]",
 	Short: "A command-line tool for processing IIIF data.",
 	Long:  festerizeMessage,
+	Args:  cobra.ArbitraryArgs,
 	Run: func(cmd *cobra.Command, args []string) {
+		if showVersion {
+			fmt.Println(versionString())
+			os.Exit(0)
+		}
+
 		// Check if nothing was inputed
-		if len(args) == 0 {
+		if len(args) == 0 && retryReportFile == "" {
 			cmd.Help()
 			os.Exit(0)
 		}
 
+		// Load credentials and expand the environment variables that templated configurations refer to
+		if err := loadEnvAndExpandFlags(cmd.Flags().Changed("env-file")); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+
 		if err := ValidateVersion(); err != nil {
-			fmt.Println("IIIF API Version must be specified. Allowed values are 2 or 3")
-			fmt.Println(iiifApiHelp)
+			fmt.Fprintln(humanOutput(), "IIIF API Version must be specified. Allowed values are 2 or 3")
+			fmt.Fprintln(humanOutput(), iiifApiHelp)
+			os.Exit(1)
+		}
+
+		if err := ValidateFlags(); err != nil {
+			fmt.Fprintln(humanOutput(), err)
 			os.Exit(1)
 		}
 
 		if err := ValidateLoglevel(); err != nil {
-			fmt.Println("Invalid log level. Allowed values are INFO, DEBUG, or ERROR.")
+			fmt.Fprintln(humanOutput(), "Invalid log level. Allowed values are INFO, DEBUG, or ERROR.")
+			os.Exit(1)
+		}
+
+		if err := ValidateMirrorPolicy(); err != nil {
+			fmt.Fprintln(humanOutput(), "Invalid mirror policy. Allowed values are all or any.")
+			os.Exit(1)
+		}
+
+		if err := ValidateOutputFormat(); err != nil {
+			fmt.Fprintln(humanOutput(), "Invalid output format. Allowed values are text or json.")
+			os.Exit(1)
+		}
+
+		if err := ValidateColor(); err != nil {
+			fmt.Fprintln(humanOutput(), "Invalid color mode. Allowed values are auto, always, or never.")
+			os.Exit(1)
+		}
+
+		if err := ValidateOutputTemplate(outputTemplate); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+
+		if err := ValidateFailureMode(); err != nil {
+			fmt.Fprintln(humanOutput(), "Invalid failure mode. Allowed values are total or consecutive.")
+			os.Exit(1)
+		}
+
+		if err := ValidateConnectionPool(maxIdleConns, maxConnsPerHost); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		uploadTransport = newUploadTransport(maxIdleConns, maxConnsPerHost, disableKeepAlives)
+
+		if err := ValidatePartContentType(partContentType); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if err := ValidatePreview(preview); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if err := ValidateStdinName(stdinName); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if err := ValidateMergeName(mergeName); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if err := ValidateOnConflict(); err != nil {
+			fmt.Fprintln(humanOutput(), "Invalid conflict policy. Allowed values are defer, fail, or skip.")
+			os.Exit(1)
+		}
+		if err := ValidateCollisionPolicy(); err != nil {
+			fmt.Fprintln(humanOutput(), "Invalid collision policy. Allowed values are error or number.")
+			os.Exit(1)
+		}
+
+		if err := ValidateLimit(fileLimit); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if err := ValidateVerifyConcurrency(verifyConcurrency); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if err := ValidateChunkRows(chunkRows); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if err := ValidateBasePath(basePath); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if err := ValidateMultiValueSeparator(); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+
+		var err error
+		if fieldMap, err = ParseFieldMap(fieldMappings); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if delimiter, err = ParseDelimiter(delimiterValue); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if productionPattern, err = ParseProductionPattern(productionPatternValue); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if schemaFile != "" {
+			if columnSchema, err = LoadSchema(schemaFile); err != nil {
+				fmt.Fprintln(humanOutput(), err)
+				os.Exit(1)
+			}
+		}
+		if maxUploadRate, err = ParseRate(maxUploadRateValue); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if extraHeaders, err = ParseHeaders(customHeaders); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if since != "" {
+			if sinceThreshold, err = ParseSince(since); err != nil {
+				fmt.Fprintln(humanOutput(), err)
+				os.Exit(1)
+			}
+		}
+
+		if err := ValidateLogFormat(); err != nil {
+			fmt.Fprintln(humanOutput(), "Invalid log format. Allowed values are json or console.")
+			os.Exit(1)
+		}
+		if err := ValidateLogRotation(); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if logSampling, err = ParseLogSampling(logSamplingValue); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if !appendLog {
+			if err := truncateLogFile(); err != nil {
+				fmt.Fprintln(humanOutput(), err)
+				os.Exit(1)
+			}
+		}
+		if batchID == "" {
+			batchID = newBatchID(startTime)
+		}
+		if tags, err = ParseTags(tagValues); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		if err := logSessionStart(logFormat); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
+		// Rebuild the logger with the requested format, outputs, level, and sampling
+		Logger = withTags(withBatchID(logger(logFormat, logStderr, parseLogLevel(loglevel), logSampling), batchID), tags)
+
+		// Check how uploads are authenticated, with the credentials from the env file loaded above
+		if err := ValidateAuth(); err != nil {
+			fmt.Fprintln(humanOutput(), err)
 			os.Exit(1)
 		}
-		// Set loglevel for logger
-		switch loglevel {
-		case "INFO":
-			Logger = Logger.WithOptions(zap.IncreaseLevel(zapcore.InfoLevel))
-		case "DEBUG":
-			Logger = Logger.WithOptions(zap.IncreaseLevel(zapcore.DebugLevel))
-		case "ERROR":
-			Logger = Logger.WithOptions(zap.IncreaseLevel(zapcore.ErrorLevel))
-		default:
-			Logger = Logger.WithOptions(zap.IncreaseLevel(zapcore.InfoLevel))
+
+		// Expand the globs that the shell didn't, as Windows shells don't
+		args = ExpandGlobs(args)
+
+		// Enqueue the files that failed in a previous run
+		if retryReportFile != "" {
+			previous, err := ReadReport(retryReportFile)
+			if err != nil {
+				Logger.Error("Error reading retry report",
+					zap.String("report", retryReportFile),
+					zap.Error(err))
+				fmt.Fprintf(humanOutput(), "There was an error reading the retry report: %v\n", err)
+				os.Exit(int(INVALID_REPORT_SPECIFIED))
+			}
+			failed := FailedFiles(previous)
+			for _, result := range previous.Files {
+				piped := result.Path == stdinSource || slices.Contains(result.Inputs, stdinSource)
+				if result.Status == StatusFailure && piped {
+					Logger.Warn("Can't retry a CSV that was read from stdin",
+						zap.String("report", retryReportFile),
+						zap.String("filename", result.Filename))
+				}
+			}
+			Logger.Info("Retrying failed files from previous run",
+				zap.String("report", retryReportFile),
+				zap.Int("count", len(failed)))
+			args = append(args, failed...)
 		}
 
 		if len(args) == 0 {
-			fmt.Println("Please provide one or more CSV files")
+			fmt.Fprintln(humanOutput(), "Please provide one or more CSV files")
 			os.Exit(int(NO_FILES_SPECIFIED))
 		}
 		src = append(src, args...)
+
+		if err := ValidateUploadName(uploadNameOverride, src); err != nil {
+			fmt.Fprintln(humanOutput(), err)
+			os.Exit(1)
+		}
 	},
 }
 
@@ -166,6 +661,28 @@ func ValidateVersion() error {
 	}
 }
 
+// ValidateFlags rejects combinations of flags that can't be used together
+func ValidateFlags() error {
+	switch {
+	case reportFile != "" && filepath.Clean(reportFile) == filepath.Clean(metricsFile):
+		return errors.New("--report and --metrics-file can't write to the same file")
+	case metricsFile != "" && filepath.Clean(metricsFile) == filepath.Clean(retryReportFile):
+		return errors.New("--metrics-file can't overwrite the report passed to --retry-report")
+	case since != "" && retryReportFile != "":
+		return errors.New("--since can't be used with --retry-report, since it could skip the files to retry")
+	case maxRuntime < 0:
+		return errors.New("--max-runtime can't be negative")
+	case maxCellLength < 0:
+		return errors.New("--max-cell-length can't be negative")
+	case noOutput && out == stdoutOutput:
+		return errors.New("--no-output can't be used with --out -, since nothing would be written")
+	case out == stdoutOutput && outputFormat == OutputFormatJSON:
+		return errors.New("--out - can't be used with --output-format json, since both write to stdout")
+	default:
+		return nil
+	}
+}
+
 // ApplyExitOnHelp exits out of program if --help is flag
 func ApplyExitOnHelp(c *cobra.Command, exitCode int) {
 	helpFunc := c.HelpFunc()
@@ -175,44 +692,68 @@ func ApplyExitOnHelp(c *cobra.Command, exitCode int) {
 	})
 }
 
-// logger creates logger with output of info and debug to file and erro to stdout
-func logger() *zap.Logger {
-	pe := zap.NewDevelopmentEncoderConfig()
-
-	fileEncoder := zapcore.NewJSONEncoder(pe)
-
-	pe.EncodeTime = zapcore.ISO8601TimeEncoder // The encoder can be customized for each output
-
+// logger creates logger that writes entries at or above the supplied level to the log file in the supplied
+// format, and optionally to stderr as well, sampling repeated messages if asked to
+func logger(format string, toStderr bool, level zapcore.Level, sampling LogSampling) *zap.Logger {
 	// Create file core
-	file, err := os.Create(logFile)
+	file, err := openLogFile()
 	if err != nil {
 		panic(err)
 	}
 
-	fileCore := zapcore.NewCore(fileEncoder, zapcore.AddSync(file), zap.DebugLevel)
+	cores := []zapcore.Core{newLogCore(logEncoder(format), zapcore.AddSync(file), level, sampling)}
+
+	// Create stderr core
+	if toStderr {
+		cores = append(cores, newLogCore(logEncoder(format), zapcore.Lock(os.Stderr), level, sampling))
+	}
 
-	// Create a logger with two cores
-	logger := zap.New(zapcore.NewTee(fileCore), zap.AddCaller())
+	// Create a logger with the cores, each of which scrubs credentials from what it writes
+	logger := zap.New(zapcore.NewTee(cores...), zap.AddCaller())
 
 	return logger
 }
 
-// CreateOuputDir creates output directory
-func CreateOutputDir() error {
-	if _, err := os.Stat(out); os.IsNotExist(err) {
-		fmt.Printf("Output directory %s not found, creating it.\n", out)
-		if err := os.MkdirAll(out, os.ModePerm); err != nil {
+// OverwritePolicy decides what happens when the output directory already exists
+type OverwritePolicy int
+
+const (
+	// OverwritePrompt asks the user whether to use an existing output directory
+	OverwritePrompt OverwritePolicy = iota
+	// OverwriteAllow uses an existing output directory without asking
+	OverwriteAllow
+	// OverwriteDeny refuses to use an existing output directory
+	OverwriteDeny
+)
+
+// errAborted is returned when the user declines to use an existing output directory
+var errAborted = errors.New("aborted")
+
+// CreateOutputDir creates the output directory at path, or decides whether an existing one can be used
+// according to the policy; when prompting, the answer is read from in
+func CreateOutputDir(path string, in io.Reader, policy OverwritePolicy) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Fprintf(humanOutput(), "Output directory %s not found, creating it.\n", path)
+		if err := os.MkdirAll(path, os.ModePerm); err != nil {
 			return errors.New("error creating output directory")
 		}
-	} else {
-		fmt.Printf("Output directory %s found, should we continue? YES might overwrite any existing output files. (yes/no): ", out)
+		return nil
+	}
+
+	switch policy {
+	case OverwriteAllow:
+		return nil
+	case OverwriteDeny:
+		return fmt.Errorf("output directory %s already exists", path)
+	default:
+		fmt.Fprintf(humanOutput(), "Output directory %s found, should we continue? YES might overwrite any existing output files. (yes/no): ", path)
 		var response string
-		fmt.Scanln(&response)
+		fmt.Fscanln(in, &response)
 		if response != "yes" {
-			return errors.New("aborted")
+			return errAborted
 		}
+		return nil
 	}
-	return nil
 }
 
 // FesterStatus checks Fester availability
@@ -231,37 +772,95 @@ func FesterStatus(getStatusURL string) (int, error) {
 	}
 }
 
+// truncate shortens a string to at most max bytes for logging
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max] + "...(truncated)"
+}
+
+// UploadOptions holds the settings of an upload to Fester: the form fields and headers it's sent with, how the
+// CSV is read and rewritten beforehand, and how the request is made
+type UploadOptions struct {
+	IIIFVersion       string
+	IIIFHost          string
+	MetadataUpdate    bool
+	Headers           map[string]string
+	Input             CSVInput
+	Transforms        []CSVTransform
+	SplitByType       bool
+	ChunkRows         int
+	Compress          bool
+	Idempotency       bool
+	UploadName        string
+	PartContentType   string
+	RecordRequestsDir string
+	LogHeaders        []string
+	MaxRate           int64
+	Trace             bool
+
+	// UploadedBytes, if set, counts the bytes of the request bodies sent
+	UploadedBytes *atomic.Int64
+}
+
 // uploadCSV uploads csv to Fester and returns respone
-func uploadCSV(filePath, postURL, iiifAPIVersion, iiifHost string,
-	metadataUpdate bool, headers map[string]string) (*http.Response, []byte, error) {
-	file, err := os.Open(filePath)
+func uploadCSV(ctx context.Context, filePath, postURL string,
+	opts UploadOptions) (*http.Response, []byte, error) {
+	file, err := opts.Input.open(filePath)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer file.Close()
 
+	content, err := transformCSV(file, opts.Input, opts.Transforms)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Name gzipped files after the CSV they contain
+	return postCSV(ctx, csvName(filePath), content, postURL, opts)
+}
+
+// postCSV sends CSV content to Fester under the supplied filename and returns the response
+func postCSV(ctx context.Context, uploadName string, content io.Reader, postURL string,
+	opts UploadOptions) (*http.Response, []byte, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
 	// Add the file field to the request
-	part, err := writer.CreateFormFile("file", filePath)
+	contentType := opts.PartContentType
+	if contentType == "" {
+		contentType = defaultPartContentType
+	}
+	part, err := writer.CreatePart(filePartHeader(multipartFilename(uploadName, opts.UploadName), contentType))
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// Derive the upload's idempotency key from its content as it's copied
+	var keyHash *idempotencyHash
+	if opts.Idempotency {
+		keyHash = newIdempotencyHash(uploadName)
+		content = io.TeeReader(content, keyHash)
+	}
+
 	// Copy the file content into the form field
-	_, err = io.Copy(part, file)
+	_, err = io.Copy(part, content)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Add other fields to the request payload
-	writer.WriteField("iiif-version", "v"+iiifAPIVersion)
-	if iiifHost != "" {
-		writer.WriteField("iiif-host", iiifHost)
+	fields := []string{"file", "iiif-version"}
+	writer.WriteField("iiif-version", "v"+opts.IIIFVersion)
+	if opts.IIIFHost != "" {
+		writer.WriteField("iiif-host", opts.IIIFHost)
+		fields = append(fields, "iiif-host")
 	}
-	if metadataUpdate {
+	if opts.MetadataUpdate {
 		writer.WriteField("metadata-update", "true")
+		fields = append(fields, "metadata-update")
 	}
 
 	// Close the multipart writer
@@ -270,49 +869,236 @@ func uploadCSV(filePath, postURL, iiifAPIVersion, iiifHost string,
 		return nil, nil, err
 	}
 
+	// Compress the request body when the server accepts gzipped uploads
+	payload := body
+	if opts.Compress {
+		if payload, err = gzipBytes(body.Bytes()); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Record how long each phase of the request takes
+	var trace *UploadTrace
+	if opts.Trace {
+		ctx, trace = withUploadTrace(ctx)
+	}
+
 	// Create a POST request with the file upload
-	request, err := http.NewRequest("POST", postURL, body)
+	request, err := http.NewRequestWithContext(ctx, "POST", postURL, payload)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// Cap the rate the upload is sent at, keeping its content length
+	throttleRequest(request, opts.MaxRate)
+
 	// Set the content type for the request
 	request.Header.Set("Content-Type", writer.FormDataContentType())
+	if opts.Compress {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+
+	// Let Fester recognize an upload it has already processed; it's sent again with the same key when retried
+	if keyHash != nil {
+		request.Header.Set(idempotencyKeyHeader, keyHash.Key(opts.IIIFVersion, opts.IIIFHost, opts.MetadataUpdate))
+	}
 
 	// Add custom headers to the request
-	for key, value := range headers {
+	for key, value := range opts.Headers {
 		request.Header.Set(key, value)
 	}
 
+	if err := authenticate(request, authToken); err != nil {
+		return nil, nil, err
+	}
+
+	// Keep a copy of the request, as it goes over the wire, for reproducing problems
+	recordRequest(opts.RecordRequestsDir, uploadName, request, payload.Bytes(), opts.Headers)
+
+	Logger.Debug("Sending request to Fester",
+		zap.String("method", request.Method),
+		zap.String("url", request.URL.String()),
+		zap.Any("headers", redactHeaders(request.Header)),
+		zap.Strings("fields", fields))
+
 	// Make the request
-	client := &http.Client{}
+	client := newUploadClient()
 
-	response, err := client.Do(request)
+	response, err := doWithRateLimit(ctx, client, request)
 	if err != nil {
 		return nil, nil, err
 	}
+	defer response.Body.Close()
+	if opts.UploadedBytes != nil {
+		opts.UploadedBytes.Add(request.ContentLength)
+	}
 
 	// Create a copy of the response body
 	responseBody, err := io.ReadAll(response.Body)
 	if err != nil {
 		return nil, nil, err
 	}
+	if trace != nil {
+		logUploadTrace(request.URL.String(), trace)
+	}
 
-	defer response.Body.Close()
+	Logger.Debug("Received response from Fester",
+		zap.Int("status_code", response.StatusCode),
+		zap.String("body", truncate(string(responseBody), maxLoggedBodyLength)))
+
+	// Log the headers that help to find the request in Fester's own logs
+	if logged := selectHeaders(response.Header, opts.LogHeaders); len(logged) > 0 {
+		Logger.Info("Fester response headers",
+			zap.String("url", request.URL.String()),
+			zap.Int("status_code", response.StatusCode),
+			zap.Any("headers", redactMap(logged)))
+	}
 
 	return response, responseBody, nil
 }
 
+// writeReport writes the results of the run to the report file, if one was requested
+func writeReport(reportFile string, results []FileResult) {
+	if reportFile == "" {
+		return
+	}
+	if err := WriteReport(reportFile, NewReport(results)); err != nil {
+		Logger.Error("Error writing report",
+			zap.String("report", reportFile),
+			zap.Error(err))
+		fmt.Fprintln(humanOutput(), "There was an error writing the report")
+	}
+}
+
+// finish writes the report and metrics for the run, if they were requested
+func finish(cfg Config, results []FileResult, uploadedBytes int64) {
+	writeReport(cfg.ReportFile, results)
+	writeMetrics(cfg.MetricsFile, results, uploadedBytes, time.Since(cfg.StartTime))
+}
+
 // init initates flags
 func init() {
 	// Flags
 	rootCmd.Flags().StringVarP(&iiifApiVersion, "iiif-api-version", "v", "", iiifApiHelp)
-	rootCmd.Flags().StringVarP(&server, "server", "", "https://test.ingest.iiif.library.ucla.edu", "URL of the Fester service dedicated for ingest")
-	rootCmd.Flags().StringVarP(&out, "out", "", "output", "Local directory to put the updated CSV")
+	rootCmd.Flags().StringArrayVarP(&servers, "server", "", []string{defaultServer}, serverHelp)
+	rootCmd.Flags().StringVarP(&mirrorPolicy, "mirror-policy", "", MirrorPolicyAll, mirrorPolicyHelp)
+	rootCmd.Flags().StringVarP(&recordRequestsDir, "record-requests", "", "", recordRequestsHelp)
+	rootCmd.Flags().StringVarP(&saveErrorsDir, "save-errors", "", "", "Directory to save the full response of each failed upload in, named after the input")
+	rootCmd.Flags().StringVarP(&partContentType, "part-content-type", "", defaultPartContentType, "Content type of the uploaded CSV's part of the request")
+	rootCmd.Flags().StringVarP(&uploadNameOverride, "upload-name", "", "", "Filename to send Fester the CSV under, instead of its own name (for a single file)")
+	rootCmd.Flags().StringVarP(&preHook, "pre-hook", "", "", preHookHelp)
+	rootCmd.Flags().StringVarP(&postHook, "post-hook", "", "", postHookHelp)
+	rootCmd.Flags().BoolVarP(&diffSummary, "diff-summary", "", false, "Report how many rows of each CSV gained manifest URLs, and which collection and work rows didn't")
+	rootCmd.Flags().IntVarP(&preview, "preview", "", 0, "Print the header and the first and last N rows of each CSV Fester returns to stderr")
+	rootCmd.Flags().BoolVarP(&summaryTable, "summary-table", "", false, "Print a table of the outcome of each file at the end of the run")
+	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Don't print the success banners or the summary table")
+	rootCmd.Flags().BoolVarP(&noOutput, "no-output", "", false, "Upload the CSVs without saving the CSVs Fester returns")
+	rootCmd.Flags().StringVarP(&out, "out", "", "output", "Local directory to put the updated CSV, or - to write it to stdout")
+	rootCmd.Flags().StringVarP(&basePath, "base-path", "", "", basePathHelp)
 	rootCmd.Flags().StringVarP(&iiifhost, "iiifhost", "", "", "IIIF image server URL (optional)")
 	rootCmd.Flags().BoolVarP(&metadata, "metadata-update", "m", false, "Only update manifest (work) metadata; don't update canvases (pages).")
 	rootCmd.Flags().BoolVarP(&strictMode, "strict-mode", "", false, strictModeHelp)
+	rootCmd.Flags().BoolVarP(&strictValidation, "strict-validation", "", false, strictValidationHelp)
+	rootCmd.Flags().BoolVarP(&strictServer, "strict-server", "", false, strictServerHelp)
+	rootCmd.Flags().StringVarP(&logFormat, "log-format", "", LogFormatJSON, "Format of the log file (json or console)")
+	rootCmd.Flags().StringVarP(&batchID, "batch-id", "", "", batchIDHelp)
+	rootCmd.Flags().StringArrayVarP(&tagValues, "tag", "", nil, tagHelp)
+	rootCmd.Flags().BoolVarP(&appendLog, "append-log", "", false, "Append to the log file instead of truncating it at the start of the run")
+	rootCmd.Flags().IntVarP(&logMaxSize, "log-max-size", "", defaultLogMaxSize, "Size in megabytes at which the log file is rotated")
+	rootCmd.Flags().IntVarP(&logMaxBackups, "log-max-backups", "", defaultLogMaxBackups, "Number of rotated log files to keep (0 keeps them all)")
+	rootCmd.Flags().IntVarP(&logMaxAge, "log-max-age", "", defaultLogMaxAge, "Days to keep rotated log files (0 keeps them regardless of age)")
+	rootCmd.Flags().StringVarP(&logSamplingValue, "log-sampling", "", "", logSamplingHelp)
+	rootCmd.Flags().BoolVarP(&logStderr, "log-stderr", "", false, "Also write the log to stderr, at the --loglevel level, to watch a run live")
 	rootCmd.Flags().StringVarP(&loglevel, "loglevel", "", "INFO", "Log level (INFO, DEBUG, ERROR)")
+	rootCmd.Flags().BoolVarP(&showVersion, "version", "", false, "Print the festerize version and exit")
+	rootCmd.Flags().BoolVarP(&merge, "merge", "", false, mergeHelp)
+	rootCmd.Flags().StringVarP(&mergeName, "merge-name", "", defaultMergeName, "Filename to upload and save the CSVs combined with --merge as")
+	rootCmd.Flags().StringVarP(&stdinName, "stdin-name", "", defaultStdinName, "Filename to upload and save a CSV read from stdin (given as the - source) as")
+	rootCmd.Flags().StringVarP(&outputTemplate, "output-template", "", defaultOutputTemplate, outputTemplateHelp)
+	rootCmd.Flags().BoolVarP(&mirrorTree, "mirror-tree", "", false, mirrorTreeHelp)
+	rootCmd.Flags().StringVarP(&collisionPolicy, "collision-policy", "", CollisionPolicyError, collisionPolicyHelp)
+	rootCmd.Flags().StringVarP(&onConflict, "on-conflict", "", ConflictFail, onConflictHelp)
+	rootCmd.Flags().StringVarP(&outputFormat, "output-format", "", OutputFormatText, outputFormatHelp)
+	rootCmd.Flags().StringVarP(&colorMode, "color", "", ColorAuto, "Color success and failure messages (auto, always, or never)")
+	rootCmd.Flags().StringVarP(&delimiterValue, "delimiter", "", ",", delimiterHelp)
+	rootCmd.Flags().BoolVarP(&detectEncoding, "detect-encoding", "", false, detectEncodingHelp)
+	rootCmd.Flags().BoolVarP(&normalizeDelimiter, "normalize-delimiter", "", false, "Convert CSVs split on another --delimiter to comma-delimited before upload")
+	rootCmd.Flags().BoolVarP(&validateCSV, "validate-csv", "", false, "Check that each CSV parses cleanly, with as many fields in every row as in the header, before upload")
+	rootCmd.Flags().StringVarP(&schemaFile, "schema-file", "", "", schemaFileHelp)
+	rootCmd.Flags().BoolVarP(&canonicalHeader, "canonical-header", "", false, canonicalHeaderHelp)
+	rootCmd.Flags().StringSliceVarP(&multiValueColumns, "multivalue-columns", "", nil, multiValueColumnsHelp)
+	rootCmd.Flags().IntVarP(&maxCellLength, "max-cell-length", "", 0, "Warn about cells longer than this many characters, which Fester may truncate (0 for no check)")
+	rootCmd.Flags().StringVarP(&multiValueSeparator, "multivalue-sep", "", defaultMultiValueSeparator, "Separator between the values of a --multivalue-columns cell")
+	rootCmd.Flags().BoolVarP(&validateARKs, "validate-arks", "", false, "Check that the Item ARK and Parent ARK columns hold well-formed ARKs before upload")
+	rootCmd.Flags().BoolVarP(&noLock, "no-lock", "", false, "Don't lock the output directory against concurrent festerize runs")
+	rootCmd.Flags().BoolVarP(&strictCompat, "strict-compat", "", false, strictCompatHelp)
+	rootCmd.Flags().IntVarP(&maxFailures, "max-failures", "", 0, maxFailuresHelp)
+	rootCmd.Flags().StringVarP(&failureMode, "failure-mode", "", FailureModeTotal, "How failures count towards --max-failures (total or consecutive)")
+	rootCmd.Flags().BoolVarP(&interactive, "interactive", "", false, "Show a summary of the upload and ask for confirmation before starting it, exiting with code 16 if it isn't given")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "", false, "Don't ask for confirmation with --interactive or before uploading to production (required when not run from a terminal)")
+	rootCmd.Flags().StringVarP(&confirmServer, "confirm-server", "", "", "Hostname of the production server the run is meant for, to upload to it without being asked")
+	rootCmd.Flags().StringVarP(&productionPatternValue, "production-pattern", "", defaultProductionPattern, productionPatternHelp)
+	rootCmd.Flags().StringVarP(&since, "since", "", "", sinceHelp)
+	rootCmd.Flags().BoolVarP(&autoOrder, "auto-order", "", false, autoOrderHelp)
+	rootCmd.Flags().BoolVarP(&splitByType, "split-by-type", "", false, splitByTypeHelp)
+	rootCmd.Flags().IntVarP(&chunkRows, "chunk-rows", "", 0, chunkRowsHelp)
+	rootCmd.Flags().BoolVarP(&shuffle, "shuffle", "", false, shuffleHelp)
+	rootCmd.Flags().Int64VarP(&seed, "seed", "", 0, "Seed for --shuffle, to repeat the order of an earlier run (default random, and logged)")
+	rootCmd.Flags().IntVarP(&fileLimit, "limit", "", 0, limitHelp)
+	rootCmd.Flags().BoolVarP(&warnUnknownObjectTypes, "warn-unknown-object-types", "", false, warnUnknownObjectTypesHelp)
+	rootCmd.Flags().BoolVarP(&fixObjectTypes, "fix-object-types", "", false, "Correct the case and surrounding whitespace of Object Type values, such as \"collection \", before upload")
+	rootCmd.Flags().BoolVarP(&prettyErrors, "pretty-errors", "", false, prettyErrorsHelp)
+	rootCmd.Flags().BoolVarP(&dedupeOutput, "dedupe-output", "", false, "Leave an output CSV alone when Fester returns exactly what it already holds, keeping its modification time")
+	rootCmd.Flags().BoolVarP(&verifyOutputMode, "verify-output", "", false, verifyOutputHelp)
+	rootCmd.Flags().IntVarP(&verifyConcurrency, "verify-concurrency", "", defaultVerifyConcurrency, "Number of manifests --verify-output requests at once")
+	rootCmd.Flags().BoolVarP(&countOnlyMode, "count-only", "", false, countOnlyHelp)
+	rootCmd.Flags().StringSliceVarP(&loggedResponseHeaders, "log-response-headers", "", []string{"X-Request-Id", "Server"},
+		"Response headers from Fester to log after each upload, such as its request ID (comma separated)")
+	rootCmd.Flags().BoolVarP(&compressUpload, "compress-upload", "", false, compressUploadHelp)
+	rootCmd.Flags().BoolVarP(&idempotency, "idempotency", "", false, idempotencyHelp)
+	rootCmd.Flags().DurationVarP(&maxRuntime, "max-runtime", "", 0, maxRuntimeHelp)
+	rootCmd.Flags().DurationVarP(&maxRetryWait, "max-retry-wait", "", time.Minute, maxRetryWaitHelp)
+	rootCmd.Flags().BoolVarP(&followRedirects, "follow-redirects", "", true, followRedirectsHelp)
+	rootCmd.Flags().IntVarP(&maxIdleConns, "max-idle-conns", "", defaultMaxIdleConns, "Number of idle connections to keep for reuse (0 for no limit)")
+	rootCmd.Flags().IntVarP(&maxConnsPerHost, "max-conns-per-host", "", 0, "Number of connections that may be open to a server at once (0 for no limit)")
+	rootCmd.Flags().StringVarP(&maxUploadRateValue, "max-upload-rate", "", "", maxUploadRateHelp)
+	rootCmd.Flags().BoolVarP(&traceUploads, "trace", "", false, traceHelp)
+	rootCmd.Flags().BoolVarP(&disableKeepAlives, "disable-keep-alives", "", false, "Open a new connection for every request, for debugging")
+	rootCmd.Flags().StringVarP(&authToken, "token", "", "", "Bearer token to authenticate uploads with (default $"+tokenEnvVar+")")
+	rootCmd.Flags().StringVarP(&envFile, "env-file", "", defaultEnvFile, "File to load environment variables, such as "+tokenEnvVar+", from")
+	rootCmd.Flags().StringArrayVarP(&customHeaders, "header", "", nil, "Add a header to upload requests, as key:value (repeatable)")
+	rootCmd.Flags().StringVarP(&collectionName, "collection-name", "", "", collectionNameHelp)
+	rootCmd.Flags().StringVarP(&arkPrefix, "ark-prefix", "", "", arkPrefixHelp)
+	rootCmd.Flags().BoolVarP(&sortRows, "sort-rows", "", false, sortRowsHelp)
+	rootCmd.Flags().StringVarP(&sortKey, "sort-key", "", itemARKColumn, "Column --sort-rows sorts rows of the same object type by")
+	rootCmd.Flags().BoolVarP(&keepCollectionRows, "keep-collection-rows", "", true, "With --ark-prefix, keep collection rows whatever their Item ARK")
+	rootCmd.Flags().StringArrayVarP(&fieldMappings, "map", "", nil, "Rename a CSV column before it is checked and uploaded, as old=new (repeatable)")
+	rootCmd.Flags().StringVarP(&reportFile, "report", "", "", "Write a JSON report of the outcome of each file to this path")
+	rootCmd.Flags().StringVarP(&metricsFile, "metrics-file", "", "", "Write Prometheus text-format metrics for the run to this path")
+	rootCmd.Flags().StringVarP(&retryReportFile, "retry-report", "", "", "Re-process only the files that failed in this previous JSON report")
+
+	// Subcommands
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(endpointsCmd)
+	endpointsCmd.Flags().StringVarP(&endpointsServer, "server", "", defaultServer, "URL of the Fester service to check")
+	endpointsCmd.Flags().StringVarP(&basePath, "base-path", "", "", basePathHelp)
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().StringVarP(&replayServer, "server", "", defaultServer, "URL of the Fester service to send the requests to")
+	replayCmd.Flags().StringVarP(&authToken, "token", "", "", "Bearer token to authenticate with (default $"+tokenEnvVar+")")
+	replayCmd.Flags().StringVarP(&envFile, "env-file", "", defaultEnvFile, "File to load environment variables, such as "+tokenEnvVar+", from")
+	replayCmd.Flags().StringArrayVarP(&replayHeaderValues, "header", "", nil, "Add a header to the requests, as key:value, in place of a hidden one (repeatable)")
+	rootCmd.AddCommand(compareCmd)
+	compareCmd.Flags().StringVarP(&compareServer, "server", "", defaultServer, "URL of the Fester service to compare with")
+	compareCmd.Flags().StringVarP(&basePath, "base-path", "", "", basePathHelp)
+	compareCmd.Flags().StringVarP(&compareCollection, "collection", "", "", "ARK of the collection to compare with (default the CSV's collection row)")
+	compareCmd.Flags().StringVarP(&authToken, "token", "", "", "Bearer token to authenticate with (default $"+tokenEnvVar+")")
+	compareCmd.Flags().StringVarP(&envFile, "env-file", "", defaultEnvFile, "File to load environment variables, such as "+tokenEnvVar+", from")
+	compareCmd.Flags().StringVarP(&delimiterValue, "delimiter", "", ",", delimiterHelp)
+	compareCmd.Flags().BoolVarP(&detectEncoding, "detect-encoding", "", false, detectEncodingHelp)
+	rootCmd.AddCommand(columnsCmd)
+	columnsCmd.Flags().StringVarP(&delimiterValue, "delimiter", "", ",", delimiterHelp)
+	columnsCmd.Flags().BoolVarP(&detectEncoding, "detect-encoding", "", false, detectEncodingHelp)
 }
 
 func main() {
@@ -320,142 +1106,23 @@ func main() {
 	if err := rootCmd.Execute(); err != nil {
 		Logger.Error("Error setting command line",
 			zap.Error(err))
-		fmt.Println("There was an error setting the command line")
+		fmt.Fprintln(humanOutput(), "There was an error setting the command line")
 		os.Exit(1)
 	}
 
-	// Create output directory
-	if err := CreateOutputDir(); err != nil {
-		Logger.Error("Error creating output directory",
-			zap.Error(err))
-		fmt.Println("There was an error creating an output directory")
-		os.Exit(int(INVALID_OUTPUT_SPECIFIED))
-	}
+	// Stop uploading when the user interrupts the run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// HTTP request URLs.
-	getStatusURL := server + "/fester/status"
-	postCSVUrl := server + "/collections"
+	cfg := configFromFlags()
+	logEffectiveConfig(cfg, rootCmd.Flags())
 
-	// HTTP request headers
-	requestHeaders := map[string]string{
-		"User-Agent": fmt.Sprintf("%s/%s", "Festerize", festerizeVersion),
+	exitCode, _, err := run(ctx, cfg)
+	if err := logSessionEnd(logFormat, exitCode); err != nil {
+		fmt.Fprintln(humanOutput(), err)
 	}
-
-	// Check if Fester is available
-	if statusCode, err := FesterStatus(getStatusURL); err != nil {
-		if statusCode != 0 {
-			Logger.Error("Error connecting to Fester: Unexpected status code",
-				zap.Int("status_code", statusCode),
-			)
-		} else {
-			Logger.Error("Error making HTTP request to Fester",
-				zap.Error(err),
-			)
-		}
-		fmt.Println("There was an error connecting to Fester")
-		os.Exit(int(FESTER_UNAVAILABLE))
-	} else {
-		Logger.Info("Got valid status code connected to Fester",
-			zap.Int("status_code", statusCode),
-		)
-	}
-
-	for _, pathString := range src {
-		// Convert the path string to an absolute path
-		absPath, err := filepath.Abs(pathString)
-		filename := filepath.Base(absPath)
-		if err != nil {
-			Logger.Error("Error getting absolute path",
-				zap.Error(err))
-			fmt.Println("There was an error getting the absolute path of the CSV")
-			if strictMode {
-				os.Exit(int(FILE_IO_ERROR))
-			}
-			continue
-		}
-
-		if _, err := os.Stat(absPath); os.IsNotExist(err) {
-			Logger.Error("File does not exist",
-				zap.String("filename", filename),
-				zap.Error(err),
-			)
-			fmt.Printf("%s does not exist\n", filename)
-			if strictMode {
-				os.Exit(int(NONEXISTENT_FILE_SPECIFIED))
-			}
-		} else if strings.EqualFold(filepath.Ext(filename), ".csv") {
-			Logger.Info("Uploading file to Fester",
-				zap.String("filename", filename),
-				zap.String("post URL", postCSVUrl))
-			response, responseBody, err := uploadCSV(absPath, postCSVUrl, iiifApiVersion, iiifhost, metadata, requestHeaders)
-			if response.StatusCode == 201 {
-				Logger.Info("File was uploaded to Fester succesfully",
-					zap.String("filename", filename),
-				)
-
-				// Save the result CSV to the output directory
-				csvPath := filepath.Join(out, filename)
-
-				file, err := os.Create(csvPath)
-				if err != nil {
-					Logger.Error("Error creating file", zap.Error(err))
-					fmt.Printf("There was an error creating the festerized version of %s\n", filename)
-					if strictMode {
-						os.Exit(int(FILE_IO_ERROR))
-					}
-					continue
-				}
-				defer file.Close()
-
-				_, err = file.Write(responseBody)
-				if err != nil {
-					Logger.Error("Error writing to file", zap.Error(err))
-					fmt.Printf("There was an error writing to %s\n", filename)
-					if strictMode {
-						os.Exit(int(FILE_IO_ERROR))
-					}
-					continue
-				} else {
-					extraSatisfaction := []string{"🎉", "🎊", "✨", "💯", "😎", "✔️ ", "👍"} // Add more awesome characters if needed
-
-					// Create a string of emojis repeated
-					borderChar := extraSatisfaction[rand.Intn(len(extraSatisfaction))]
-					message := "SUCCESS! Uploaded " + filename
-					numSatisfaction := len(message)/2 + 3
-					fmt.Println(strings.Repeat(borderChar, numSatisfaction))
-					fmt.Println(borderChar, message, borderChar)
-					fmt.Println(strings.Repeat(borderChar, numSatisfaction))
-
-				}
-			} else {
-				if err != nil {
-					Logger.Error("There was an error creating and posting the request: ", zap.Error(err))
-					fmt.Printf("There was an error creating and posting the request for %s\n", filename)
-				}
-
-				doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(responseBody)))
-				if err != nil {
-					Logger.Error("Failed to parse error HTML",
-						zap.Error(err))
-					continue
-				}
-				// Log error response
-				errorCause := doc.Find("#error-message").Text()
-				Logger.Error("Failed to upload file to Fester",
-					zap.String("filename", filename),
-					zap.String("error", errorCause))
-				if strictMode {
-					os.Exit(int(FESTER_ERROR_RESPONSE))
-				}
-			}
-		} else {
-			Logger.Error("This file is not a CSV file",
-				zap.String("filename", filename))
-			fmt.Printf("%s is not a CSV", filename)
-			if strictMode {
-				os.Exit(int(NON_CSV_FILE_SPECIFIED))
-			}
-		}
+	if err != nil {
+		stop()
+		os.Exit(exitCode)
 	}
-
 }
@@ -3,17 +3,21 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -24,6 +28,9 @@ var userInputMutex sync.Mutex
 var TestOutputDir string = "test/test-resources/test_output_dir"
 var TestDirUnFester string = "test/test-resources/un-festerized"
 var TestDirFester string = "test/test-resources/festerized"
+var TestDirGzipped string = "test/test-resources/gzipped"
+var TestDirMalformed string = "test/test-resources/malformed"
+var TestDirDelimited string = "test/test-resources/delimited"
 
 // MemorySink implements zap.Sink by writing all messages to a buffer.
 type MemorySink struct {
@@ -44,12 +51,12 @@ func createLogger() (Logger *zap.Logger, sink *MemorySink) {
 		return sink, nil
 	})
 
-	// Create a logger instance using the registered sink.
-	Logger = zap.New(zapcore.NewCore(
+	// Create a logger instance using the registered sink, redacting like the real logger.
+	Logger = zap.New(newRedactingCore(zapcore.NewCore(
 		zapcore.NewJSONEncoder(zap.NewDevelopmentEncoderConfig()),
 		zapcore.AddSync(sink),
 		zapcore.DebugLevel,
-	))
+	)))
 	return Logger, sink
 }
 
@@ -67,30 +74,51 @@ func simulateUserInput(input string) {
 	os.Stdin = reader
 }
 
+// capturedStdout holds what was printed to the standard out while it was redirected
+type capturedStdout struct {
+	buffer   bytes.Buffer
+	writer   *os.File
+	original *os.File
+	copied   chan struct{}
+	stopped  sync.Once
+}
+
+// stop restores the standard out and waits until everything printed to the pipe has been copied into the buffer
+func (c *capturedStdout) stop() {
+	c.stopped.Do(func() {
+		os.Stdout = c.original
+		_ = c.writer.Close()
+		<-c.copied
+	})
+}
+
+// String stops the redirect and returns everything that was printed while it was in place
+func (c *capturedStdout) String() string {
+	c.stop()
+	return c.buffer.String()
+}
+
 // redirectStdoutToBuffer redirects the standard out so that it is not seen when running test
-func redirectStdoutToBuffer(t *testing.T) *bytes.Buffer {
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
+func redirectStdoutToBuffer(t *testing.T) *capturedStdout {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	returnedBuffer := new(bytes.Buffer)
+	captured := &capturedStdout{writer: w, original: os.Stdout, copied: make(chan struct{})}
+	os.Stdout = w
 
 	go func() {
-		defer func() {
-			_ = r.Close()
-			os.Stdout = oldStdout
-		}()
+		defer close(captured.copied)
+		defer r.Close()
 
-		io.Copy(returnedBuffer, r)
+		io.Copy(&captured.buffer, r)
 	}()
 
 	// Restore os.Stdout when the test ends
-	t.Cleanup(func() {
-		_ = r.Close()
-		os.Stdout = oldStdout
-	})
+	t.Cleanup(captured.stop)
 
-	return returnedBuffer
+	return captured
 }
 
 // compareCSVs compares two CSV files and returns true if they are identical, false otherwise.
@@ -192,50 +220,119 @@ func TestValidateVersion(t *testing.T) {
 	}
 }
 
+// TestValidateFlags tests that incompatible flag combinations are rejected
+func TestValidateFlags(t *testing.T) {
+	defer func() { reportFile, metricsFile, retryReportFile, since = "", "", "", "" }()
+
+	tests := []struct {
+		name        string
+		report      string
+		metrics     string
+		retryReport string
+		since       string
+		wantErr     bool
+	}{
+		{"No files", "", "", "", "", false},
+		{"Separate files", "report.json", "metrics.prom", "previous.json", "", false},
+		{"Report and metrics in one file", "run.out", "./run.out", "", "", true},
+		{"Metrics over the retry report", "", "previous.json", "previous.json", "", true},
+		{"Report over the retry report", "previous.json", "", "previous.json", "", false},
+		{"Since without retry", "", "", "", "2024-06-01T00:00:00Z", false},
+		{"Since with retry", "", "", "previous.json", "2024-06-01T00:00:00Z", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reportFile, metricsFile, retryReportFile, since = tt.report, tt.metrics, tt.retryReport, tt.since
+			err := ValidateFlags()
+
+			if tt.wantErr && err == nil {
+				t.Error("Expected an error, but got none.")
+			} else if !tt.wantErr && err != nil {
+				t.Error("Unexpected error:", err)
+			}
+		})
+	}
+}
+
+// TestValidateFlagsOutput tests that output modes that can't work together are rejected
+func TestValidateFlagsOutput(t *testing.T) {
+	defer func() { out, outputFormat, noOutput = "output", OutputFormatText, false }()
+
+	tests := []struct {
+		name    string
+		out     string
+		format  string
+		none    bool
+		wantErr bool
+	}{
+		{"No output", "output", OutputFormatText, true, false},
+		{"Stdout", stdoutOutput, OutputFormatText, false, false},
+		{"No output to stdout", stdoutOutput, OutputFormatText, true, true},
+		{"Stdout with JSON results", stdoutOutput, OutputFormatJSON, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, outputFormat, noOutput = tt.out, tt.format, tt.none
+			assert.Equal(t, tt.wantErr, ValidateFlags() != nil)
+		})
+	}
+}
+
 // TestCreateOutputDir tests the creation of an output directory given valid and invalid inputs
 func TestCreateOutputDir(t *testing.T) {
 	_ = redirectStdoutToBuffer(t)
 
+	outputDir := filepath.Join(t.TempDir(), "output")
+
 	testCases := []struct {
 		name          string
-		out           string
+		policy        OverwritePolicy
 		userInput     string
 		expectedError error
 	}{
 		{
 			name:          "Output directory does not exist",
-			out:           TestOutputDir,
+			policy:        OverwritePrompt,
 			userInput:     "",
 			expectedError: nil,
 		},
 		{
 			name:          "Output directory already exists, user enters 'yes'",
-			out:           TestOutputDir,
+			policy:        OverwritePrompt,
 			userInput:     "yes\n",
 			expectedError: nil,
 		},
 		{
 			name:          "Output directory already exists, user enters 'no'",
-			out:           TestOutputDir,
+			policy:        OverwritePrompt,
 			userInput:     "no\n",
 			expectedError: errors.New("aborted"),
 		},
+		{
+			name:          "Output directory already exists, overwriting allowed",
+			policy:        OverwriteAllow,
+			userInput:     "",
+			expectedError: nil,
+		},
+		{
+			name:          "Output directory already exists, overwriting denied",
+			policy:        OverwriteDeny,
+			userInput:     "yes\n",
+			expectedError: errors.New("output directory " + outputDir + " already exists"),
+		},
 	}
 	for _, tc := range testCases {
-		out = tc.out
-		// Use the helper function to simulate user input during testing
-		simulateUserInput(tc.userInput)
-		// Call the function being tested
-		err := CreateOutputDir()
-
-		// Clean up the created directory
-		defer os.RemoveAll(tc.out)
+		// Call the function being tested, answering any prompt with the test case's input
+		err := CreateOutputDir(outputDir, strings.NewReader(tc.userInput), tc.policy)
 
 		// Check the result against the expected error
 		if (err != nil && tc.expectedError == nil) || (err == nil && tc.expectedError != nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
 			t.Errorf("[%s] Test failed. Test failed onExpected error: %v, got: %v", tc.name, tc.expectedError, err)
 		}
 	}
+	assert.DirExists(t, outputDir)
 }
 
 // TestFesterStatus tests proper responses to connect to Server
@@ -346,8 +443,11 @@ func TestUploadCSV(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.fileName, func(t *testing.T) {
 			filePath := testDirUnFester + tc.fileName
-			response, responseBody, err := uploadCSV(filePath, tc.postURL, tc.iiifAPIVersion, tc.iiifHost,
-				tc.metadataUpdate, tc.headers)
+			response, responseBody, err := uploadCSV(context.Background(), filePath, tc.postURL,
+				UploadOptions{
+					IIIFVersion: tc.iiifAPIVersion, IIIFHost: tc.iiifHost,
+					MetadataUpdate: tc.metadataUpdate, Headers: tc.headers,
+				})
 			assert.Equal(t, err, nil)
 			assert.Equal(t, response.StatusCode, tc.expStatusCode)
 			if response.StatusCode == 201 {
@@ -378,6 +478,174 @@ func TestUploadCSV(t *testing.T) {
 	}
 }
 
+// TestUploadCSVDebugRedactsAuthorization tests that debug logging of a request never includes credentials
+func TestUploadCSVDebugRedactsAuthorization(t *testing.T) {
+	logger, sink := createLogger()
+	defer logger.Sync()
+
+	Logger = logger
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "Item ARK,IIIF Manifest URL\n")
+	}))
+	defer ts.Close()
+
+	headers := map[string]string{
+		"User-Agent":    "Festerize/test",
+		"Authorization": "Basic dXNlcjpzM2NyZXQ=",
+	}
+	response, _, err := uploadCSV(context.Background(), TestDirUnFester+"/ballin.csv", ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", Headers: headers})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+
+	output := sink.String()
+	assert.Contains(t, output, "Sending request to Fester")
+	assert.Contains(t, output, "Received response from Fester")
+	assert.Contains(t, output, "iiif-version")
+	assert.Contains(t, output, redactedValue)
+	assert.NotContains(t, output, "dXNlcjpzM2NyZXQ=")
+}
+
+// TestUploadCSVGzipped tests that a gzipped CSV is decompressed before it's sent to Fester
+func TestUploadCSVGzipped(t *testing.T) {
+	var received []byte
+	var receivedName string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		received, _ = io.ReadAll(file)
+		receivedName = header.Filename
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	response, _, err := uploadCSV(context.Background(), TestDirGzipped+"/chase.csv.gz", ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", Headers: map[string]string{}})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+
+	expected, err := os.ReadFile(TestDirUnFester + "/chase.csv")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), string(received))
+	assert.Equal(t, "chase.csv", filepath.Base(receivedName))
+}
+
+// TestUploadCSVCompressed tests that a gzipped upload decompresses to the original CSV
+func TestUploadCSVCompressed(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	var encoding string
+	var received []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		body, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.Body = body
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received, _ = io.ReadAll(file)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	response, _, err := uploadCSV(context.Background(), TestDirUnFester+"/chase.csv", ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", Headers: map[string]string{}, Compress: true})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Equal(t, "gzip", encoding)
+
+	expected, err := os.ReadFile(TestDirUnFester + "/chase.csv")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), string(received))
+}
+
+// TestUploadCSVCancelled tests that cancelling the context stops an upload that's waiting on Fester
+func TestUploadCSVCancelled(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(10 * time.Second):
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	started := time.Now()
+	_, _, err := uploadCSV(ctx, TestDirUnFester+"/ballin.csv", ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", Headers: map[string]string{}})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(started), 2*time.Second)
+}
+
+// failingBody is a response body whose reads fail, recording whether it was closed
+type failingBody struct {
+	closed *bool
+}
+
+func (body failingBody) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
+
+func (body failingBody) Close() error {
+	*body.closed = true
+	return nil
+}
+
+// failingBodyTransport answers every request with a body that can't be read
+type failingBodyTransport struct {
+	closed bool
+}
+
+func (transport *failingBodyTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusCreated,
+		Header:     http.Header{},
+		Body:       failingBody{&transport.closed},
+		Request:    request,
+	}, nil
+}
+
+// TestUploadCSVReadErrorClosesBody tests that the response body is closed when reading it fails
+func TestUploadCSVReadErrorClosesBody(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	transport := &failingBodyTransport{}
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = transport
+	defer func() { http.DefaultTransport = defaultTransport }()
+
+	_, _, err := uploadCSV(context.Background(), TestDirUnFester+"/ballin.csv", "http://fester.test/collections",
+		UploadOptions{IIIFVersion: "2", Headers: map[string]string{}})
+
+	assert.ErrorContains(t, err, "connection reset by peer")
+	assert.True(t, transport.closed, "response body was left open")
+}
+
 // TestMainValid tests an instance where all inputs are valid to the program and a file should be processed fully
 func TestMainValid(t *testing.T) {
 	redirectStdoutToBuffer(t)
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// defaultMergeName is the filename merged CSVs are uploaded and saved as
+const defaultMergeName = "merged.csv"
+
+// ValidateMergeName validates the filename merged CSVs are uploaded and saved as
+func ValidateMergeName(name string) error {
+	if name == "" || filepath.Base(name) != name || !IsCSVFile(name) {
+		return fmt.Errorf("invalid merge name %q: expected a filename ending in .csv", name)
+	}
+	return nil
+}
+
+// headerColumns returns a header's column names as columnIndex compares them
+func headerColumns(header []string) []string {
+	columns := make([]string, len(header))
+	for index, column := range header {
+		columns[index] = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+	}
+	return columns
+}
+
+// MergeRecords combines CSVs into one with a single header, the first CSV's; the other CSVs must have the same
+// columns, in any order, and their rows are rearranged to match it
+func MergeRecords(paths []string, files [][][]string) ([][]string, error) {
+	var merged [][]string
+	var header []string
+	for index, records := range files {
+		if len(records) == 0 {
+			return nil, fmt.Errorf("can't merge %s, which is empty", paths[index])
+		}
+
+		columns := headerColumns(records[0])
+		for position, column := range columns {
+			if slices.Contains(columns[:position], column) {
+				return nil, fmt.Errorf("can't merge %s, which has more than one %q column", paths[index], column)
+			}
+		}
+		if header == nil {
+			header = columns
+			merged = append(merged, header)
+		}
+
+		if missing, extra := columnDifference(header, columns); len(missing) > 0 || len(extra) > 0 {
+			return nil, fmt.Errorf("can't merge %s, whose columns don't match %s: missing %q, extra %q",
+				paths[index], paths[0], missing, extra)
+		}
+
+		for _, row := range records[1:] {
+			mergedRow := make([]string, len(header))
+			for position, column := range header {
+				if source := slices.Index(columns, column); source < len(row) {
+					mergedRow[position] = row[source]
+				}
+			}
+			merged = append(merged, mergedRow)
+		}
+	}
+	return merged, nil
+}
+
+// columnDifference returns the columns of the header that columns is missing, and the ones it has that the
+// header doesn't
+func columnDifference(header, columns []string) ([]string, []string) {
+	var missing, extra []string
+	for _, column := range header {
+		if !slices.Contains(columns, column) {
+			missing = append(missing, column)
+		}
+	}
+	for _, column := range columns {
+		if !slices.Contains(header, column) {
+			extra = append(extra, column)
+		}
+	}
+	return missing, extra
+}
+
+// MergeCSVs merges the CSVs, read as the input says, into a temporary file with the supplied name, so it can be
+// checked and uploaded like any other file; the returned func removes it
+func MergeCSVs(paths []string, name string, input CSVInput) (string, func(), error) {
+	files := make([][][]string, len(paths))
+	for index, path := range paths {
+		records, err := input.readRecords(path)
+		if err != nil {
+			return "", nil, fmt.Errorf("can't merge %s: %w", path, err)
+		}
+		files[index] = records
+	}
+
+	merged, err := MergeRecords(paths, files)
+	if err != nil {
+		return "", nil, err
+	}
+	content, err := writeCSVRecords(merged)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "festerize-merge-")
+	if err != nil {
+		return "", nil, err
+	}
+	remove := func() { os.RemoveAll(dir) }
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, content.Bytes(), 0644); err != nil {
+		remove()
+		return "", nil, err
+	}
+	return path, remove, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMergeRecordsIdenticalHeaders tests that CSVs with the same header are merged under a single header
+func TestMergeRecordsIdenticalHeaders(t *testing.T) {
+	merged, err := MergeRecords([]string{"collection.csv", "works.csv"}, [][][]string{
+		{{"\ufeffItem ARK", "Object Type"}, {"ark:/21198/zz0001", "Collection"}},
+		{{"Item ARK", "Object Type"}, {"ark:/21198/zz0002", "Work"}, {"ark:/21198/zz0003", "Work"}},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"Item ARK", "Object Type"},
+		{"ark:/21198/zz0001", "Collection"},
+		{"ark:/21198/zz0002", "Work"},
+		{"ark:/21198/zz0003", "Work"},
+	}, merged)
+}
+
+// TestMergeRecordsReorderedHeaders tests that rows are rearranged to the first CSV's column order
+func TestMergeRecordsReorderedHeaders(t *testing.T) {
+	merged, err := MergeRecords([]string{"collection.csv", "works.csv"}, [][][]string{
+		{{"Item ARK", "Parent ARK", "Object Type"}, {"ark:/21198/zz0001", "", "Collection"}},
+		{{"Object Type", "Item ARK", "Parent ARK"}, {"Work", "ark:/21198/zz0002", "ark:/21198/zz0001"}, {"Work"}},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"Item ARK", "Parent ARK", "Object Type"},
+		{"ark:/21198/zz0001", "", "Collection"},
+		{"ark:/21198/zz0002", "ark:/21198/zz0001", "Work"},
+		{"", "", "Work"},
+	}, merged)
+}
+
+// TestMergeRecordsMismatchedHeaders tests that CSVs with different or repeated columns aren't merged
+func TestMergeRecordsMismatchedHeaders(t *testing.T) {
+	paths := []string{"collection.csv", "works.csv"}
+
+	_, err := MergeRecords(paths, [][][]string{
+		{{"Item ARK", "Object Type", "Title"}},
+		{{"Item ARK", "Object Type", "File Name"}},
+	})
+	assert.ErrorContains(t, err, `can't merge works.csv, whose columns don't match collection.csv: missing ["Title"], extra ["File Name"]`)
+
+	_, err = MergeRecords(paths, [][][]string{
+		{{"Item ARK", "Object Type"}},
+		{{"Item ARK", "Object Type", "Item ARK"}},
+	})
+	assert.ErrorContains(t, err, `more than one "Item ARK" column`)
+
+	_, err = MergeRecords(paths, [][][]string{{{"Item ARK"}}, {}})
+	assert.ErrorContains(t, err, "works.csv, which is empty")
+}
+
+// TestRunMerge tests that merged CSVs are uploaded in one request, with one output CSV written
+func TestRunMerge(t *testing.T) {
+	var uploads []string
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		if file, header, err := r.FormFile("file"); err == nil {
+			content, _ := io.ReadAll(file)
+			uploads = append(uploads, header.Filename+"\n"+string(content))
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("Item ARK\n"))
+	})
+
+	cfg := testConfig(t, h.server.URL, writeTestCSV(t, "collection.csv", testCollectionCSV),
+		writeTestCSV(t, "works.csv", testWorksCSV))
+	cfg.Merge, cfg.MergeName = true, "papers.csv"
+	exitCode, results, err := run(context.Background(), cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, []string{"papers.csv\nItem ARK,Parent ARK,Object Type\n" +
+		"ark:/21198/zz0001,,Collection\nark:/21198/zz0002,ark:/21198/zz0001,Work\n" +
+		"ark:/21198/zz0003,ark:/21198/zz0002,Page\nark:/21198/zz0004,ark:/21198/zz0009,page\n"}, uploads)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "papers.csv", results[0].Filename)
+		assert.Empty(t, results[0].Path)
+		assert.Equal(t, cfg.Sources, results[0].Inputs)
+	}
+	assert.FileExists(t, filepath.Join(cfg.OutputDir, "papers.csv"))
+}
+
+// TestRunMergeDelimited tests that the merged CSV of tab-delimited inputs, which is written with commas, is checked
+// and uploaded as a comma-delimited file
+func TestRunMergeDelimited(t *testing.T) {
+	var uploads []string
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		if file, _, err := r.FormFile("file"); err == nil {
+			content, _ := io.ReadAll(file)
+			uploads = append(uploads, string(content))
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	cfg := testConfig(t, h.server.URL,
+		writeTestCSV(t, "collection.csv", "Item ARK\tObject Type\tTitle\nark:/21198/zz0001\tCollection\tPapers\n"),
+		writeTestCSV(t, "works.csv", "Item ARK\tObject Type\tTitle\nark:/21198/zz0002\tWork\tLetter, 1920\n"))
+	cfg.Merge, cfg.MergeName, cfg.Input.Delimiter = true, defaultMergeName, '\t'
+	cfg.ValidateCSV, cfg.StrictMode = true, true
+	exitCode, _, err := run(context.Background(), cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, []string{"Item ARK,Object Type,Title\nark:/21198/zz0001,Collection,Papers\n" +
+		"ark:/21198/zz0002,Work,\"Letter, 1920\"\n"}, uploads)
+}
+
+// TestRunMergeMismatched tests that CSVs whose columns don't match stop the run before anything is uploaded
+func TestRunMergeMismatched(t *testing.T) {
+	h := newRunHarness(t, respondWith(http.StatusCreated, ""))
+	cfg := testConfig(t, h.server.URL, writeTestCSV(t, "collection.csv", testCollectionCSV),
+		writeTestCSV(t, "titled.csv", "Item ARK,Title\nark:/21198/zz0002,Letter\n"))
+	cfg.Merge, cfg.MergeName = true, defaultMergeName
+	exitCode, _, err := run(context.Background(), cfg)
+
+	assert.ErrorContains(t, err, "whose columns don't match")
+	assert.Equal(t, int(INVALID_CSV_SPECIFIED), exitCode)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FormatMetrics formats the outcome of a run as Prometheus text-format metrics
+func FormatMetrics(results []FileResult, bytes int64, duration time.Duration) string {
+	var succeeded, failed, skipped int
+	statusCodes := map[int]int{}
+	for _, result := range results {
+		switch result.Status {
+		case StatusSuccess:
+			succeeded++
+		case StatusSkipped:
+			skipped++
+		default:
+			failed++
+		}
+		if result.StatusCode != 0 {
+			statusCodes[result.StatusCode]++
+		}
+	}
+
+	var metrics strings.Builder
+	writeMetric := func(name, metricType, help string, value any) {
+		fmt.Fprintf(&metrics, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, metricType, name, value)
+	}
+
+	writeMetric("festerize_files_processed_total", "counter", "Files processed.", len(results))
+	writeMetric("festerize_files_succeeded_total", "counter", "Files uploaded and saved successfully.", succeeded)
+	writeMetric("festerize_files_failed_total", "counter", "Files that failed.", failed)
+	writeMetric("festerize_files_skipped_total", "counter", "Files skipped after being uploaded before their parents, or without rows under the ARK prefix.", skipped)
+	writeMetric("festerize_uploaded_bytes_total", "counter", "Bytes of request bodies sent to Fester.", bytes)
+	writeMetric("festerize_run_duration_seconds", "gauge", "Duration of the run in seconds.", duration.Seconds())
+
+	codes := make([]int, 0, len(statusCodes))
+	for code := range statusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	fmt.Fprintf(&metrics, "# HELP festerize_responses_total Files by the HTTP status code of Fester's response.\n")
+	fmt.Fprintf(&metrics, "# TYPE festerize_responses_total counter\n")
+	for _, code := range codes {
+		fmt.Fprintf(&metrics, "festerize_responses_total{status_code=\"%d\"} %d\n", code, statusCodes[code])
+	}
+	return metrics.String()
+}
+
+// writeMetrics writes metrics for the run to the metrics file, if one was requested
+func writeMetrics(metricsFile string, results []FileResult, uploadedBytes int64, duration time.Duration) {
+	if metricsFile == "" {
+		return
+	}
+
+	metrics := FormatMetrics(results, uploadedBytes, duration)
+	if err := os.WriteFile(metricsFile, []byte(metrics), 0644); err != nil {
+		Logger.Error("Error writing metrics",
+			zap.String("metrics file", metricsFile),
+			zap.Error(err))
+		fmt.Fprintln(humanOutput(), "There was an error writing the metrics")
+	}
+}
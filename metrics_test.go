@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFormatMetrics tests that the metrics contain the expected counter names and values
+func TestFormatMetrics(t *testing.T) {
+	metrics := FormatMetrics([]FileResult{
+		{Status: StatusSuccess, StatusCode: 201},
+		{Status: StatusSuccess, StatusCode: 201},
+		{Status: StatusFailure, StatusCode: 500},
+		{Status: StatusFailure},
+		{Status: StatusSkipped, StatusCode: 400},
+	}, 2048, 1500*time.Millisecond)
+
+	for _, line := range []string{
+		"# TYPE festerize_files_processed_total counter",
+		"festerize_files_processed_total 5",
+		"festerize_files_succeeded_total 2",
+		"festerize_files_failed_total 2",
+		"festerize_files_skipped_total 1",
+		"festerize_uploaded_bytes_total 2048",
+		"# TYPE festerize_run_duration_seconds gauge",
+		"festerize_run_duration_seconds 1.5",
+		`festerize_responses_total{status_code="201"} 2`,
+		`festerize_responses_total{status_code="400"} 1`,
+		`festerize_responses_total{status_code="500"} 1`,
+	} {
+		assert.Contains(t, metrics, line+"\n")
+	}
+}
+
+// TestWriteMetrics tests that the metrics file counts the bytes uploaded during the run
+func TestWriteMetrics(t *testing.T) {
+	var uploaded atomic.Int64
+	ts := newStubFester(t, http.StatusCreated, "", nil)
+	response, _, err := uploadCSV(context.Background(), TestDirUnFester+"/chase.csv", ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", Headers: map[string]string{}, UploadedBytes: &uploaded})
+	assert.Nil(t, err)
+	assert.Greater(t, uploaded.Load(), int64(0))
+	assert.Equal(t, response.Request.ContentLength, uploaded.Load())
+
+	results := []FileResult{{Status: StatusSuccess, StatusCode: response.StatusCode}}
+	metricsFile := filepath.Join(t.TempDir(), "metrics.prom")
+	writeMetrics(metricsFile, results, uploaded.Load(), time.Second)
+
+	metrics, err := os.ReadFile(metricsFile)
+	assert.Nil(t, err)
+	assert.Contains(t, string(metrics), "festerize_files_succeeded_total 1\n")
+	assert.Contains(t, string(metrics), "festerize_uploaded_bytes_total "+strconv.FormatInt(uploaded.Load(), 10)+"\n")
+	assert.Contains(t, string(metrics), `festerize_responses_total{status_code="201"} 1`)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Policies for deciding whether a file mirrored to several servers was uploaded
+const (
+	MirrorPolicyAll string = "all"
+	MirrorPolicyAny string = "any"
+)
+
+// ServerResult is the outcome of uploading a file to a single server
+type ServerResult struct {
+	Server   string
+	Response *http.Response
+	Body     []byte
+	Err      error
+	Duration time.Duration
+}
+
+// Succeeded checks whether the server accepted the upload
+func (r ServerResult) Succeeded() bool {
+	return r.Err == nil && r.Response != nil && IsSuccessStatus(r.Response.StatusCode)
+}
+
+// IsSuccessStatus checks whether Fester accepted an upload; it answers with 201 Created, but any 2xx
+// status means the returned CSV can be used
+func IsSuccessStatus(statusCode int) bool {
+	return statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices
+}
+
+// ValidateMirrorPolicy validates the mirror policy
+func ValidateMirrorPolicy() error {
+	switch mirrorPolicy {
+	case MirrorPolicyAll, MirrorPolicyAny:
+		return nil
+	default:
+		return errors.New("invalid mirror policy. Allowed values are all or any")
+	}
+}
+
+// statusURL returns the URL of a server's status endpoint
+func statusURL(server string) string {
+	return endpointURL(server, "/fester/status")
+}
+
+// collectionsURL returns the URL of a server's CSV upload endpoint
+func collectionsURL(server string) string {
+	return endpointURL(server, "/collections")
+}
+
+// uploadToServers uploads a CSV to each of the servers in turn; the primary server comes first
+func uploadToServers(ctx context.Context, filePath string, servers []string, opts UploadOptions) []ServerResult {
+	results := make([]ServerResult, 0, len(servers))
+
+	for _, server := range servers {
+		start := time.Now()
+		response, body, err := uploadFile(ctx, filePath, collectionsURL(server), opts)
+		result := ServerResult{Server: server, Response: response, Body: body, Err: err, Duration: time.Since(start)}
+
+		Logger.Info("Upload finished",
+			zap.String("filename", filepath.Base(filePath)),
+			zap.String("server", server),
+			zap.Duration("duration", result.Duration))
+
+		if result.Succeeded() {
+			Logger.Info("Server accepted file",
+				zap.String("server", server),
+				zap.Int("status_code", response.StatusCode))
+		} else if err != nil {
+			Logger.Error("Error uploading file to server",
+				zap.String("server", server),
+				zap.Error(err))
+		} else {
+			Logger.Error("Server rejected file",
+				zap.String("server", server),
+				zap.Int("status_code", response.StatusCode))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// uploadDuration returns the total time spent uploading a file to the servers
+func uploadDuration(results []ServerResult) time.Duration {
+	var total time.Duration
+	for _, result := range results {
+		total += result.Duration
+	}
+	return total
+}
+
+// MirrorSucceeded checks whether a file counts as uploaded under the supplied mirror policy
+func MirrorSucceeded(results []ServerResult, policy string) bool {
+	if len(results) == 0 {
+		return false
+	}
+	for _, result := range results {
+		if policy == MirrorPolicyAny && result.Succeeded() {
+			return true
+		} else if policy != MirrorPolicyAny && !result.Succeeded() {
+			return false
+		}
+	}
+	return policy != MirrorPolicyAny
+}
+
+// successfulResult returns the result the output CSV is taken from: the primary server's, or if
+// that failed, the first server that accepted the file
+func successfulResult(results []ServerResult) ServerResult {
+	for _, result := range results {
+		if result.Succeeded() {
+			return result
+		}
+	}
+	return ServerResult{}
+}
+
+// failedResult returns the result of the first server that didn't accept the file
+func failedResult(results []ServerResult) ServerResult {
+	for _, result := range results {
+		if !result.Succeeded() {
+			return result
+		}
+	}
+	return ServerResult{}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newStubFester creates a stub Fester server that answers uploads with the supplied status code
+func newStubFester(t *testing.T, statusCode int, body string, uploads *int32) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if uploads != nil {
+			atomic.AddInt32(uploads, 1)
+		}
+		w.WriteHeader(statusCode)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// TestUploadToServers tests that a file is uploaded to every server and the policy decides the outcome
+func TestUploadToServers(t *testing.T) {
+	var primaryUploads, secondaryUploads int32
+	primary := newStubFester(t, http.StatusCreated, "primary", &primaryUploads)
+	secondary := newStubFester(t, http.StatusInternalServerError, "secondary", &secondaryUploads)
+
+	results := uploadToServers(context.Background(), TestDirUnFester+"/chase.csv", []string{primary.URL,
+		secondary.URL}, UploadOptions{IIIFVersion: "2", Headers: map[string]string{}})
+	assert.Len(t, results, 2)
+	assert.Equal(t, int32(1), primaryUploads)
+	assert.Equal(t, int32(1), secondaryUploads)
+
+	assert.False(t, MirrorSucceeded(results, MirrorPolicyAll))
+	assert.True(t, MirrorSucceeded(results, MirrorPolicyAny))
+	assert.Equal(t, "primary", string(successfulResult(results).Body))
+	assert.Equal(t, "secondary", string(failedResult(results).Body))
+}
+
+// TestMirrorSucceeded tests the mirror policies against combinations of server outcomes
+func TestMirrorSucceeded(t *testing.T) {
+	created := ServerResult{Response: &http.Response{StatusCode: http.StatusCreated}}
+	failed := ServerResult{Response: &http.Response{StatusCode: http.StatusBadRequest}}
+
+	tests := []struct {
+		name     string
+		results  []ServerResult
+		policy   string
+		expected bool
+	}{
+		{"All succeeded, all policy", []ServerResult{created, created}, MirrorPolicyAll, true},
+		{"One failed, all policy", []ServerResult{created, failed}, MirrorPolicyAll, false},
+		{"One failed, any policy", []ServerResult{failed, created}, MirrorPolicyAny, true},
+		{"All failed, any policy", []ServerResult{failed, failed}, MirrorPolicyAny, false},
+		{"No servers", nil, MirrorPolicyAll, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, MirrorSucceeded(tc.results, tc.policy))
+		})
+	}
+}
+
+// TestSuccessfulResultPrefersPrimary tests that the output CSV comes from the primary server when it succeeds
+func TestSuccessfulResultPrefersPrimary(t *testing.T) {
+	primary := newStubFester(t, http.StatusCreated, "primary", nil)
+	secondary := newStubFester(t, http.StatusCreated, "secondary", nil)
+
+	results := uploadToServers(context.Background(), TestDirUnFester+"/chase.csv", []string{primary.URL,
+		secondary.URL}, UploadOptions{IIIFVersion: "2", Headers: map[string]string{}})
+	assert.True(t, MirrorSucceeded(results, MirrorPolicyAll))
+	assert.Equal(t, primary.URL, successfulResult(results).Server)
+}
+
+// TestUploadToServersLogsDuration tests that a non-zero duration is logged and recorded for each upload
+func TestUploadToServersLogsDuration(t *testing.T) {
+	logger, sink := createLogger()
+	defer logger.Sync()
+
+	Logger = logger
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	results := uploadToServers(context.Background(), TestDirUnFester+"/chase.csv", []string{ts.URL},
+		UploadOptions{IIIFVersion: "2", Headers: map[string]string{}})
+	assert.GreaterOrEqual(t, results[0].Duration, 10*time.Millisecond)
+	assert.Equal(t, results[0].Duration, uploadDuration(results))
+
+	var logged bool
+	for _, line := range strings.Split(strings.TrimSpace(sink.String()), "\n") {
+		var entry map[string]any
+		assert.Nil(t, json.Unmarshal([]byte(line), &entry))
+		if entry["M"] == "Upload finished" {
+			duration, err := time.ParseDuration(entry["duration"].(string))
+			assert.Nil(t, err)
+			assert.Greater(t, duration, time.Duration(0))
+			logged = true
+		}
+	}
+	assert.True(t, logged, "upload duration should have been logged")
+}
+
+// TestIsSuccessStatus tests that every 2xx status counts as success
+func TestIsSuccessStatus(t *testing.T) {
+	for _, statusCode := range []int{http.StatusOK, http.StatusCreated, http.StatusAccepted} {
+		assert.True(t, IsSuccessStatus(statusCode), statusCode)
+	}
+	for _, statusCode := range []int{http.StatusMovedPermanently, http.StatusBadRequest, http.StatusInternalServerError} {
+		assert.False(t, IsSuccessStatus(statusCode), statusCode)
+	}
+}
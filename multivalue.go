@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// defaultMultiValueSeparator separates the values of a multi-value cell
+const defaultMultiValueSeparator string = "|"
+
+// ValidateMultiValueSeparator checks that --multivalue-sep can split a cell
+func ValidateMultiValueSeparator() error {
+	if multiValueSeparator == "" {
+		return errors.New("invalid multi-value separator: it can't be empty")
+	}
+	return nil
+}
+
+// ValidateMultiValue checks that the multi-value cells of the given columns split cleanly: with no separator
+// at either end, and no empty values between separators; an empty cell has no values, and is fine
+func ValidateMultiValue(records [][]string, columns []string, separator string) []ValidationProblem {
+	if len(records) == 0 || len(columns) == 0 {
+		return nil
+	}
+
+	var problems []ValidationProblem
+	for _, column := range columns {
+		index := columnIndex(records[0], column)
+		if index == -1 {
+			Logger.Warn("Multi-value column not found in CSV header",
+				zap.String("column", column))
+			continue
+		}
+
+		for rowIndex, row := range records[1:] {
+			value := cell(row, index)
+			if reason := multiValueProblem(value, separator); reason != "" {
+				problems = append(problems, ValidationProblem{rowIndex + 2, column, value, reason})
+			}
+		}
+	}
+	return problems
+}
+
+// multiValueProblem describes what's wrong with a multi-value cell, or returns an empty string if it's fine
+func multiValueProblem(value, separator string) string {
+	if value == "" {
+		return ""
+	}
+
+	values := strings.Split(value, separator)
+	switch {
+	case strings.TrimSpace(values[0]) == "":
+		return fmt.Sprintf("starts with separator %q", separator)
+	case strings.TrimSpace(values[len(values)-1]) == "":
+		return fmt.Sprintf("ends with separator %q", separator)
+	}
+	for position, subValue := range values {
+		if strings.TrimSpace(subValue) == "" {
+			return fmt.Sprintf("empty value %d of %d", position+1, len(values))
+		}
+	}
+	return ""
+}
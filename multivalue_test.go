@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMultiValueProblem tests well-formed and malformed multi-value cells
+func TestMultiValueProblem(t *testing.T) {
+	tests := map[string]string{
+		"":                     "",
+		"Letters":              "",
+		"Letters|Photographs":  "",
+		"Letters | Maps | Art": "",
+		"|Letters":             `starts with separator "|"`,
+		"Letters|":             `ends with separator "|"`,
+		"Letters| ":            `ends with separator "|"`,
+		"Letters||Maps":        "empty value 2 of 3",
+		"Letters| |Maps":       "empty value 2 of 3",
+		"|":                    `starts with separator "|"`,
+	}
+
+	for value, reason := range tests {
+		t.Run(value, func(t *testing.T) {
+			assert.Equal(t, reason, multiValueProblem(value, "|"))
+		})
+	}
+}
+
+// TestValidateMultiValue tests that only the listed columns are checked, with each malformed cell reported
+// against its row
+func TestValidateMultiValue(t *testing.T) {
+	logger, sink := createLogger()
+	Logger = logger
+
+	records := [][]string{
+		{"Item ARK", "Subject", "Name.creator", "Description.note"},
+		{"ark:/21198/zz0001", "Letters|Maps", "Ballin, Hugo", "a||b"},
+		{"ark:/21198/zz0002", "Letters||Maps", "Ballin, Hugo;", ""},
+		{"ark:/21198/zz0003", "", "Ballin, Hugo;;Ballin, Mabel", "|"},
+	}
+
+	problems := ValidateMultiValue(records, []string{"Subject", "Name.creator", "Genre"}, "|")
+	assert.Equal(t, []ValidationProblem{{3, "Subject", "Letters||Maps", "empty value 2 of 3"}}, problems)
+	assert.Contains(t, sink.String(), `"column":"Genre"`)
+
+	problems = ValidateMultiValue(records, []string{"Name.creator"}, ";")
+	assert.Equal(t, []ValidationProblem{
+		{3, "Name.creator", "Ballin, Hugo;", `ends with separator ";"`},
+		{4, "Name.creator", "Ballin, Hugo;;Ballin, Mabel", "empty value 2 of 3"},
+	}, problems)
+}
+
+// TestValidateFileMultiValue tests that multi-value cells are only checked when columns are listed
+func TestValidateFileMultiValue(t *testing.T) {
+	path := writeTestCSV(t, "subjects.csv", "Item ARK,Subject\nark:/21198/zz0001,Letters|\n")
+
+	problems, err := ValidateFile(path, Config{})
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+
+	cfg := Config{MultiValueColumns: []string{"Subject"}, MultiValueSeparator: defaultMultiValueSeparator}
+	problems, err = ValidateFile(path, cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, []ValidationProblem{{2, "Subject", "Letters|", `ends with separator "|"`}}, problems)
+}
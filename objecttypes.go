@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// FindUnknownObjectTypes finds the rows whose object type Fester won't recognize: ones that aren't a
+// collection, work, or page whatever their case and surrounding whitespace, and, unless they'll be fixed
+// before upload, ones that are but aren't spelled the way Fester expects
+func FindUnknownObjectTypes(records [][]string, fixing bool) []ValidationProblem {
+	if len(records) == 0 {
+		return nil
+	}
+	typeIndex := columnIndex(records[0], objectTypeColumn)
+	if typeIndex == -1 {
+		return nil
+	}
+
+	var problems []ValidationProblem
+	for rowIndex, row := range records[1:] {
+		value := ""
+		if typeIndex < len(row) {
+			value = row[typeIndex]
+		}
+
+		normalized := normalizeObjectType(value)
+		switch _, known := objectTypeRanks[normalized]; {
+		case !known:
+			problems = append(problems, ValidationProblem{rowIndex + 2, objectTypeColumn, value,
+				"not Collection, Work, or Page"})
+		case value != normalized && !fixing:
+			problems = append(problems, ValidationProblem{rowIndex + 2, objectTypeColumn, value,
+				fmt.Sprintf("should be written %q; --fix-object-types corrects it", normalized)})
+		}
+	}
+	return problems
+}
+
+// warnAboutUnknownObjectTypes warns about the rows whose object type Fester won't recognize; they're not
+// errors, since Fester decides what to do with them
+func warnAboutUnknownObjectTypes(path string, records [][]string, fixing bool) {
+	problems := FindUnknownObjectTypes(records, fixing)
+	if len(problems) == 0 {
+		return
+	}
+
+	rows := make([]int, len(problems))
+	for index, problem := range problems {
+		Logger.Warn("Unrecognized object type",
+			zap.String("path", path),
+			zap.Int("row", problem.Row),
+			zap.String("value", problem.Value),
+			zap.String("reason", problem.Reason))
+		rows[index] = problem.Row
+	}
+	fmt.Fprintf(humanOutput(), "Warning: %d rows in %s have an object type Fester may not recognize, on rows %v "+
+		"(see log)\n", len(problems), filepath.Base(path), rows)
+}
+
+// FixObjectTypes rewrites object types that differ from Collection, Work, or Page only in their case or
+// surrounding whitespace; other values are left for Fester to judge
+func FixObjectTypes(records [][]string) ([][]string, error) {
+	if len(records) == 0 {
+		return records, nil
+	}
+	typeIndex := columnIndex(records[0], objectTypeColumn)
+	if typeIndex == -1 {
+		return records, nil
+	}
+
+	fixed := 0
+	for _, row := range records[1:] {
+		if typeIndex >= len(row) {
+			continue
+		}
+		normalized := normalizeObjectType(row[typeIndex])
+		if _, known := objectTypeRanks[normalized]; known && row[typeIndex] != normalized {
+			row[typeIndex] = normalized
+			fixed++
+		}
+	}
+	if fixed > 0 {
+		Logger.Info("Corrected object types", zap.Int("rows", fixed))
+	}
+	return records, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testObjectTypesCSV has a typo'd object type and one with the wrong case and whitespace
+const testObjectTypesCSV = `Item ARK,Parent ARK,Object Type,Title
+ark:/21198/zz0001,,collection ,Papers
+ark:/21198/zz0002,ark:/21198/zz0001,Works,Letter
+ark:/21198/zz0003,ark:/21198/zz0002,Page,Page 1
+ark:/21198/zz0004,ark:/21198/zz0001,,Map
+`
+
+// TestFindUnknownObjectTypes tests that unrecognized object types are reported with their row numbers, and
+// misspelled ones only when they won't be fixed
+func TestFindUnknownObjectTypes(t *testing.T) {
+	records := readCSVString(t, testObjectTypesCSV)
+
+	assert.Equal(t, []ValidationProblem{
+		{2, objectTypeColumn, "collection ", `should be written "Collection"; --fix-object-types corrects it`},
+		{3, objectTypeColumn, "Works", "not Collection, Work, or Page"},
+		{5, objectTypeColumn, "", "not Collection, Work, or Page"},
+	}, FindUnknownObjectTypes(records, false))
+
+	assert.Equal(t, []ValidationProblem{
+		{3, objectTypeColumn, "Works", "not Collection, Work, or Page"},
+		{5, objectTypeColumn, "", "not Collection, Work, or Page"},
+	}, FindUnknownObjectTypes(records, true))
+
+	assert.Empty(t, FindUnknownObjectTypes(readCSVString(t, "Item ARK,Title\nark:/1,Letter\n"), false))
+}
+
+// TestFixObjectTypes tests that only the case and whitespace of object types are corrected
+func TestFixObjectTypes(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+	transformed := transformString(t, testObjectTypesCSV, Config{FixObjectTypes: true}.transforms()...)
+	assert.Equal(t, [][]string{
+		{"Item ARK", "Parent ARK", "Object Type", "Title"},
+		{"ark:/21198/zz0001", "", "Collection", "Papers"},
+		{"ark:/21198/zz0002", "ark:/21198/zz0001", "Works", "Letter"},
+		{"ark:/21198/zz0003", "ark:/21198/zz0002", "Page", "Page 1"},
+		{"ark:/21198/zz0004", "ark:/21198/zz0001", "", "Map"},
+	}, readCSVString(t, transformed))
+}
+
+// TestValidateFileUnknownObjectTypes tests that unrecognized object types are warned about without failing the
+// file
+func TestValidateFileUnknownObjectTypes(t *testing.T) {
+	logger, sink := createLogger()
+	Logger = logger
+	path := writeTestCSV(t, "types.csv", testObjectTypesCSV)
+
+	var problems []ValidationProblem
+	var err error
+	output := captureStdout(t, func() { problems, err = ValidateFile(path, Config{WarnUnknownObjectTypes: true}) })
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+	assert.Contains(t, output, "Warning: 3 rows in types.csv have an object type Fester may not recognize, on rows [2 3 5]")
+	assert.Contains(t, sink.String(), "Unrecognized object type")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Formats for reporting the outcome of each file on stdout
+const (
+	OutputFormatText string = "text"
+	OutputFormatJSON string = "json"
+)
+
+// ValidateOutputFormat validates the output format
+func ValidateOutputFormat() error {
+	switch outputFormat {
+	case OutputFormatText, OutputFormatJSON:
+		return nil
+	default:
+		return errors.New("invalid output format. Allowed values are text or json")
+	}
+}
+
+// When the messages for people are colored
+const (
+	ColorAuto   string = "auto"
+	ColorAlways string = "always"
+	ColorNever  string = "never"
+)
+
+// ANSI escape codes for the colors of the messages for people
+const (
+	ansiGreen string = "\033[32m"
+	ansiRed   string = "\033[31m"
+	ansiReset string = "\033[0m"
+)
+
+// ValidateColor validates the color mode
+func ValidateColor() error {
+	switch colorMode {
+	case ColorAuto, ColorAlways, ColorNever:
+		return nil
+	default:
+		return errors.New("invalid color mode. Allowed values are auto, always, or never")
+	}
+}
+
+// colorEnabled checks whether messages for people are colored; in auto mode they are when they're printed to a
+// terminal and NO_COLOR isn't set
+func colorEnabled() bool {
+	switch colorMode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	default:
+		file, ok := humanOutput().(*os.File)
+		return ok && os.Getenv("NO_COLOR") == "" && isTerminal(file)
+	}
+}
+
+// colorize wraps text in an ANSI color when color is enabled, leaving any trailing newlines outside the color
+func colorize(color, text string) string {
+	if !colorEnabled() {
+		return text
+	}
+	body := strings.TrimRight(text, "\n")
+	return color + body + ansiReset + text[len(body):]
+}
+
+// printSuccess prints a message for people about something that worked
+func printSuccess(text string) {
+	fmt.Fprint(humanOutput(), colorize(ansiGreen, text))
+}
+
+// printFailure prints a message for people about something that went wrong
+func printFailure(format string, args ...any) {
+	fmt.Fprint(humanOutput(), colorize(ansiRed, fmt.Sprintf(format, args...)))
+}
+
+// stdoutOutput is the output directory that stands for writing the festerized CSV to stdout
+const stdoutOutput = "-"
+
+// humanOutput returns where messages for people are printed; with JSON output, or the festerized CSV
+// written to stdout, they go to stderr so stdout only carries results
+func humanOutput() io.Writer {
+	if outputFormat == OutputFormatJSON || out == stdoutOutput {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// emitResult writes the result to stdout as a JSON line when the JSON output format is selected
+func emitResult(result FileResult) {
+	if outputFormat != OutputFormatJSON {
+		return
+	}
+
+	line, err := json.Marshal(result)
+	if err != nil {
+		Logger.Error("Error encoding result", zap.String("filename", result.Filename), zap.Error(err))
+		return
+	}
+	fmt.Fprintln(os.Stdout, string(line))
+}
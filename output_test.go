@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureStdout runs the supplied function and returns what it printed to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	oldStdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+
+	fn()
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buffer bytes.Buffer
+	io.Copy(&buffer, r)
+	r.Close()
+	return buffer.String()
+}
+
+// TestValidateOutputFormat tests output formats are properly validated
+func TestValidateOutputFormat(t *testing.T) {
+	defer func() { outputFormat = OutputFormatText }()
+
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{"text", false},
+		{"json", false},
+		{"xml", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			outputFormat = tt.format
+			err := ValidateOutputFormat()
+
+			if tt.wantErr && err == nil {
+				t.Error("Expected an error, but got none.")
+			} else if !tt.wantErr && err != nil {
+				t.Error("Unexpected error:", err)
+			}
+		})
+	}
+}
+
+// TestEmitResult tests that results are written as JSON lines only with the JSON output format
+func TestEmitResult(t *testing.T) {
+	defer func() { outputFormat = OutputFormatText }()
+
+	result := FileResult{
+		Filename:     "chase.csv",
+		Path:         "/tmp/chase.csv",
+		Status:       StatusSuccess,
+		StatusCode:   201,
+		ManifestURLs: []string{"https://test.iiif.library.ucla.edu/ark%3A%2F21198%2Fzz00093zr9/manifest"},
+	}
+
+	outputFormat = OutputFormatText
+	assert.Equal(t, "", captureStdout(t, func() { emitResult(result) }))
+
+	outputFormat = OutputFormatJSON
+	output := captureStdout(t, func() {
+		emitResult(result)
+		emitResult(FileResult{Filename: "random.csv", Status: StatusFailure, Error: "file does not exist"})
+	})
+
+	lines := bytes.Split(bytes.TrimSpace([]byte(output)), []byte("\n"))
+	assert.Len(t, lines, 2)
+
+	var decoded FileResult
+	assert.Nil(t, json.Unmarshal(lines[0], &decoded))
+	assert.Equal(t, result, decoded)
+	assert.Nil(t, json.Unmarshal(lines[1], &decoded))
+	assert.Equal(t, "file does not exist", decoded.Error)
+}
+
+// TestPrintFailureColor tests that failures are red only when color is enabled, and plain otherwise
+func TestPrintFailureColor(t *testing.T) {
+	defer func() { colorMode = ColorAuto }()
+
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{ColorNever, "ballin.csv does not exist\n"},
+		{ColorAlways, ansiRed + "ballin.csv does not exist" + ansiReset + "\n"},
+		{ColorAuto, "ballin.csv does not exist\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.mode, func(t *testing.T) {
+			colorMode = tc.mode
+			output := captureStdout(t, func() { printFailure("%s does not exist\n", "ballin.csv") })
+			assert.Equal(t, tc.expected, output)
+		})
+	}
+}
+
+// TestPrintSuccessColor tests that the success banner is green when color is always on
+func TestPrintSuccessColor(t *testing.T) {
+	defer func() { colorMode = ColorAuto }()
+	colorMode = ColorAlways
+
+	banner := successBanner("✨", "SUCCESS! Uploaded ballin.csv")
+	output := captureStdout(t, func() { printSuccess(banner) })
+
+	assert.Equal(t, ansiGreen+strings.TrimSuffix(banner, "\n")+ansiReset+"\n", output)
+}
+
+// TestValidateColor tests color modes are properly validated
+func TestValidateColor(t *testing.T) {
+	defer func() { colorMode = ColorAuto }()
+
+	for _, mode := range []string{ColorAuto, ColorAlways, ColorNever} {
+		colorMode = mode
+		assert.Nil(t, ValidateColor(), mode)
+	}
+	colorMode = "rainbow"
+	assert.NotNil(t, ValidateColor())
+}
+
+// TestRunStdout tests that the festerized CSV is written to stdout, without the banner, and nothing is saved
+func TestRunStdout(t *testing.T) {
+	defer func() { out = "output" }()
+	logger, _ := createLogger()
+	Logger = logger
+	out = stdoutOutput
+
+	ts := newRunStub(t, http.StatusOK, http.StatusCreated, "Item ARK,IIIF Manifest URL\n")
+	cfg := testConfig(t, ts.URL, TestDirUnFester+"/ballin.csv")
+	cfg.OutputDir = stdoutOutput
+
+	var exitCode int
+	var err error
+	var stdout string
+	stderr := captureStderr(t, func() {
+		stdout = captureStdout(t, func() {
+			exitCode, _, err = run(context.Background(), cfg)
+		})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "Item ARK,IIIF Manifest URL\n", stdout)
+	assert.NotContains(t, stderr, "SUCCESS!")
+	assert.NoFileExists(t, stdoutOutput)
+
+	// Several files can't share stdout
+	cfg.Sources = append(cfg.Sources, TestDirUnFester+"/chase.csv")
+	captureStderr(t, func() {
+		exitCode, _, err = run(context.Background(), cfg)
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, int(INVALID_OUTPUT_SPECIFIED), exitCode)
+}
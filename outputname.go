@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultOutputTemplate names output CSVs after their inputs
+const defaultOutputTemplate string = "{name}{ext}"
+
+// placeholderRegexp matches the placeholders in an output template
+var placeholderRegexp = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// outputPlaceholders returns the values of the placeholders for an input file
+func outputPlaceholders(inputName string, date time.Time) map[string]string {
+	inputName = csvName(filepath.Base(inputName))
+	ext := filepath.Ext(inputName)
+
+	return map[string]string{
+		"name":    strings.TrimSuffix(inputName, ext),
+		"ext":     ext,
+		"date":    date.Format("2006-01-02"),
+		"version": festerizeVersion,
+	}
+}
+
+// ValidateOutputTemplate checks that an output template only uses known placeholders and names a file
+func ValidateOutputTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("output template must not be empty")
+	}
+	if strings.ContainsAny(template, `/\`) {
+		return fmt.Errorf("output template %q must name a file, not a path", template)
+	}
+
+	known := outputPlaceholders("", time.Time{})
+	for _, match := range placeholderRegexp.FindAllStringSubmatch(template, -1) {
+		if _, found := known[match[1]]; !found {
+			return fmt.Errorf("unknown placeholder {%s} in output template; allowed placeholders are "+
+				"{name}, {ext}, {date}, and {version}", match[1])
+		}
+	}
+	return nil
+}
+
+// OutputFilename computes the name of the output CSV for an input file from a validated template
+func OutputFilename(template string, inputName string, date time.Time) string {
+	values := outputPlaceholders(inputName, date)
+	return placeholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return values[placeholder[1:len(placeholder)-1]]
+	})
+}
+
+// Policies for handling two inputs whose output files would have the same path
+const (
+	CollisionPolicyError  string = "error"
+	CollisionPolicyNumber string = "number"
+)
+
+// ValidateCollisionPolicy validates the collision policy
+func ValidateCollisionPolicy() error {
+	switch collisionPolicy {
+	case CollisionPolicyError, CollisionPolicyNumber:
+		return nil
+	default:
+		return fmt.Errorf("invalid collision policy. Allowed values are error or number")
+	}
+}
+
+// commonDir returns the deepest directory containing all of the files
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+
+	common := filepath.Dir(paths[0])
+	for _, path := range paths[1:] {
+		dir := filepath.Dir(path)
+		for !isWithin(common, dir) {
+			parent := filepath.Dir(common)
+			if parent == common {
+				break
+			}
+			common = parent
+		}
+	}
+	return common
+}
+
+// isWithin checks whether a directory is the same as or inside another
+func isWithin(parent string, dir string) bool {
+	rel, err := filepath.Rel(parent, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// outputDir returns the directory an input's output CSV goes in: the output directory itself, or when
+// mirroring the input tree, the input's directory relative to the tree's root recreated under it
+func outputDir(out string, treeRoot string, inputPath string) string {
+	if treeRoot == "" {
+		return out
+	}
+	rel, err := filepath.Rel(treeRoot, filepath.Dir(inputPath))
+	if err != nil {
+		return out
+	}
+	return filepath.Join(out, rel)
+}
+
+// claimOutput records that an output file is written for an input and returns its path; if another input
+// already claimed the path (compared as the file system would), it's an error or, under the number policy, a
+// counter is added to the name
+func claimOutput(written map[string]string, outputPath string, inputPath string, policy string) (string, error) {
+	earlier, found := written[pathKey(outputPath)]
+	if !found || pathKey(earlier) == pathKey(inputPath) {
+		written[pathKey(outputPath)] = inputPath
+		return outputPath, nil
+	}
+	if policy != CollisionPolicyNumber {
+		return "", fmt.Errorf("output file %s was already written for %s", outputPath, earlier)
+	}
+
+	ext := filepath.Ext(outputPath)
+	base := strings.TrimSuffix(outputPath, ext)
+	for counter := 1; ; counter++ {
+		numbered := fmt.Sprintf("%s-%d%s", base, counter, ext)
+		if _, found := written[pathKey(numbered)]; !found {
+			written[pathKey(numbered)] = inputPath
+			return numbered, nil
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOutputFilename tests placeholder substitution in output templates
+func TestOutputFilename(t *testing.T) {
+	original := festerizeVersion
+	defer func() { festerizeVersion = original }()
+	festerizeVersion = "1.2.3"
+
+	date := time.Date(2024, time.June, 1, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		template string
+		input    string
+		expected string
+	}{
+		{defaultOutputTemplate, "ballin.csv", "ballin.csv"},
+		{defaultOutputTemplate, "/data/csvs/Ballin.CSV", "Ballin.CSV"},
+		{defaultOutputTemplate, "chase.csv.gz", "chase.csv"},
+		{"{name}-v{version}-{date}.csv", "ballin.csv", "ballin-v1.2.3-2024-06-01.csv"},
+		{"festerized-{name}{ext}", "edson.csv", "festerized-edson.csv"},
+		{"{name}.{name}.csv", "edson.csv", "edson.edson.csv"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.template+" "+tc.input, func(t *testing.T) {
+			assert.Nil(t, ValidateOutputTemplate(tc.template))
+			assert.Equal(t, tc.expected, OutputFilename(tc.template, tc.input, date))
+		})
+	}
+}
+
+// TestValidateOutputTemplate tests that unknown placeholders and paths are rejected
+func TestValidateOutputTemplate(t *testing.T) {
+	for _, template := range []string{"", "{name}-{time}.csv", "{Name}.csv", "{}.csv", "out/{name}.csv", `out\{name}.csv`} {
+		assert.NotNil(t, ValidateOutputTemplate(template), template)
+	}
+}
+
+// TestClaimOutput tests that two inputs can't write to the same output file unless numbering is allowed
+func TestClaimOutput(t *testing.T) {
+	date := time.Now()
+	template := "{date}.csv"
+	written := map[string]string{}
+
+	first := OutputFilename(template, "ballin.csv", date)
+	second := OutputFilename(template, "chase.csv", date)
+	assert.Equal(t, first, second)
+
+	path, err := claimOutput(written, first, "ballin.csv", CollisionPolicyError)
+	assert.Nil(t, err)
+	assert.Equal(t, first, path)
+
+	_, err = claimOutput(written, second, "chase.csv", CollisionPolicyError)
+	assert.NotNil(t, err)
+
+	// The same input may be listed twice
+	path, err = claimOutput(written, first, "ballin.csv", CollisionPolicyError)
+	assert.Nil(t, err)
+	assert.Equal(t, first, path)
+}
+
+// TestClaimOutputNumbered tests that same-named files from different input directories are numbered when
+// written to a flat output directory
+func TestClaimOutputNumbered(t *testing.T) {
+	written := map[string]string{}
+	inputs := []string{
+		filepath.FromSlash("/data/a/ballin.csv"),
+		filepath.FromSlash("/data/b/ballin.csv"),
+		filepath.FromSlash("/data/c/ballin.csv"),
+	}
+
+	var outputs []string
+	for _, input := range inputs {
+		output := filepath.Join("output", OutputFilename(defaultOutputTemplate, input, time.Now()))
+		path, err := claimOutput(written, output, input, CollisionPolicyNumber)
+		assert.Nil(t, err)
+		outputs = append(outputs, path)
+	}
+
+	assert.Equal(t, []string{
+		filepath.Join("output", "ballin.csv"),
+		filepath.Join("output", "ballin-1.csv"),
+		filepath.Join("output", "ballin-2.csv"),
+	}, outputs)
+}
+
+// TestOutputDirMirrorTree tests that same-named files from different input directories keep their directories
+// when the input tree is mirrored
+func TestOutputDirMirrorTree(t *testing.T) {
+	inputs := []string{
+		filepath.FromSlash("/data/batch/a/ballin.csv"),
+		filepath.FromSlash("/data/batch/b/ballin.csv"),
+		filepath.FromSlash("/data/batch/b/deeper/chase.csv"),
+	}
+
+	root := commonDir(inputs)
+	assert.Equal(t, filepath.FromSlash("/data/batch"), root)
+	assert.Equal(t, filepath.Join("output", "a"), outputDir("output", root, inputs[0]))
+	assert.Equal(t, filepath.Join("output", "b"), outputDir("output", root, inputs[1]))
+	assert.Equal(t, filepath.Join("output", "b", "deeper"), outputDir("output", root, inputs[2]))
+
+	// Without mirroring, everything goes in the output directory
+	assert.Equal(t, "output", outputDir("output", "", inputs[2]))
+
+	// A single input's tree is just its own directory
+	assert.Equal(t, filepath.FromSlash("/data/batch/a"), commonDir(inputs[:1]))
+	assert.Equal(t, filepath.FromSlash("/data/batch"), commonDir([]string{inputs[2], inputs[0]}))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// globMeta holds the characters that make a source a glob pattern
+const globMeta string = "*?["
+
+// ExpandGlobs expands the sources that are glob patterns, since Windows shells pass them on as they are;
+// a source that names an existing file is kept even if it looks like a pattern, and a pattern that matches
+// nothing is kept so that it's reported as missing
+func ExpandGlobs(sources []string) []string {
+	expanded := make([]string, 0, len(sources))
+	for _, source := range sources {
+		if !strings.ContainsAny(source, globMeta) {
+			expanded = append(expanded, source)
+			continue
+		}
+		if _, err := os.Stat(source); err == nil {
+			expanded = append(expanded, source)
+			continue
+		}
+
+		matches, err := filepath.Glob(source)
+		if err != nil || len(matches) == 0 {
+			Logger.Warn("Glob pattern matched no files", zap.String("pattern", source), zap.Error(err))
+			expanded = append(expanded, source)
+			continue
+		}
+		expanded = append(expanded, matches...)
+	}
+	return expanded
+}
+
+// pathKey returns the form paths are compared in: cleaned, and on Windows, whose file systems ignore case,
+// lower case
+func pathKey(path string) string {
+	path = filepath.Clean(path)
+	if runtime.GOOS == "windows" {
+		return strings.ToLower(path)
+	}
+	return path
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExpandGlobs tests that glob patterns are expanded in order and other sources are kept as they are
+func TestExpandGlobs(t *testing.T) {
+	logger, sink := createLogger()
+	Logger = logger
+
+	dir := t.TempDir()
+	for _, name := range []string{"chase.csv", "ballin.csv", "notes.txt", "batch[1].csv"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("Item ARK\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expanded := ExpandGlobs([]string{
+		filepath.Join(dir, "*.csv"),
+		stdinSource,
+		filepath.Join(dir, "batch[1].csv"),
+		filepath.Join(dir, "*.tsv"),
+	})
+	assert.Equal(t, []string{
+		filepath.Join(dir, "ballin.csv"),
+		filepath.Join(dir, "batch[1].csv"),
+		filepath.Join(dir, "chase.csv"),
+		stdinSource,
+		filepath.Join(dir, "batch[1].csv"),
+		filepath.Join(dir, "*.tsv"),
+	}, expanded)
+	assert.Contains(t, sink.String(), "Glob pattern matched no files")
+}
+
+// TestExpandGlobsSlashes tests that patterns written with forward slashes work on every platform
+func TestExpandGlobsSlashes(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	expanded := ExpandGlobs([]string{filepath.FromSlash(TestDirUnFester + "/ch*.csv")})
+	assert.Equal(t, []string{
+		filepath.FromSlash(TestDirUnFester + "/chandler.csv"),
+		filepath.FromSlash(TestDirUnFester + "/chase.csv"),
+	}, expanded)
+}
+
+// TestPathKey tests that paths are compared case-insensitively on Windows only
+func TestPathKey(t *testing.T) {
+	upper, lower := filepath.FromSlash("Output/Ballin.csv"), filepath.FromSlash("output/./ballin.csv")
+	assert.Equal(t, runtime.GOOS == "windows", pathKey(upper) == pathKey(lower))
+	assert.Equal(t, pathKey(filepath.FromSlash("output/ballin.csv")), pathKey(filepath.FromSlash("output/./ballin.csv")))
+}
+
+// TestClaimOutputCase tests that outputs differing only in case collide on Windows, where they're the same file
+func TestClaimOutputCase(t *testing.T) {
+	written := map[string]string{}
+	first := filepath.Join("output", OutputFilename(defaultOutputTemplate, "Ballin.csv", time.Now()))
+	second := filepath.Join("output", OutputFilename(defaultOutputTemplate, "ballin.csv", time.Now()))
+
+	_, err := claimOutput(written, first, filepath.FromSlash("/data/a/Ballin.csv"), CollisionPolicyError)
+	assert.Nil(t, err)
+	_, err = claimOutput(written, second, filepath.FromSlash("/data/b/ballin.csv"), CollisionPolicyError)
+	if runtime.GOOS == "windows" {
+		assert.NotNil(t, err)
+	} else {
+		assert.Nil(t, err)
+	}
+}
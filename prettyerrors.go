@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrorSummary is what people need to know about a file that failed to upload, without opening the log
+type ErrorSummary struct {
+	Filename   string
+	StatusCode int
+	Message    string
+	RequestID  string
+	Fix        string
+}
+
+// summarizeError describes a failed upload from the error it failed with and the status code Fester answered
+// with, which is zero if it didn't answer
+func summarizeError(filename string, statusCode int, err error) ErrorSummary {
+	summary := ErrorSummary{Filename: filename, StatusCode: statusCode, Fix: suggestFix(statusCode, err)}
+	if err != nil {
+		summary.Message = err.Error()
+	}
+
+	var festerError FesterError
+	if errors.As(err, &festerError) {
+		summary.RequestID = festerError.RequestID
+		if festerError.Detail != "" && festerError.Title != "" && festerError.Title != festerError.Detail {
+			summary.Message = festerError.Title + ": " + festerError.Detail
+		}
+	}
+	return summary
+}
+
+// suggestFix suggests what to do about the known kinds of failed uploads, or returns an empty string
+func suggestFix(statusCode int, err error) string {
+	var festerError FesterError
+	var urlErr *url.Error
+	switch {
+	case errors.As(err, &festerError) && IsOrderingError(statusCode, festerError):
+		return "Upload the CSV with this file's parent rows first, or pass --auto-order or --on-conflict defer."
+	case errors.Is(err, context.DeadlineExceeded):
+		return "The run ran out of time; raise --max-runtime or upload fewer files at once."
+	case errors.As(err, &urlErr):
+		return "Check that --server is right and that Fester can be reached from here."
+	}
+
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "Check the credentials: --token, $" + tokenEnvVar + ", or the user and password in --server."
+	case http.StatusNotFound:
+		return "Check --server and --base-path; Fester's upload endpoint wasn't found there."
+	case http.StatusRequestEntityTooLarge:
+		return "The CSV is too large for one request; upload it in parts with --chunk-rows."
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return "Fester or the proxy in front of it is unavailable; try again later."
+	}
+	return ""
+}
+
+// writeErrorSummary writes a failed upload as a short block for people to read
+func writeErrorSummary(w io.Writer, summary ErrorSummary) error {
+	var block strings.Builder
+	fmt.Fprintf(&block, "  Status:     %s\n", statusText(summary.StatusCode))
+	if summary.Message != "" {
+		fmt.Fprintf(&block, "  Error:      %s\n", summary.Message)
+	}
+	if summary.RequestID != "" {
+		fmt.Fprintf(&block, "  Request ID: %s\n", summary.RequestID)
+	}
+	if summary.Fix != "" {
+		fmt.Fprintf(&block, "  Fix:        %s\n", summary.Fix)
+	}
+
+	_, err := fmt.Fprint(w, colorize(ansiRed, "Failed to upload "+summary.Filename+"\n")+block.String()+"\n")
+	return err
+}
+
+// statusText describes a status code, or the lack of one when Fester couldn't be reached
+func statusText(statusCode int) string {
+	if statusCode == 0 {
+		return "no response"
+	}
+	return fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode))
+}
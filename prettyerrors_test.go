@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWriteErrorSummary tests the block printed for Fester's error page about a work uploaded before its parent
+func TestWriteErrorSummary(t *testing.T) {
+	defer func() { colorMode = ColorAuto }()
+	colorMode = ColorNever
+
+	body, err := os.ReadFile(TestDirErrorPages + "/fester-error.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	festerError, err := ParseFesterError(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, writeErrorSummary(&buffer, summarizeError("ballin.csv", http.StatusBadRequest, festerError)))
+	assert.Equal(t, "Failed to upload ballin.csv\n"+
+		"  Status:     400 Bad Request\n"+
+		"  Error:      400 Bad Request: Work ark:/21198/zz00093cw5 has a parent collection that has not been festerized\n"+
+		"  Request ID: 7f3c2a91-5d1e-4c8b-9a0e-2b6f4d8e1c37\n"+
+		"  Fix:        Upload the CSV with this file's parent rows first, or pass --auto-order or --on-conflict defer.\n"+
+		"\n", buffer.String())
+}
+
+// TestWriteErrorSummaryUnreachable tests the block printed when Fester couldn't be reached
+func TestWriteErrorSummaryUnreachable(t *testing.T) {
+	defer func() { colorMode = ColorAuto }()
+	colorMode = ColorNever
+
+	err := &url.Error{Op: "Post", URL: "https://fester.example.org/collections", Err: errors.New("connection refused")}
+	var buffer bytes.Buffer
+	assert.NoError(t, writeErrorSummary(&buffer, summarizeError("ballin.csv", 0, err)))
+	assert.Equal(t, "Failed to upload ballin.csv\n"+
+		"  Status:     no response\n"+
+		"  Error:      Post \"https://fester.example.org/collections\": connection refused\n"+
+		"  Fix:        Check that --server is right and that Fester can be reached from here.\n"+
+		"\n", buffer.String())
+}
+
+// TestSuggestFix tests the fixes suggested for each known kind of failure
+func TestSuggestFix(t *testing.T) {
+	assert.Contains(t, suggestFix(http.StatusUnauthorized, FesterError{Title: "Unauthorized"}), "--token")
+	assert.Contains(t, suggestFix(http.StatusRequestEntityTooLarge, nil), "--chunk-rows")
+	assert.Contains(t, suggestFix(http.StatusServiceUnavailable, nil), "try again later")
+	assert.Contains(t, suggestFix(0, context.DeadlineExceeded), "--max-runtime")
+	assert.Empty(t, suggestFix(http.StatusBadRequest, FesterError{Detail: "Malformed CSV"}))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// maxPreviewCellWidth is the number of characters of a cell shown in a preview, so that long descriptions
+// don't push the other columns off the screen
+const maxPreviewCellWidth int = 24
+
+// ValidatePreview validates the number of rows shown from each end of a returned CSV
+func ValidatePreview(rows int) error {
+	if rows < 0 {
+		return fmt.Errorf("invalid --preview %d: expected a number of rows, or 0 for no preview", rows)
+	}
+	return nil
+}
+
+// PreviewRows selects the header and the first and last n data rows of a CSV, along with the number of rows
+// left out between them; a CSV with no more than 2n data rows is shown whole
+func PreviewRows(records [][]string, n int) ([][]string, int) {
+	if len(records) == 0 {
+		return nil, 0
+	}
+
+	header, rows := records[0], records[1:]
+	if len(rows) <= 2*n {
+		return records, 0
+	}
+
+	selected := [][]string{header}
+	selected = append(selected, rows[:n]...)
+	selected = append(selected, rows[len(rows)-n:]...)
+	return selected, len(rows) - 2*n
+}
+
+// previewCell shortens a cell to the preview's width, and keeps it on one line
+func previewCell(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if utf8.RuneCountInString(value) <= maxPreviewCellWidth {
+		return value
+	}
+	return string([]rune(value)[:maxPreviewCellWidth-1]) + "…"
+}
+
+// writePreview writes the header and the first and last n data rows of a returned CSV as a table
+func writePreview(w io.Writer, filename string, body []byte, n int) error {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	selected, omitted := PreviewRows(records, n)
+	fmt.Fprintf(w, "%s (%d rows):\n", filename, max(len(records)-1, 0))
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for index, row := range selected {
+		// Mark where rows were left out, after the header and the first n rows, keeping the columns aligned
+		// on both sides of it
+		if omitted > 0 && index == n+1 {
+			fmt.Fprintf(table, "… %d more rows …\t\n", omitted)
+		}
+		cells := make([]string, len(row))
+		for column, value := range row {
+			cells[column] = previewCell(value)
+		}
+		fmt.Fprintln(table, strings.Join(cells, "\t"))
+	}
+	return table.Flush()
+}
+
+// previewCSV prints a preview of a returned CSV, logging rather than failing the file if it can't be read
+func previewCSV(w io.Writer, filename string, body []byte, n int) {
+	if err := writePreview(w, filename, body, n); err != nil {
+		Logger.Warn("Could not preview Fester's response",
+			zap.String("filename", filename),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// previewRecords returns a header and the supplied number of numbered data rows
+func previewRecords(count int) [][]string {
+	records := [][]string{{"Item ARK", "Title"}}
+	for index := 1; index <= count; index++ {
+		records = append(records, []string{fmt.Sprintf("ark:/21198/zz%04d", index), fmt.Sprintf("Row %d", index)})
+	}
+	return records
+}
+
+// TestPreviewRows tests that the first and last rows are selected, and that short CSVs are shown whole
+func TestPreviewRows(t *testing.T) {
+	records := previewRecords(10)
+
+	selected, omitted := PreviewRows(records, 2)
+	assert.Equal(t, [][]string{records[0], records[1], records[2], records[9], records[10]}, selected)
+	assert.Equal(t, 6, omitted)
+
+	// Rows aren't repeated when the ends would overlap
+	for _, count := range []int{0, 1, 3, 4} {
+		selected, omitted = PreviewRows(previewRecords(count), 2)
+		assert.Equal(t, previewRecords(count), selected)
+		assert.Zero(t, omitted)
+	}
+
+	selected, omitted = PreviewRows(nil, 2)
+	assert.Empty(t, selected)
+	assert.Zero(t, omitted)
+}
+
+// TestWritePreview tests that the preview is aligned and marks the rows it leaves out
+func TestWritePreview(t *testing.T) {
+	body := "Item ARK,Title\n" +
+		"ark:/21198/zz0001,Papers\n" +
+		"ark:/21198/zz0002,\"A very long title, which goes on and on\"\n" +
+		"ark:/21198/zz0003,Page 1\n" +
+		"ark:/21198/z4,Page 2\n"
+
+	var buffer bytes.Buffer
+	assert.Nil(t, writePreview(&buffer, "ballin.csv", []byte(body), 1))
+
+	expected := strings.Join([]string{
+		"ballin.csv (4 rows):",
+		"Item ARK           Title",
+		"ark:/21198/zz0001  Papers",
+		"… 2 more rows …    ",
+		"ark:/21198/z4      Page 2",
+		"",
+	}, "\n")
+	assert.Equal(t, expected, buffer.String())
+}
+
+// TestPreviewCell tests that long and multi-line cells are shortened to one line
+func TestPreviewCell(t *testing.T) {
+	assert.Equal(t, "Papers", previewCell("Papers"))
+	assert.Equal(t, "Line one line two", previewCell("Line one\nline two"))
+	assert.Equal(t, "A very long title, whic…", previewCell("A very long title, which goes on and on"))
+}
+
+// TestValidatePreview tests that a negative number of rows is rejected
+func TestValidatePreview(t *testing.T) {
+	assert.NoError(t, ValidatePreview(0))
+	assert.NoError(t, ValidatePreview(5))
+	assert.Error(t, ValidatePreview(-1))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// defaultProductionPattern matches the hostname of the production Fester
+const defaultProductionPattern string = `^ingest\.iiif\.library\.ucla\.edu$`
+
+// ParseProductionPattern compiles the pattern production servers' hostnames match; an empty pattern means no
+// server is treated as production
+func ParseProductionPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --production-pattern %q: %w", pattern, err)
+	}
+	return compiled, nil
+}
+
+// serverHostname returns the hostname of a server URL, without its port or credentials
+func serverHostname(server string) string {
+	parsedURL, err := url.Parse(server)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsedURL.Hostname())
+}
+
+// ConfirmProductionServers makes sure a run was meant for any production server among its servers: the
+// server's hostname must have been given with --confirm-server, or --yes passed, or be typed in at the prompt.
+// Without a terminal to answer the prompt, the run is refused.
+func ConfirmProductionServers(servers []string, pattern *regexp.Regexp, confirmedHost string, assumeYes bool,
+	in io.Reader, out io.Writer, interactive bool) error {
+	if pattern == nil {
+		return nil
+	}
+
+	var reader *bufio.Reader
+	for _, server := range servers {
+		hostname := serverHostname(server)
+		if !pattern.MatchString(hostname) {
+			continue
+		}
+
+		switch {
+		case strings.EqualFold(confirmedHost, hostname):
+			Logger.Info("Production server confirmed with --confirm-server", zap.String("server", hostname))
+			continue
+		case assumeYes:
+			Logger.Warn("Uploading to production server without confirmation, since --yes was passed",
+				zap.String("server", hostname))
+			continue
+		case !interactive:
+			return fmt.Errorf("%s is a production server; pass --confirm-server %s (or --yes) to upload to it",
+				hostname, hostname)
+		}
+
+		if reader == nil {
+			reader = bufio.NewReader(in)
+		}
+		fmt.Fprintf(out, "%s is a production server. Type its hostname to upload to it: ", hostname)
+		response, _ := reader.ReadString('\n')
+		if !strings.EqualFold(strings.TrimSpace(response), hostname) {
+			return fmt.Errorf("aborted: %q doesn't match %s", strings.TrimSpace(response), hostname)
+		}
+		Logger.Info("Production server confirmed at the prompt", zap.String("server", hostname))
+	}
+	return nil
+}
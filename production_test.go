@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConfirmProductionServers tests the prompt for a production server's hostname, and the ways around it
+func TestConfirmProductionServers(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+	pattern, err := ParseProductionPattern(defaultProductionPattern)
+	if !assert.Nil(t, err) {
+		return
+	}
+	production := []string{"https://test.ingest.iiif.library.ucla.edu", "https://ingest.iiif.library.ucla.edu/"}
+
+	tests := []struct {
+		name        string
+		servers     []string
+		confirmed   string
+		assumeYes   bool
+		userInput   string
+		interactive bool
+		proceed     bool
+	}{
+		{"Test server", production[:1], "", false, "", false, true},
+		{"Hostname typed in", production, "", false, "ingest.iiif.library.ucla.edu\n", true, true},
+		{"Hostname typed in capitals", production, "", false, "INGEST.iiif.library.ucla.edu\n", true, true},
+		{"User enters 'y'", production, "", false, "y\n", true, false},
+		{"Test hostname typed in", production, "", false, "test.ingest.iiif.library.ucla.edu\n", true, false},
+		{"No input", production, "", false, "", true, false},
+		{"Not a terminal", production, "", false, "ingest.iiif.library.ucla.edu\n", false, false},
+		{"Confirmed with --confirm-server", production, "ingest.iiif.library.ucla.edu", false, "", false, true},
+		{"Other server confirmed", production, "test.ingest.iiif.library.ucla.edu", false, "", false, false},
+		{"Bypassed with --yes", production, "", true, "", false, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var output bytes.Buffer
+
+			err := ConfirmProductionServers(tc.servers, pattern, tc.confirmed, tc.assumeYes,
+				strings.NewReader(tc.userInput), &output, tc.interactive)
+
+			assert.Equal(t, tc.proceed, err == nil, err)
+			if tc.interactive && tc.confirmed == "" && !tc.assumeYes && len(tc.servers) > 1 {
+				assert.Contains(t, output.String(), "ingest.iiif.library.ucla.edu is a production server")
+			} else {
+				assert.Empty(t, output.String())
+			}
+		})
+	}
+}
+
+// TestParseProductionPattern tests that an empty pattern turns the check off, and an invalid one is rejected
+func TestParseProductionPattern(t *testing.T) {
+	pattern, err := ParseProductionPattern("")
+	assert.Nil(t, err)
+	assert.Nil(t, pattern)
+	assert.Nil(t, ConfirmProductionServers([]string{"https://ingest.iiif.library.ucla.edu"}, pattern, "", false,
+		strings.NewReader(""), &bytes.Buffer{}, false))
+
+	_, err = ParseProductionPattern("(ingest")
+	assert.ErrorContains(t, err, "invalid --production-pattern")
+}
+
+// TestRunProductionServer tests that an unconfirmed run against production stops before the output directory is
+// created or the server contacted
+func TestRunProductionServer(t *testing.T) {
+	h := newRunHarness(t, respondWith(http.StatusCreated, ""))
+	cfg := testConfig(t, h.server.URL, writeTestCSV(t, "collection.csv", testCollectionCSV))
+	cfg.Production, _ = ParseProductionPattern(`^127\.0\.0\.1$`)
+
+	exitCode, _, err := run(context.Background(), cfg)
+	assert.ErrorContains(t, err, "pass --confirm-server 127.0.0.1")
+	assert.Equal(t, int(NOT_CONFIRMED), exitCode)
+	assert.NoDirExists(t, cfg.OutputDir)
+
+	cfg.ConfirmServer = "127.0.0.1"
+	exitCode, _, err = run(context.Background(), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+}
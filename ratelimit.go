@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultRetryAfter is how long to wait after a 429 response that doesn't say when to retry
+const defaultRetryAfter time.Duration = 5 * time.Second
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+	return defaultRetryAfter
+}
+
+// doWithRateLimit sends a request, and when Fester answers 429 Too Many Requests, waits as long as its Retry-After
+// asks and sends the request again; once the waits would exceed --max-retry-wait, the 429 response is returned
+func doWithRateLimit(ctx context.Context, client *http.Client, request *http.Request) (*http.Response, error) {
+	var waited time.Duration
+	for {
+		response, err := client.Do(request)
+		if err != nil || response.StatusCode != http.StatusTooManyRequests {
+			return response, err
+		}
+
+		wait := retryAfter(response.Header.Get("Retry-After"), time.Now())
+		if waited+wait > maxRetryWait {
+			Logger.Warn("Fester is rate limiting uploads, giving up",
+				zap.String("url", request.URL.String()),
+				zap.Duration("retry_after", wait),
+				zap.Duration("waited", waited))
+			return response, nil
+		}
+
+		io.Copy(io.Discard, response.Body)
+		response.Body.Close()
+		Logger.Warn("Fester is rate limiting uploads, waiting to retry",
+			zap.String("url", request.URL.String()),
+			zap.Duration("retry_after", wait))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		waited += wait
+
+		if request, err = rewindRequest(request); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rewindRequest copies a request with a fresh body so it can be sent again
+func rewindRequest(request *http.Request) (*http.Request, error) {
+	retry := request.Clone(request.Context())
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
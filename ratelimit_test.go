@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRetryAfter tests parsing Retry-After in seconds and as an HTTP date
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 3*time.Second, retryAfter("3", now))
+	assert.Equal(t, 90*time.Second, retryAfter("Sat, 01 Jun 2024 12:01:30 GMT", now))
+	assert.Equal(t, time.Duration(0), retryAfter("Sat, 01 Jun 2024 11:00:00 GMT", now))
+	assert.Equal(t, defaultRetryAfter, retryAfter("", now))
+	assert.Equal(t, defaultRetryAfter, retryAfter("soon", now))
+}
+
+// newRateLimitedFester starts a server that answers the first upload with 429 and the next with 201
+func newRateLimitedFester(t *testing.T, requests *int32, received *[]string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if file, _, err := r.FormFile("file"); err == nil {
+			content, _ := io.ReadAll(file)
+			*received = append(*received, string(content))
+		}
+
+		if atomic.AddInt32(requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// TestPostCSVRetriesRateLimited tests that a 429 upload is sent again after Retry-After
+func TestPostCSVRetriesRateLimited(t *testing.T) {
+	logger, sink := createLogger()
+	Logger = logger
+	defer func() { maxRetryWait = time.Minute }()
+	maxRetryWait = time.Minute
+
+	var requests int32
+	var received []string
+	ts := newRateLimitedFester(t, &requests, &received)
+
+	started := time.Now()
+	response, _, err := postCSV(context.Background(), "test.csv", strings.NewReader("Item ARK\n"), ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Equal(t, int32(2), requests)
+	assert.Equal(t, []string{"Item ARK\n", "Item ARK\n"}, received)
+	assert.GreaterOrEqual(t, time.Since(started), time.Second)
+	assert.Contains(t, sink.String(), "Fester is rate limiting uploads, waiting to retry")
+}
+
+// TestPostCSVRateLimitWaitCapped tests that a 429 is returned when waiting would exceed --max-retry-wait
+func TestPostCSVRateLimitWaitCapped(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+	defer func() { maxRetryWait = time.Minute }()
+	maxRetryWait = 500 * time.Millisecond
+
+	var requests int32
+	var received []string
+	ts := newRateLimitedFester(t, &requests, &received)
+
+	response, _, err := postCSV(context.Background(), "test.csv", strings.NewReader("Item ARK\n"), ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+	assert.Equal(t, int32(1), requests)
+}
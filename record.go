@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// recordedRequests counts the requests recorded during the run, so each gets its own files even when a CSV is
+// uploaded in several requests or to several servers
+var recordedRequests atomic.Int64
+
+// RecordRequest writes a request's body, exactly as it's sent, to <n>-<name>.body in the directory, and its
+// method, URL, and headers to <n>-<name>.headers; credentials are hidden, as are the values of the custom
+// headers, since any of them can be an API key. It returns the path of the body's file.
+func RecordRequest(dir, uploadName string, request *http.Request, body []byte, customHeaders map[string]string) (
+	string, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	prefix := filepath.Join(dir, fmt.Sprintf("%04d-%s", recordedRequests.Add(1), filepath.Base(uploadName)))
+
+	headers := redactHeaders(request.Header)
+	for key := range customHeaders {
+		if key = http.CanonicalHeaderKey(key); key != "User-Agent" {
+			headers[key] = redactedValue
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var description strings.Builder
+	fmt.Fprintf(&description, "%s %s\n", request.Method, Redact(request.URL.String()))
+	for _, name := range names {
+		fmt.Fprintf(&description, "%s: %s\n", name, headers[name])
+	}
+
+	if err := os.WriteFile(prefix+".headers", []byte(description.String()), 0644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(prefix+".body", body, 0644); err != nil {
+		return "", err
+	}
+	return prefix + ".body", nil
+}
+
+// recordRequest records a request, when --record-requests is set, logging where it went; a request that can't
+// be recorded is still sent
+func recordRequest(dir, uploadName string, request *http.Request, body []byte, customHeaders map[string]string) {
+	if dir == "" {
+		return
+	}
+
+	path, err := RecordRequest(dir, uploadName, request, body, customHeaders)
+	if err != nil {
+		Logger.Warn("Could not record request",
+			zap.String("filename", filepath.Base(uploadName)),
+			zap.Error(err))
+		return
+	}
+	Logger.Info("Recorded request",
+		zap.String("filename", filepath.Base(uploadName)),
+		zap.String("path", path))
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	// sensitiveKeyPattern matches field and header names whose values are always hidden
+	sensitiveKeyPattern = regexp.MustCompile(`(?i)(authorization|password|passwd|secret|token)`)
+
+	// urlCredentialsPattern matches the password in the user info of a URL
+	urlCredentialsPattern = regexp.MustCompile(`(://[^:/@\s]+):[^@/\s]+@`)
+
+	// credentialAssignmentPattern matches credentials written as key=value or key: value
+	credentialAssignmentPattern = regexp.MustCompile(
+		`(?i)((?:authorization|password|passwd|secret|token)["']?\s*[:=]\s*["']?)(?:(?:basic|bearer)\s+)?[^\s"'&,;}]+`)
+)
+
+// isSensitiveKey checks whether values stored under the supplied name should be hidden
+func isSensitiveKey(key string) bool {
+	return sensitiveKeyPattern.MatchString(key)
+}
+
+// Redact scrubs credentials embedded in a string
+func Redact(value string) string {
+	value = urlCredentialsPattern.ReplaceAllString(value, "$1:"+redactedValue+"@")
+	return credentialAssignmentPattern.ReplaceAllString(value, "${1}"+redactedValue)
+}
+
+// redactAll returns a copy of the supplied values with the credentials in each one scrubbed
+func redactAll(values []string) []string {
+	redacted := make([]string, len(values))
+	for index, value := range values {
+		redacted[index] = Redact(value)
+	}
+	return redacted
+}
+
+// redactArgs returns a copy of the command line that is safe to log: the values of sensitive flags and of
+// --header, which can carry an API key under any name, are hidden, as are credentials in URLs
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+
+	// The flag whose value is the next argument, if it's one whose value is hidden
+	pending := ""
+	for index, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		switch {
+		case pending == "header":
+			arg = redactHeaderArg(arg)
+		case pending != "":
+			arg = redactedValue
+		case isFlagName(arg) && hasValue && isSensitiveKey(name):
+			arg = arg[:len(arg)-len(value)] + redactedValue
+		case isFlagName(arg) && hasValue && name == "header":
+			arg = arg[:len(arg)-len(value)] + redactHeaderArg(value)
+		default:
+			arg = Redact(arg)
+		}
+
+		pending = ""
+		if isFlagName(args[index]) && !hasValue && (isSensitiveKey(name) || name == "header") {
+			pending = name
+		}
+		redacted[index] = arg
+	}
+	return redacted
+}
+
+// isFlagName checks whether a command line argument is a long flag
+func isFlagName(arg string) bool {
+	return strings.HasPrefix(arg, "--")
+}
+
+// redactHeaderArg hides the value of a header given as Name: value
+func redactHeaderArg(header string) string {
+	name, _, _ := strings.Cut(header, ":")
+	return name + ": " + redactedValue
+}
+
+// redactHeaders returns a copy of the headers that is safe to log, with credentials hidden
+func redactHeaders(headers http.Header) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for key, values := range headers {
+		if isSensitiveKey(key) {
+			redacted[key] = redactedValue
+		} else {
+			redacted[key] = Redact(strings.Join(values, ", "))
+		}
+	}
+	return redacted
+}
+
+// redactMap returns a copy of a string map that is safe to log, with credentials hidden
+func redactMap(values map[string]string) map[string]string {
+	redacted := make(map[string]string, len(values))
+	for key, value := range values {
+		if isSensitiveKey(key) {
+			redacted[key] = redactedValue
+		} else {
+			redacted[key] = Redact(value)
+		}
+	}
+	return redacted
+}
+
+// redactField returns a copy of a log field with any credentials hidden
+func redactField(field zapcore.Field) zapcore.Field {
+	if isSensitiveKey(field.Key) && field.Type != zapcore.ObjectMarshalerType {
+		return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: redactedValue}
+	}
+
+	switch field.Type {
+	case zapcore.StringType:
+		field.String = Redact(field.String)
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok && err != nil {
+			return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: Redact(err.Error())}
+		}
+	case zapcore.StringerType:
+		if stringer, ok := field.Interface.(interface{ String() string }); ok && stringer != nil {
+			return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: Redact(stringer.String())}
+		}
+	case zapcore.ReflectType:
+		switch value := field.Interface.(type) {
+		case http.Header:
+			field.Interface = redactHeaders(value)
+		case map[string]string:
+			field.Interface = redactMap(value)
+		}
+	}
+	return field
+}
+
+// redactFields returns copies of the log fields with any credentials hidden
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for index, field := range fields {
+		redacted[index] = redactField(field)
+	}
+	return redacted
+}
+
+// redactingCore wraps a zapcore.Core so nothing it writes contains credentials
+type redactingCore struct {
+	zapcore.Core
+}
+
+// newRedactingCore wraps the supplied core with credential redaction
+func newRedactingCore(core zapcore.Core) zapcore.Core {
+	return &redactingCore{core}
+}
+
+// With adds structured context to the core, redacting it first
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{c.Core.With(redactFields(fields))}
+}
+
+// Check adds this core to the checked entry if the entry's level is enabled
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write redacts the entry and its fields before passing them to the wrapped core
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = Redact(entry.Message)
+	return c.Core.Write(entry, redactFields(fields))
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// RecordedRequest is an upload request saved by --record-requests
+type RecordedRequest struct {
+	Name   string
+	Method string
+	// Path and query the request was sent to, which are kept when it's replayed to another server
+	Target string
+	Header http.Header
+	Body   []byte
+}
+
+// ReplayResult is the outcome of re-sending a recorded request
+type ReplayResult struct {
+	Name       string
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// Succeeded checks whether the server accepted the replayed request
+func (result ReplayResult) Succeeded() bool {
+	return result.Err == nil && IsSuccessStatus(result.StatusCode)
+}
+
+var replayServer string
+var replayHeaderValues []string
+
+// Re-sends recorded requests
+var replayCmd = &cobra.Command{
+	Use:   "replay <dir>",
+	Short: "Re-send the upload requests saved with --record-requests to a Fester server",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := LoadEnvFile(envFile, cmd.Flags().Changed("env-file")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if authToken == "" {
+			authToken = os.Getenv(tokenEnvVar)
+		}
+		if _, err := NewAuthenticator(replayServer, authToken); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		headers, err := ParseHeaders(replayHeaderValues)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		requests, err := ReadRecordedRequests(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		results := ReplayRequests(context.Background(), newUploadClient(), replayServer, requests, authToken, headers)
+		if err := writeReplayResults(os.Stdout, results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, result := range results {
+			if !result.Succeeded() {
+				os.Exit(1)
+			}
+		}
+		os.Exit(0)
+	},
+}
+
+// ReadRecordedRequests reads the requests recorded in a directory, in the order they were recorded
+func ReadRecordedRequests(dir string) ([]RecordedRequest, error) {
+	bodies, err := filepath.Glob(filepath.Join(dir, "*.body"))
+	if err != nil {
+		return nil, err
+	} else if len(bodies) == 0 {
+		return nil, fmt.Errorf("no recorded requests found in %s", dir)
+	}
+	slices.Sort(bodies)
+
+	requests := make([]RecordedRequest, 0, len(bodies))
+	for _, bodyPath := range bodies {
+		request, err := readRecordedRequest(strings.TrimSuffix(bodyPath, ".body"))
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// readRecordedRequest reads a recorded request from its .headers and .body files; hidden header values are
+// left out, since the credentials they stood for are added again when the request is sent
+func readRecordedRequest(prefix string) (RecordedRequest, error) {
+	request := RecordedRequest{Name: filepath.Base(prefix), Header: http.Header{}}
+
+	body, err := os.ReadFile(prefix + ".body")
+	if err != nil {
+		return request, err
+	}
+	request.Body = body
+
+	description, err := os.ReadFile(prefix + ".headers")
+	if err != nil {
+		return request, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(description))
+	if !scanner.Scan() {
+		return request, fmt.Errorf("%s.headers is empty", request.Name)
+	}
+	method, rawURL, found := strings.Cut(scanner.Text(), " ")
+	recordedURL, err := url.Parse(rawURL)
+	if !found || err != nil {
+		return request, fmt.Errorf("%s.headers doesn't start with a method and URL", request.Name)
+	}
+	request.Method, request.Target = method, recordedURL.RequestURI()
+
+	for scanner.Scan() {
+		name, value, found := strings.Cut(scanner.Text(), ": ")
+		if !found {
+			return request, fmt.Errorf("invalid header in %s.headers: %q", request.Name, scanner.Text())
+		}
+		if value != redactedValue {
+			request.Header.Add(name, value)
+		}
+	}
+	return request, scanner.Err()
+}
+
+// ReplayRequests re-sends the recorded requests to the server, one after another, with the supplied
+// credentials and headers in place of the hidden ones
+func ReplayRequests(ctx context.Context, client *http.Client, server string, requests []RecordedRequest,
+	token string, headers map[string]string) []ReplayResult {
+	results := make([]ReplayResult, 0, len(requests))
+	for _, recorded := range requests {
+		start := time.Now()
+		statusCode, err := replayRequest(ctx, client, server, recorded, token, headers)
+		results = append(results, ReplayResult{recorded.Name, statusCode, time.Since(start), err})
+	}
+	return results
+}
+
+// replayRequest sends a recorded request and returns the status code; the error Fester describes is returned
+// for a request it rejects
+func replayRequest(ctx context.Context, client *http.Client, server string, recorded RecordedRequest, token string,
+	headers map[string]string) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, recorded.Method, strings.TrimRight(server, "/")+recorded.Target,
+		bytes.NewReader(recorded.Body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header = recorded.Header.Clone()
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	if err := authenticate(request, token); err != nil {
+		return 0, err
+	}
+
+	response, err := doWithRateLimit(ctx, client, request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, err
+	}
+
+	if !IsSuccessStatus(response.StatusCode) {
+		if festerError, err := ParseFesterError(body); err == nil && festerError.Error() != "" {
+			return response.StatusCode, festerError
+		}
+		return response.StatusCode, errors.New(response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+// writeReplayResults writes the outcome of each replayed request as a table
+func writeReplayResults(w io.Writer, results []ReplayResult) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "REQUEST\tSTATUS\tDURATION\tERROR")
+	for _, result := range results {
+		message := ""
+		if result.Err != nil {
+			message = result.Err.Error()
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", result.Name, statusText(result.StatusCode),
+			result.Duration.Round(time.Millisecond), message)
+	}
+	return table.Flush()
+}
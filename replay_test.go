@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receipt is what a stub server received in an upload
+type receipt struct {
+	Path          string
+	Authorization string
+	APIKey        string
+	Content       string
+	IIIFVersion   string
+}
+
+// newReceivingStub returns a server that keeps what it receives in each upload
+func newReceivingStub(t *testing.T, receipts *[]receipt) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		*receipts = append(*receipts, receipt{r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("X-Api-Key"),
+			string(content), r.FormValue("iiif-version")})
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// TestReplayRequests tests that replayed requests reach the server just as the recorded ones did, with the
+// hidden credentials supplied again
+func TestReplayRequests(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+	dir := t.TempDir()
+
+	var recorded, replayed []receipt
+	original := newReceivingStub(t, &recorded)
+	headers := map[string]string{"User-Agent": "Festerize/test", "Authorization": "Bearer s3cret", "X-Api-Key": "k3y"}
+	for _, path := range []string{TestDirUnFester + "/ballin.csv", TestDirUnFester + "/chase.csv"} {
+		if _, _, err := uploadCSV(context.Background(), path, original.URL+"/collections",
+			UploadOptions{IIIFVersion: "3", Headers: headers, RecordRequestsDir: dir}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	requests, err := ReadRecordedRequests(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, requests, 2)
+	assert.Empty(t, requests[0].Header.Get("Authorization"))
+
+	target := newReceivingStub(t, &replayed)
+	results := ReplayRequests(context.Background(), target.Client(), target.URL+"/", requests, "s3cret",
+		map[string]string{"X-Api-Key": "k3y"})
+
+	if assert.Len(t, results, 2) {
+		assert.True(t, results[0].Succeeded())
+		assert.True(t, results[1].Succeeded())
+		assert.True(t, strings.HasSuffix(results[0].Name, "-ballin.csv"))
+	}
+	assert.Equal(t, recorded, replayed)
+	ballin, _ := os.ReadFile(TestDirUnFester + "/ballin.csv")
+	assert.Equal(t, receipt{"/collections", "Bearer s3cret", "k3y", string(ballin), "v3"}, replayed[0])
+}
+
+// TestReplayRequestsRejected tests that a request the server rejects is reported with Fester's error
+func TestReplayRequestsRejected(t *testing.T) {
+	page, err := os.ReadFile(TestDirErrorPages + "/fester-error.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(page)
+	}))
+	defer ts.Close()
+
+	requests := []RecordedRequest{{Name: "0001-ballin.csv", Method: http.MethodPost, Target: "/collections",
+		Header: http.Header{}, Body: []byte("body")}}
+	results := ReplayRequests(context.Background(), ts.Client(), ts.URL, requests, "", nil)
+
+	if assert.Len(t, results, 1) {
+		assert.False(t, results[0].Succeeded())
+		assert.Equal(t, http.StatusBadRequest, results[0].StatusCode)
+		assert.ErrorContains(t, results[0].Err, "has not been festerized")
+	}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, writeReplayResults(&buffer, results))
+	assert.Contains(t, buffer.String(), "0001-ballin.csv")
+	assert.Contains(t, buffer.String(), "400 Bad Request")
+}
+
+// TestReadRecordedRequestsMissing tests that a directory without recorded requests is an error
+func TestReadRecordedRequestsMissing(t *testing.T) {
+	_, err := ReadRecordedRequests(t.TempDir())
+	assert.ErrorContains(t, err, "no recorded requests")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "0001-ballin.csv.body"), []byte("body"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadRecordedRequests(dir)
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// reportFormatVersion is the version of the JSON report format; bump it when the layout changes
+const reportFormatVersion = 2
+
+// legacyReport holds the fields of a version 1 report that later versions replaced: it named a single server
+type legacyReport struct {
+	Server string `json:"server"`
+}
+
+// Outcomes of processing a single file
+const (
+	StatusSuccess string = "success"
+	StatusFailure string = "failure"
+	StatusSkipped string = "skipped"
+)
+
+// FileResult records the outcome of processing a single file
+type FileResult struct {
+	Filename        string   `json:"filename"`
+	Path            string   `json:"path"`
+	Status          string   `json:"status"`
+	StatusCode      int      `json:"status_code,omitempty"`
+	DurationSeconds float64  `json:"duration_seconds,omitempty"`
+	ManifestURLs    []string `json:"manifest_urls,omitempty"`
+	Error           string   `json:"error,omitempty"`
+	Inputs          []string `json:"inputs,omitempty"`
+}
+
+// Report is the JSON summary of a festerize run
+type Report struct {
+	FormatVersion    int               `json:"format_version"`
+	FesterizeVersion string            `json:"festerize_version"`
+	Servers          []string          `json:"servers"`
+	Tags             map[string]string `json:"tags,omitempty"`
+	Files            []FileResult      `json:"files"`
+}
+
+// NewReport creates a report for the supplied results, with any credentials in the server URLs scrubbed
+func NewReport(results []FileResult) Report {
+	return Report{
+		FormatVersion:    reportFormatVersion,
+		FesterizeVersion: festerizeVersion,
+		Servers:          redactAll(servers),
+		Tags:             tags,
+		Files:            results,
+	}
+}
+
+// WriteReport writes a report to the supplied path as indented JSON
+func WriteReport(path string, report Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadReport reads a report from the supplied path and checks that its format is supported
+func ReadReport(path string) (Report, error) {
+	var report Report
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+
+	// A version 1 report only differs in naming its single server
+	if report.FormatVersion == 1 {
+		var legacy legacyReport
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return report, fmt.Errorf("invalid report %s: %w", path, err)
+		}
+		if legacy.Server != "" {
+			report.Servers = []string{legacy.Server}
+		}
+		report.FormatVersion = reportFormatVersion
+	}
+	if report.FormatVersion != reportFormatVersion {
+		return report, fmt.Errorf("incompatible report format version %d (expected %d)",
+			report.FormatVersion, reportFormatVersion)
+	}
+	return report, nil
+}
+
+// FailedFiles returns the paths of the files that failed in the supplied report, or of the inputs a failed merged
+// upload was made from; skipped files were left out on purpose, so they aren't retried, and a CSV piped to
+// festerize can't be read again
+func FailedFiles(report Report) []string {
+	var failed []string
+	for _, result := range report.Files {
+		if result.Status != StatusFailure {
+			continue
+		}
+		paths := result.Inputs
+		if len(paths) == 0 {
+			paths = []string{result.Path}
+		}
+		for _, path := range paths {
+			if path != stdinSource {
+				failed = append(failed, path)
+			}
+		}
+	}
+	return failed
+}
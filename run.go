@@ -0,0 +1,832 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Config holds the settings of a festerize run
+type Config struct {
+	Sources                []string
+	Servers                []string
+	OutputDir              string
+	IIIFVersion            string
+	IIIFHost               string
+	MetadataUpdate         bool
+	StrictMode             bool
+	StrictCSV              bool
+	StrictServer           bool
+	StrictCompat           bool
+	MaxFailures            int
+	FailureMode            string
+	MirrorPolicy           string
+	OutputTemplate         string
+	MirrorTree             bool
+	CollisionPolicy        string
+	Since                  time.Time
+	AutoOrder              bool
+	AskToConfirm           bool
+	StdinIsTerminal        bool
+	In                     io.Reader
+	StdinName              string
+	NoOutput               bool
+	DiffSummary            bool
+	SummaryTable           bool
+	Preview                int
+	Quiet                  bool
+	SaveErrorsDir          string
+	MaxRuntime             time.Duration
+	OnConflict             string
+	PreHook                []string
+	PostHook               []string
+	Lock                   bool
+	CountOnly              bool
+	Merge                  bool
+	MergeName              string
+	Production             *regexp.Regexp
+	ConfirmServer          string
+	AssumeYes              bool
+	VerifyOutput           bool
+	DedupeOutput           bool
+	PrettyErrors           bool
+	Limit                  int
+	Shuffle                bool
+	Seed                   int64
+	VerifyWorkers          int
+	Input                  CSVInput
+	ARKPrefix              string
+	KeepCollectionRows     bool
+	FixObjectTypes         bool
+	SortRows               bool
+	SortKey                string
+	CollectionName         string
+	NormalizeDelimiter     bool
+	CanonicalHeader        bool
+	Schema                 *Schema
+	ValidateCSV            bool
+	ValidateARKs           bool
+	MultiValueColumns      []string
+	MultiValueSeparator    string
+	MaxCellLength          int
+	WarnUnknownObjectTypes bool
+	Upload                 UploadOptions
+	Headers                map[string]string
+	ReportFile             string
+	MetricsFile            string
+	StartTime              time.Time
+}
+
+// strictValidation checks whether a file that fails the local checks of its content stops the run
+func (cfg Config) strictValidation() bool {
+	return cfg.StrictMode || cfg.StrictCSV
+}
+
+// strictServer checks whether a file that Fester rejects, or that it can't be reached for, stops the run
+func (cfg Config) strictServer() bool {
+	return cfg.StrictMode || cfg.StrictServer
+}
+
+// configFromFlags builds the run's configuration from the command line flags
+func configFromFlags() Config {
+	// HTTP request headers
+	requestHeaders := map[string]string{
+		"User-Agent": userAgent(),
+	}
+	for key, value := range extraHeaders {
+		requestHeaders[key] = value
+	}
+
+	return Config{
+		Sources:                src,
+		Servers:                servers,
+		OutputDir:              out,
+		IIIFVersion:            iiifApiVersion,
+		IIIFHost:               iiifhost,
+		MetadataUpdate:         metadata,
+		StrictMode:             strictMode,
+		StrictCSV:              strictValidation,
+		StrictServer:           strictServer,
+		StrictCompat:           strictCompat,
+		MaxFailures:            maxFailures,
+		FailureMode:            failureMode,
+		MirrorPolicy:           mirrorPolicy,
+		OutputTemplate:         outputTemplate,
+		MirrorTree:             mirrorTree,
+		CollisionPolicy:        collisionPolicy,
+		Since:                  sinceThreshold,
+		AutoOrder:              autoOrder,
+		AskToConfirm:           interactive && !assumeYes,
+		StdinIsTerminal:        isTerminal(os.Stdin),
+		In:                     os.Stdin,
+		StdinName:              stdinName,
+		NoOutput:               noOutput,
+		DiffSummary:            diffSummary,
+		SummaryTable:           summaryTable,
+		Preview:                preview,
+		Quiet:                  quiet,
+		SaveErrorsDir:          saveErrorsDir,
+		MaxRuntime:             maxRuntime,
+		OnConflict:             onConflict,
+		PreHook:                ParseHook(preHook),
+		PostHook:               ParseHook(postHook),
+		Lock:                   !noLock,
+		CountOnly:              countOnlyMode,
+		Merge:                  merge,
+		MergeName:              mergeName,
+		Production:             productionPattern,
+		ConfirmServer:          confirmServer,
+		AssumeYes:              assumeYes,
+		VerifyOutput:           verifyOutputMode,
+		DedupeOutput:           dedupeOutput,
+		PrettyErrors:           prettyErrors,
+		Limit:                  fileLimit,
+		Shuffle:                shuffle,
+		Seed:                   shuffleSeed(seed, rootCmd.Flags().Changed("seed")),
+		VerifyWorkers:          verifyConcurrency,
+		Input:                  inputFromFlags(),
+		ARKPrefix:              arkPrefix,
+		KeepCollectionRows:     keepCollectionRows,
+		FixObjectTypes:         fixObjectTypes,
+		SortRows:               sortRows,
+		SortKey:                sortKey,
+		CollectionName:         collectionName,
+		NormalizeDelimiter:     normalizeDelimiter,
+		CanonicalHeader:        canonicalHeader,
+		Schema:                 columnSchema,
+		ValidateCSV:            validateCSV,
+		ValidateARKs:           validateARKs,
+		MultiValueColumns:      multiValueColumns,
+		MultiValueSeparator:    multiValueSeparator,
+		MaxCellLength:          maxCellLength,
+		WarnUnknownObjectTypes: warnUnknownObjectTypes,
+		Upload: UploadOptions{
+			SplitByType:       splitByType,
+			ChunkRows:         chunkRows,
+			Compress:          compressUpload,
+			Idempotency:       idempotency,
+			UploadName:        uploadNameOverride,
+			PartContentType:   partContentType,
+			RecordRequestsDir: recordRequestsDir,
+			LogHeaders:        loggedResponseHeaders,
+			MaxRate:           maxUploadRate,
+			Trace:             traceUploads,
+		},
+		Headers:     requestHeaders,
+		ReportFile:  reportFile,
+		MetricsFile: metricsFile,
+		StartTime:   startTime,
+	}
+}
+
+// uploadOptions returns the settings to upload a file with under the supplied IIIF host, counting the bytes
+// sent in uploaded
+func (cfg Config) uploadOptions(iiifHost string, uploaded *atomic.Int64) UploadOptions {
+	opts := cfg.Upload
+	opts.IIIFVersion, opts.IIIFHost, opts.MetadataUpdate, opts.Headers = cfg.IIIFVersion, iiifHost, cfg.MetadataUpdate,
+		cfg.Headers
+	opts.Input, opts.Transforms, opts.UploadedBytes = cfg.Input, cfg.transforms(), uploaded
+	return opts
+}
+
+// uploadErrorCode returns the exit code for an upload that failed without a response from Fester: connection
+// problems mean Fester is unavailable, while anything else went wrong preparing the request
+func uploadErrorCode(err error) FesterizeError {
+	var urlErr *url.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return INTERRUPTED
+	case errors.Is(err, context.DeadlineExceeded):
+		return DEADLINE_EXCEEDED
+	case errors.As(err, &urlErr):
+		return FESTER_UNAVAILABLE
+	default:
+		return FESTER_ERROR_RESPONSE
+	}
+}
+
+// run festerizes the configured files and returns the exit code and the outcome of each file processed,
+// along with the error that caused the exit code when it isn't zero
+func run(ctx context.Context, cfg Config) (int, []FileResult, error) {
+	// Counting the files touches nothing but the file system
+	if cfg.CountOnly {
+		exitCode, err := countOnly(cfg)
+		return exitCode, nil, err
+	}
+
+	// The outcome of each file, in the order they're processed, and the bytes uploaded for them
+	var results []FileResult
+	var uploaded atomic.Int64
+	var stdinPath string
+	var mergedInputs []string
+	reportedPath := func(path string) string {
+		// The buffered copy of stdin is removed when the run ends, and what was piped can't be read again
+		if absPath, _ := filepath.Abs(path); stdinPath != "" && absPath == stdinPath {
+			return stdinSource
+		}
+		return path
+	}
+	recordResult := func(result FileResult, err error) {
+		result.Filename = filepath.Base(result.Path)
+		result.Path = reportedPath(result.Path)
+		if mergedInputs != nil {
+			// The merged file is removed when the run ends, so it's the inputs that can be found and retried
+			result.Path, result.Inputs = "", mergedInputs
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+		emitResult(result)
+	}
+
+	// The time budget covers the whole run, from when festerize started
+	if cfg.MaxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, cfg.StartTime.Add(cfg.MaxRuntime))
+		defer cancel()
+	}
+
+	sources := cfg.Sources
+	overwritePolicy := OverwritePrompt
+
+	// Read a piped CSV before anything else reads stdin
+	if hasStdinSource(sources) {
+		path, remove, err := BufferStdin(cfg.In, cfg.StdinName)
+		if err != nil {
+			Logger.Error("Error reading CSV from stdin", zap.Error(err))
+			fmt.Fprintln(humanOutput(), "There was an error reading the CSV from stdin")
+			return int(FILE_IO_ERROR), results, err
+		}
+		defer remove()
+		sources = replaceStdinSource(sources, path)
+		stdinPath, _ = filepath.Abs(path)
+
+		// With stdin used up, nothing is left to answer whether an existing output directory can be used
+		overwritePolicy = OverwriteAllow
+	}
+
+	// Writing to stdout leaves no room to tell several output CSVs apart, unless they're merged into one
+	if cfg.OutputDir == stdoutOutput && len(sources) != 1 && !cfg.Merge {
+		err := errors.New("--out - writes a single CSV to stdout, so exactly one file must be given")
+		Logger.Error("Too many files for stdout output", zap.Int("files", len(sources)))
+		fmt.Fprintln(humanOutput(), err)
+		return int(INVALID_OUTPUT_SPECIFIED), results, err
+	}
+
+	// Make sure a run against production was meant for it before anything is written or uploaded; a piped CSV
+	// leaves nothing to answer the prompt
+	canPrompt := cfg.StdinIsTerminal && !hasStdinSource(cfg.Sources)
+	if err := ConfirmProductionServers(cfg.Servers, cfg.Production, cfg.ConfirmServer, cfg.AssumeYes, cfg.In,
+		humanOutput(), canPrompt); err != nil {
+		Logger.Error("Upload to production server not confirmed", zap.Error(err))
+		fmt.Fprintln(humanOutput(), err)
+		return int(NOT_CONFIRMED), results, err
+	}
+
+	// Create output directory, unless nothing is going to be written to it
+	if cfg.OutputDir != stdoutOutput && !cfg.NoOutput {
+		if err := CreateOutputDir(cfg.OutputDir, cfg.In, overwritePolicy); err != nil {
+			Logger.Error("Error creating output directory",
+				zap.Error(err))
+			fmt.Fprintln(humanOutput(), "There was an error creating an output directory")
+			return int(INVALID_OUTPUT_SPECIFIED), results, err
+		}
+	}
+
+	// Lock the output directory so concurrent runs don't overwrite each other's files
+	if cfg.Lock && cfg.OutputDir != stdoutOutput && !cfg.NoOutput {
+		release, err := AcquireLock(cfg.OutputDir)
+		if err != nil {
+			Logger.Error("Error locking output directory", zap.Error(err))
+			fmt.Fprintln(humanOutput(), err)
+			return int(OUTPUT_LOCKED), results, err
+		}
+		defer release()
+	}
+
+	// Check if Fester is available on every server
+	for _, server := range cfg.Servers {
+		if statusCode, err := FesterStatus(statusURL(server)); err != nil {
+			if statusCode != 0 {
+				Logger.Error("Error connecting to Fester: Unexpected status code",
+					zap.String("server", server),
+					zap.Int("status_code", statusCode),
+				)
+			} else {
+				Logger.Error("Error making HTTP request to Fester",
+					zap.String("server", server),
+					zap.Error(err),
+				)
+			}
+			fmt.Fprintln(humanOutput(), "There was an error connecting to Fester")
+			return int(FESTER_UNAVAILABLE), results, err
+		} else {
+			Logger.Info("Got valid status code connected to Fester",
+				zap.String("server", server),
+				zap.Int("status_code", statusCode),
+			)
+		}
+	}
+
+	// Check that every server runs a version of Fester that works with the requested IIIF version
+	for _, server := range cfg.Servers {
+		if err := checkServerVersion(ctx, server, cfg.IIIFVersion, cfg.Headers); err != nil {
+			if cfg.StrictCompat {
+				Logger.Error("Fester is not known to be compatible",
+					zap.String("server", server),
+					zap.Error(err))
+				fmt.Fprintln(humanOutput(), err)
+				return int(INCOMPATIBLE_SERVER), results, err
+			}
+			Logger.Warn("Fester may not be compatible",
+				zap.String("server", server),
+				zap.Error(err))
+		}
+	}
+
+	if !cfg.Since.IsZero() {
+		sources = FilterSince(sources, cfg.Since)
+	}
+
+	// Shuffle before ordering by dependencies and limiting, so parents still come first and the files left out
+	// are picked at random
+	if cfg.Shuffle {
+		sources = ShuffleFiles(sources, cfg.Seed)
+		Logger.Info("Shuffled files", zap.Int64("seed", cfg.Seed), zap.Strings("files", sources))
+	}
+
+	// The checks before uploading share one read of the files; ordering needs it before the limit is applied,
+	// while the other checks only need the files that are left
+	var batch []BatchFile
+	if cfg.AutoOrder {
+		batch = readBatch(sources, cfg.Input)
+		sources = OrderByDependencies(sources, batch)
+		Logger.Info("Ordered files by their dependencies", zap.Strings("files", sources))
+	}
+
+	// Only upload the first files, once they're filtered and in the order they'd be uploaded
+	if cfg.Limit > 0 {
+		var dropped []string
+		if sources, dropped = LimitFiles(sources, cfg.Limit); len(dropped) > 0 {
+			Logger.Info("Skipping files past the limit",
+				zap.Int("limit", cfg.Limit),
+				zap.Int("skipped", len(dropped)),
+				zap.Strings("files", dropped))
+			fmt.Fprintf(humanOutput(), "Uploading the first %d files; skipping %d more\n", cfg.Limit, len(dropped))
+		}
+	}
+
+	if cfg.AutoOrder {
+		batch = selectBatch(batch, sources)
+	} else if len(sources) > 0 {
+		batch = readBatch(sources, cfg.Input)
+	}
+
+	// Warn about works and pages that Fester will reject because their parents are missing
+	warnAboutOrphans(batch, cfg.MetadataUpdate)
+
+	// Catch rows that would give Fester conflicting manifests
+	if err := checkDuplicateARKs(batch, cfg.strictValidation()); err != nil {
+		Logger.Error("Duplicate Item ARKs in the provided files", zap.Error(err))
+		fmt.Fprintln(humanOutput(), err)
+		return int(INVALID_CSV_SPECIFIED), results, err
+	}
+
+	// Upload the files as one, so that rows can refer to parents in any of them
+	if cfg.Merge && len(sources) > 1 {
+		path, remove, err := MergeCSVs(sources, cfg.MergeName, cfg.Input)
+		if err != nil {
+			Logger.Error("Error merging CSVs", zap.Error(err))
+			fmt.Fprintln(humanOutput(), err)
+			return int(INVALID_CSV_SPECIFIED), results, err
+		}
+		defer remove()
+		Logger.Info("Merged CSVs into one upload",
+			zap.Strings("files", sources),
+			zap.String("name", cfg.MergeName))
+		for _, source := range sources {
+			mergedInputs = append(mergedInputs, reportedPath(source))
+		}
+		sources = []string{path}
+
+		// The merged file is always written with commas, whatever the inputs were delimited with
+		cfg.Input.Delimiter = ','
+	}
+
+	if cfg.AskToConfirm {
+		if err := ConfirmUpload(NewUploadSummary(cfg, sources), cfg.In, humanOutput(), cfg.StdinIsTerminal); err != nil {
+			Logger.Error("Upload not confirmed", zap.Error(err))
+			fmt.Fprintln(humanOutput(), err)
+			return int(NOT_CONFIRMED), results, err
+		}
+	}
+
+	// From here on, the report and metrics are written however the run ends, after the summary table
+	defer func() { finish(cfg, results, uploaded.Load()) }()
+	if cfg.SummaryTable && !cfg.Quiet {
+		defer func() { writeSummaryTable(humanOutput(), results) }()
+	}
+
+	// Output CSVs written so far, keyed by pathKey, and the inputs they came from
+	written := map[string]string{}
+
+	// When mirroring the input tree, output directories are recreated relative to the inputs' common directory
+	var treeRoot string
+	if cfg.MirrorTree {
+		var absPaths []string
+		for _, pathString := range sources {
+			if absPath, err := filepath.Abs(pathString); err == nil {
+				absPaths = append(absPaths, absPath)
+			}
+		}
+		treeRoot = commonDir(absPaths)
+	}
+
+	// Files uploaded before their parents, which are given a second pass once the other files are done
+	deferred := map[string]bool{}
+
+	// Removes the temporary CSV the pre-hook wrote for the file being processed, once it's done with
+	removeHooked := func() {}
+	defer func() { removeHooked() }()
+
+	// Deferred files are appended to the sources, so they're counted against them afresh on every pass
+	for index := 0; index < len(sources); index++ {
+		pathString := sources[index]
+
+		// The previous file is done with, however its iteration ended
+		removeHooked()
+		removeHooked = func() {}
+
+		// Don't start any more uploads once the run has run out of time or been cancelled
+		if err := ctx.Err(); errors.Is(err, context.DeadlineExceeded) {
+			Logger.Error("Stopping after reaching the maximum runtime",
+				zap.Duration("max_runtime", cfg.MaxRuntime),
+				zap.Int("unprocessed", len(sources)-index))
+			fmt.Fprintf(humanOutput(), "Stopping because the run took longer than %s; %d files were not processed\n",
+				cfg.MaxRuntime, len(sources)-index)
+			return int(DEADLINE_EXCEEDED), results, err
+		} else if err != nil {
+			Logger.Warn("Run cancelled before all files were uploaded", zap.Error(err))
+			fmt.Fprintln(humanOutput(), "Festerize was interrupted before all files were uploaded")
+			return int(INTERRUPTED), results, err
+		}
+
+		// Give up once too many files have failed
+		if err := FailureLimitReached(results, cfg.MaxFailures, cfg.FailureMode); err != nil {
+			Logger.Error("Stopping after too many failures",
+				zap.Error(err),
+				zap.Int("unprocessed", len(sources)-index))
+			fmt.Fprintf(humanOutput(), "Stopping because %s; %d files were not processed\n", err, len(sources)-index)
+			return int(TOO_MANY_FAILURES), results, err
+		}
+
+		// Convert the path string to an absolute path
+		absPath, err := filepath.Abs(pathString)
+		filename := filepath.Base(absPath)
+		if err != nil {
+			Logger.Error("Error getting absolute path",
+				zap.Error(err))
+			printFailure("There was an error getting the absolute path of the CSV\n")
+			recordResult(FileResult{Path: pathString, Status: StatusFailure}, err)
+			if cfg.StrictMode {
+				return int(FILE_IO_ERROR), results, err
+			}
+			continue
+		}
+
+		if _, err := os.Stat(absPath); os.IsNotExist(err) {
+			Logger.Error("File does not exist",
+				zap.String("filename", filename),
+				zap.Error(err),
+			)
+			printFailure("%s does not exist\n", filename)
+			recordResult(FileResult{Path: absPath, Status: StatusFailure}, err)
+			if cfg.StrictMode {
+				return int(NONEXISTENT_FILE_SPECIFIED), results, err
+			}
+		} else if IsCSVFile(filename) {
+			// Let the pre-hook prepare the CSV that's checked and uploaded
+			uploadPath := absPath
+			if len(cfg.PreHook) > 0 {
+				hookedPath, remove, err := RunPreHook(ctx, cfg.PreHook, absPath, hookEnv(filename, absPath, "", "", 0))
+				if err != nil {
+					printFailure("The pre-hook failed for %s and it was not uploaded\n", filename)
+					recordResult(FileResult{Path: absPath, Status: StatusFailure}, err)
+					if cfg.StrictMode {
+						return int(HOOK_FAILED), results, err
+					}
+					continue
+				}
+				removeHooked = remove
+				uploadPath = hookedPath
+			}
+
+			if cfg.Input.DetectEncoding {
+				logEncoding(uploadPath, filename)
+			}
+
+			if problems, err := ValidateFile(uploadPath, cfg); err != nil || len(problems) > 0 {
+				if err != nil {
+					Logger.Error("Error reading CSV for validation",
+						zap.String("filename", filename),
+						zap.Error(err))
+				}
+				for _, problem := range problems {
+					Logger.Error("Invalid CSV content",
+						zap.String("filename", filename),
+						zap.Int("row", problem.Row),
+						zap.String("column", problem.Column),
+						zap.String("value", problem.Value),
+						zap.String("reason", problem.Reason))
+				}
+				printFailure("%s failed validation and was not uploaded\n", filename)
+				if err == nil {
+					err = fmt.Errorf("%d validation problems, first: %s", len(problems), problems[0])
+				}
+				recordResult(FileResult{Path: absPath, Status: StatusFailure}, err)
+				if cfg.strictValidation() {
+					return int(INVALID_CSV_SPECIFIED), results, err
+				}
+				continue
+			}
+
+			// Only upload the rows under the ARK prefix, and none of a file without any
+			if cfg.ARKPrefix != "" {
+				err := countARKPrefixRows(uploadPath, filename, cfg.ARKPrefix, cfg.KeepCollectionRows, cfg.Input)
+				if errors.Is(err, errNoMatchingRows) {
+					Logger.Warn("Skipping file without rows matching the ARK prefix",
+						zap.String("filename", filename),
+						zap.String("ark_prefix", cfg.ARKPrefix))
+					fmt.Fprintf(humanOutput(), "%s has no rows with an Item ARK starting with %s; skipping it\n",
+						filename, cfg.ARKPrefix)
+					recordResult(FileResult{Path: absPath, Status: StatusSkipped}, err)
+					continue
+				} else if err != nil {
+					Logger.Error("Error filtering rows by ARK prefix",
+						zap.String("filename", filename),
+						zap.Error(err))
+					printFailure("%s was not uploaded: %v\n", filename, err)
+					recordResult(FileResult{Path: absPath, Status: StatusFailure}, err)
+					if cfg.strictValidation() {
+						return int(INVALID_CSV_SPECIFIED), results, err
+					}
+					continue
+				}
+			}
+
+			// A CSV can name the host of its own images, in place of --iiifhost
+			iiifHost, err := fileIIIFHost(uploadPath, cfg.IIIFHost, cfg.Input)
+			if err != nil {
+				Logger.Error("Error reading IIIF host from CSV",
+					zap.String("filename", filename),
+					zap.Error(err))
+				printFailure("%s was not uploaded: %v\n", filename, err)
+				recordResult(FileResult{Path: absPath, Status: StatusFailure}, err)
+				if cfg.strictValidation() {
+					return int(INVALID_CSV_SPECIFIED), results, err
+				}
+				continue
+			}
+			if iiifHost != cfg.IIIFHost {
+				Logger.Info("Using IIIF host from CSV",
+					zap.String("filename", filename),
+					zap.String("iiif_host", iiifHost))
+			}
+
+			Logger.Info("Uploading file to Fester",
+				zap.String("filename", filename),
+				zap.Strings("servers", cfg.Servers))
+			serverResults := uploadToServers(ctx, uploadPath, cfg.Servers, cfg.uploadOptions(iiifHost, &uploaded))
+
+			// Use the response that the output CSV is taken from, or the one that explains the failure
+			uploaded := MirrorSucceeded(serverResults, cfg.MirrorPolicy)
+			selected := failedResult(serverResults)
+			if uploaded {
+				selected = successfulResult(serverResults)
+			}
+			response, responseBody, err := selected.Response, selected.Body, selected.Err
+			result := FileResult{Path: absPath, Status: StatusFailure, DurationSeconds: uploadDuration(serverResults).Seconds()}
+			if response != nil {
+				result.StatusCode = response.StatusCode
+			}
+			if uploaded {
+				Logger.Info("File was uploaded to Fester succesfully",
+					zap.String("filename", filename),
+				)
+
+				// Save the result CSV, to stdout or to the output directory, unless it isn't wanted
+				var outputPath string
+				if cfg.NoOutput {
+					Logger.Info("Not saving festerized CSV", zap.String("filename", filename))
+				} else if cfg.OutputDir == stdoutOutput {
+					if _, err := os.Stdout.Write(responseBody); err != nil {
+						Logger.Error("Error writing to stdout", zap.Error(err))
+						printFailure("There was an error writing the festerized version of %s to stdout\n", filename)
+						recordResult(result, err)
+						return int(FILE_IO_ERROR), results, err
+					}
+				} else {
+					// Don't overwrite an output CSV from earlier in the run
+					csvDir := outputDir(cfg.OutputDir, treeRoot, absPath)
+					csvPath := filepath.Join(csvDir, OutputFilename(cfg.OutputTemplate, filename, cfg.StartTime))
+					if csvPath, err = claimOutput(written, csvPath, absPath, cfg.CollisionPolicy); err != nil {
+						Logger.Error("Output filename collision",
+							zap.String("filename", filename),
+							zap.Error(err))
+						printFailure("The festerized version of %s would overwrite an earlier output file\n", filename)
+						recordResult(result, err)
+						if cfg.StrictMode {
+							return int(FILE_IO_ERROR), results, err
+						}
+						continue
+					}
+
+					if err := os.MkdirAll(csvDir, os.ModePerm); err != nil {
+						Logger.Error("Error creating output directory", zap.Error(err))
+						printFailure("There was an error creating the festerized version of %s\n", filename)
+						recordResult(result, err)
+						if cfg.StrictMode {
+							return int(FILE_IO_ERROR), results, err
+						}
+						continue
+					}
+
+					// Leave an identical output CSV from an earlier run alone, so its modification time is kept
+					unchanged := false
+					if cfg.DedupeOutput {
+						var compareErr error
+						if unchanged, compareErr = outputUnchanged(csvPath, responseBody); compareErr != nil {
+							Logger.Warn("Could not compare with existing output CSV",
+								zap.String("filename", filename),
+								zap.Error(compareErr))
+						}
+					}
+
+					if unchanged {
+						Logger.Info("Festerized CSV unchanged, not rewriting it",
+							zap.String("filename", filename),
+							zap.String("path", csvPath))
+					} else {
+						file, err := os.Create(csvPath)
+						if err != nil {
+							Logger.Error("Error creating file", zap.Error(err))
+							printFailure("There was an error creating the festerized version of %s\n", filename)
+							recordResult(result, err)
+							if cfg.StrictMode {
+								return int(FILE_IO_ERROR), results, err
+							}
+							continue
+						}
+
+						// Close the file before moving on, so a large batch doesn't run out of file descriptors
+						_, err = file.Write(responseBody)
+						if closeErr := file.Close(); err == nil {
+							err = closeErr
+						}
+						if err != nil {
+							Logger.Error("Error writing to file", zap.Error(err))
+							printFailure("There was an error writing to %s\n", filename)
+							recordResult(result, err)
+							if cfg.StrictMode {
+								return int(FILE_IO_ERROR), results, err
+							}
+							continue
+						}
+					}
+					outputPath = csvPath
+				}
+
+				// Hand the saved CSV on to the post-hook
+				if len(cfg.PostHook) > 0 && outputPath != "" {
+					vars := hookEnv(filename, absPath, StatusSuccess, selected.Server, result.StatusCode)
+					if err := RunPostHook(ctx, cfg.PostHook, outputPath, vars); err != nil {
+						printFailure("The post-hook failed for the festerized version of %s\n", filename)
+						recordResult(result, err)
+						if cfg.StrictMode {
+							return int(HOOK_FAILED), results, err
+						}
+						continue
+					}
+				}
+
+				manifestURLs, err := ManifestURLs(responseBody)
+				if err != nil {
+					Logger.Warn("Could not read manifest URLs from Fester's response",
+						zap.String("filename", filename),
+						zap.Error(err))
+				}
+
+				// Check that the manifests Fester says it made can be retrieved
+				if cfg.VerifyOutput && len(manifestURLs) > 0 {
+					err := verifyOutput(ctx, filename, selected.Server, manifestURLs, cfg.VerifyWorkers, cfg.Headers)
+					if err != nil {
+						printFailure("Some manifests for %s could not be retrieved: %v\n", filename, err)
+						result.ManifestURLs = manifestURLs
+						recordResult(result, err)
+						if cfg.strictServer() {
+							return int(MANIFEST_UNAVAILABLE), results, err
+						}
+						continue
+					}
+				}
+
+				result.Status, result.ManifestURLs = StatusSuccess, manifestURLs
+				recordResult(result, nil)
+
+				if cfg.DiffSummary {
+					logDiffSummary(uploadPath, responseBody, cfg.Input, cfg.transforms())
+				}
+				if cfg.Preview > 0 {
+					previewCSV(os.Stderr, filename, responseBody, cfg.Preview)
+				}
+
+				// Keep piped output clean CSV
+				if cfg.OutputDir != stdoutOutput && !cfg.Quiet {
+					extraSatisfaction := []string{"🎉", "🎊", "✨", "💯", "😎", "✔️ ", "👍"} // Add more awesome characters if needed
+
+					// Frame the message in a randomly chosen emoji
+					borderChar := extraSatisfaction[rand.Intn(len(extraSatisfaction))]
+					printSuccess(successBanner(borderChar, "SUCCESS! Uploaded "+filename))
+				}
+			} else {
+				if err != nil {
+					Logger.Error("There was an error creating and posting the request: ", zap.Error(err))
+					printFailure("There was an error creating and posting the request for %s\n", filename)
+					if cfg.PrettyErrors {
+						writeErrorSummary(os.Stderr, summarizeError(filename, result.StatusCode, err))
+					}
+					recordResult(result, err)
+					if cfg.strictServer() {
+						return int(uploadErrorCode(err)), results, err
+					}
+					continue
+				}
+
+				// Keep the whole error page for debugging
+				saveErrorBody(cfg.SaveErrorsDir, absPath, response, responseBody)
+
+				festerError, err := ParseFesterError(responseBody)
+				if err != nil {
+					Logger.Error("Failed to parse error HTML",
+						zap.Error(err))
+					recordResult(result, err)
+					continue
+				}
+				// Log error response
+				fields := append([]zap.Field{zap.String("filename", filename)}, festerError.zapFields()...)
+
+				// Give a file that came before its parents another chance, or leave it be, if asked to
+				if IsOrderingError(result.StatusCode, festerError) {
+					switch {
+					case cfg.OnConflict == ConflictDefer && !deferred[absPath]:
+						deferred[absPath] = true
+						sources = append(sources, pathString)
+						Logger.Warn("Deferring file uploaded before its parents", fields...)
+						fmt.Fprintf(humanOutput(), "%s was uploaded before its parents; trying it again at the end\n", filename)
+						continue
+					case cfg.OnConflict == ConflictSkip:
+						Logger.Warn("Skipping file uploaded before its parents", fields...)
+						fmt.Fprintf(humanOutput(), "%s was uploaded before its parents; skipping it\n", filename)
+						result.Status = StatusSkipped
+						recordResult(result, festerError)
+						continue
+					}
+				}
+
+				Logger.Error("Failed to upload file to Fester", fields...)
+				if cfg.PrettyErrors {
+					writeErrorSummary(os.Stderr, summarizeError(filename, result.StatusCode, festerError))
+				}
+				recordResult(result, festerError)
+				if cfg.strictServer() {
+					return int(FESTER_ERROR_RESPONSE), results, festerError
+				}
+			}
+		} else {
+			Logger.Error("This file is not a CSV file",
+				zap.String("filename", filename))
+			printFailure("%s is not a CSV", filename)
+			err := errors.New("not a CSV file")
+			recordResult(FileResult{Path: absPath, Status: StatusFailure}, err)
+			if cfg.StrictMode {
+				return int(NON_CSV_FILE_SPECIFIED), results, err
+			}
+		}
+	}
+
+	Logger.Info("Batch finished",
+		zap.Int("files", len(results)),
+		zap.Duration("duration", time.Since(cfg.StartTime)))
+	return 0, results, nil
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runHarness is what a run test needs: a Fester stub to upload to, and what the run logged and printed
+type runHarness struct {
+	server *httptest.Server
+	log    *MemorySink
+	stdout *capturedStdout
+}
+
+// newRunHarness installs a test logger, captures stdout and starts a Fester stub that reports it's available and
+// hands everything else to the supplied upload handler (which answers 201 Created when nil)
+func newRunHarness(t *testing.T, upload http.HandlerFunc) *runHarness {
+	logger, sink := createLogger()
+	Logger = logger
+
+	return &runHarness{
+		server: newStatusStub(t, http.StatusOK, upload),
+		log:    sink,
+		stdout: redirectStdoutToBuffer(t),
+	}
+}
+
+// newStatusStub starts a Fester stub that answers its status endpoint with the supplied status and hands everything
+// else to the supplied upload handler (which answers 201 Created when nil)
+func newStatusStub(t *testing.T, statusCode int, upload http.HandlerFunc) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fester/status" {
+			w.WriteHeader(statusCode)
+			return
+		}
+		if upload == nil {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		upload(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// respondWith returns an upload handler that answers every upload with the supplied status and body
+func respondWith(uploadCode int, uploadBody string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(uploadCode)
+		w.Write([]byte(uploadBody))
+	}
+}
+
+// newRunStub starts a Fester stub that reports the supplied status and answers uploads with the supplied response
+func newRunStub(t *testing.T, statusCode, uploadCode int, uploadBody string) *httptest.Server {
+	return newStatusStub(t, statusCode, respondWith(uploadCode, uploadBody))
+}
+
+// testConfig returns a run configuration that uploads the supplied files to the supplied server
+func testConfig(t *testing.T, server string, sources ...string) Config {
+	return Config{
+		Sources:         sources,
+		Servers:         []string{server},
+		OutputDir:       filepath.Join(t.TempDir(), "output"),
+		IIIFVersion:     "2",
+		MirrorPolicy:    MirrorPolicyAll,
+		OutputTemplate:  defaultOutputTemplate,
+		CollisionPolicy: CollisionPolicyError,
+		Lock:            true,
+		Headers:         map[string]string{"User-Agent": userAgent()},
+		StartTime:       time.Now(),
+		In:              strings.NewReader(""),
+	}
+}
+
+// TestRunExitCodes tests the exit codes of runs that stop early
+func TestRunExitCodes(t *testing.T) {
+	newRunHarness(t, nil)
+
+	notCSV := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(notCSV, []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	errorPage := `<html><body><p id="error-message">Collection not found</p></body></html>`
+
+	tests := []struct {
+		name       string
+		statusCode int
+		uploadCode int
+		strict     bool
+		source     string
+		exitCode   FesterizeError
+	}{
+		{"Fester unavailable", http.StatusServiceUnavailable, http.StatusCreated, false, TestDirUnFester + "/ballin.csv", FESTER_UNAVAILABLE},
+		{"Missing file", http.StatusOK, http.StatusCreated, true, TestDirUnFester + "/missing.csv", NONEXISTENT_FILE_SPECIFIED},
+		{"Not a CSV", http.StatusOK, http.StatusCreated, true, notCSV, NON_CSV_FILE_SPECIFIED},
+		{"Fester error", http.StatusOK, http.StatusInternalServerError, true, TestDirUnFester + "/ballin.csv", FESTER_ERROR_RESPONSE},
+		{"Fester error without strict mode", http.StatusOK, http.StatusInternalServerError, false, TestDirUnFester + "/ballin.csv", 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newRunStub(t, tc.statusCode, tc.uploadCode, errorPage)
+			cfg := testConfig(t, ts.URL, tc.source)
+			cfg.StrictMode = tc.strict
+
+			exitCode, _, err := run(context.Background(), cfg)
+
+			assert.Equal(t, int(tc.exitCode), exitCode)
+			assert.Equal(t, tc.exitCode != 0, err != nil)
+			assert.NoFileExists(t, filepath.Join(cfg.OutputDir, lockFilename))
+		})
+	}
+}
+
+// TestRunGranularStrictness tests that --strict-validation only stops at files that fail local checks, and
+// --strict-server only at files that Fester rejects
+func TestRunGranularStrictness(t *testing.T) {
+	newRunHarness(t, nil)
+
+	invalid := []string{TestDirMalformed + "/ragged.csv", TestDirUnFester + "/chase.csv"}
+	valid := []string{TestDirUnFester + "/ballin.csv", TestDirUnFester + "/chase.csv"}
+	tests := []struct {
+		name             string
+		uploadCode       int
+		sources          []string
+		strictValidation bool
+		strictServer     bool
+		exitCode         FesterizeError
+		processed        int
+	}{
+		{"Invalid CSV with strict validation", http.StatusCreated, invalid, true, false, INVALID_CSV_SPECIFIED, 1},
+		{"Invalid CSV with strict server", http.StatusCreated, invalid, false, true, 0, 2},
+		{"Fester error with strict server", http.StatusInternalServerError, valid, false, true, FESTER_ERROR_RESPONSE, 1},
+		{"Fester error with strict validation", http.StatusInternalServerError, valid, true, false, 0, 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newRunStub(t, http.StatusOK, tc.uploadCode, "<html><body><p id=\"error-message\">Boom</p></body></html>")
+			cfg := testConfig(t, ts.URL, tc.sources...)
+			cfg.ValidateCSV = true
+			cfg.StrictCSV, cfg.StrictServer = tc.strictValidation, tc.strictServer
+
+			exitCode, results, err := run(context.Background(), cfg)
+
+			assert.Equal(t, int(tc.exitCode), exitCode)
+			assert.Equal(t, tc.exitCode != 0, err != nil)
+			assert.Len(t, results, tc.processed)
+		})
+	}
+}
+
+// TestRunSuccess tests that a successful run writes the festerized CSV and records the result
+func TestRunSuccess(t *testing.T) {
+	newRunHarness(t, nil)
+
+	festerized, err := os.ReadFile(TestDirFester + "/ballin.csv")
+	assert.Nil(t, err)
+
+	for _, uploadCode := range []int{http.StatusCreated, http.StatusOK} {
+		t.Run(http.StatusText(uploadCode), func(t *testing.T) {
+			ts := newRunStub(t, http.StatusOK, uploadCode, string(festerized))
+			cfg := testConfig(t, ts.URL, TestDirUnFester+"/ballin.csv")
+
+			exitCode, results, err := run(context.Background(), cfg)
+
+			assert.Equal(t, 0, exitCode)
+			assert.Nil(t, err)
+			written, err := os.ReadFile(filepath.Join(cfg.OutputDir, "ballin.csv"))
+			assert.Nil(t, err)
+			assert.Equal(t, string(festerized), string(written))
+			if assert.Len(t, results, 1) {
+				assert.Equal(t, StatusSuccess, results[0].Status)
+				assert.Equal(t, uploadCode, results[0].StatusCode)
+			}
+		})
+	}
+}
+
+// TestRunCancelled tests that a cancelled run stops before uploading anything
+func TestRunCancelled(t *testing.T) {
+	h := newRunHarness(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	exitCode, results, err := run(ctx, testConfig(t, h.server.URL, TestDirUnFester+"/ballin.csv"))
+
+	assert.Equal(t, int(INTERRUPTED), exitCode)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, results)
+}
+
+// TestRunMaxRuntime tests that a run that takes too long cancels the upload in progress, leaves the rest of the
+// files unprocessed, and still writes its report
+func TestRunMaxRuntime(t *testing.T) {
+	release := make(chan struct{})
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(10 * time.Second):
+		}
+	})
+	defer close(release)
+
+	cfg := testConfig(t, h.server.URL, TestDirUnFester+"/ballin.csv", TestDirUnFester+"/chandler.csv")
+	cfg.MaxRuntime = 200 * time.Millisecond
+	cfg.ReportFile = filepath.Join(t.TempDir(), "report.json")
+
+	exitCode, results, err := run(context.Background(), cfg)
+
+	assert.Equal(t, int(DEADLINE_EXCEEDED), exitCode)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(cfg.StartTime), 2*time.Second)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, StatusFailure, results[0].Status)
+	}
+	assert.Contains(t, h.log.String(), `"unprocessed":1`)
+	assert.FileExists(t, cfg.ReportFile)
+}
+
+// TestUploadErrorCode tests that failed connections are told apart from other upload errors
+func TestUploadErrorCode(t *testing.T) {
+	connectionErr := &url.Error{Op: "Post", URL: "http://127.0.0.1:1/collections", Err: errors.New("connection refused")}
+	cancelledErr := &url.Error{Op: "Post", URL: "http://127.0.0.1:1/collections", Err: context.Canceled}
+
+	assert.Equal(t, FESTER_UNAVAILABLE, uploadErrorCode(connectionErr))
+	assert.Equal(t, INTERRUPTED, uploadErrorCode(cancelledErr))
+	assert.Equal(t, DEADLINE_EXCEEDED, uploadErrorCode(fmt.Errorf("upload: %w", context.DeadlineExceeded)))
+	assert.Equal(t, FESTER_ERROR_RESPONSE, uploadErrorCode(errors.New("can't set the collection name")))
+}
+
+// TestRunNetworkAndServerErrors tests the strict-mode exit codes of dropped connections and error responses
+func TestRunNetworkAndServerErrors(t *testing.T) {
+	dropping := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		connection, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			connection.Close()
+		}
+	}).server
+	rejecting := newRunStub(t, http.StatusOK, http.StatusBadRequest, "")
+
+	tests := []struct {
+		name     string
+		server   string
+		exitCode FesterizeError
+	}{
+		{"Connection dropped", dropping.URL, FESTER_UNAVAILABLE},
+		{"Error response", rejecting.URL, FESTER_ERROR_RESPONSE},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig(t, tc.server, TestDirUnFester+"/ballin.csv")
+			cfg.StrictMode = true
+
+			exitCode, _, err := run(context.Background(), cfg)
+
+			assert.Equal(t, int(tc.exitCode), exitCode)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+// TestRunStrictCompat tests that an incompatible Fester only stops the run with --strict-compat
+func TestRunStrictCompat(t *testing.T) {
+	h := newRunHarness(t, nil)
+
+	var requests int32
+	ts := newVersionedFester(t, "0.9.0", &requests)
+	defer delete(festerVersions, ts.URL)
+
+	cfg := testConfig(t, ts.URL, TestDirUnFester+"/missing.csv")
+	exitCode, results, err := run(context.Background(), cfg)
+	assert.Equal(t, 0, exitCode)
+	assert.Nil(t, err)
+	assert.Contains(t, h.log.String(), "Fester may not be compatible")
+
+	cfg = testConfig(t, ts.URL, TestDirUnFester+"/missing.csv")
+	cfg.StrictCompat = true
+	exitCode, results, err = run(context.Background(), cfg)
+	assert.Equal(t, int(INCOMPATIBLE_SERVER), exitCode)
+	assert.NotNil(t, err)
+	assert.Empty(t, results)
+}
+
+// TestRunNoOutput tests that files are uploaded and reported without anything being written locally
+func TestRunNoOutput(t *testing.T) {
+	h := newRunHarness(t, respondWith(http.StatusCreated, "Festerized CSV"))
+	cfg := testConfig(t, h.server.URL, TestDirUnFester+"/ballin.csv")
+	cfg.NoOutput, cfg.StrictMode = true, true
+
+	exitCode, results, err := run(context.Background(), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.NoDirExists(t, cfg.OutputDir)
+	assert.Len(t, results, 1)
+	assert.Equal(t, StatusSuccess, results[0].Status)
+	assert.Contains(t, h.log.String(), "Not saving festerized CSV")
+}
+
+// openFileCount returns the number of file descriptors the test process has open
+func openFileCount(t *testing.T) int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("Can't count open file descriptors on this platform")
+	}
+	return len(entries)
+}
+
+// TestRunClosesOutputFiles tests that output files are closed as each file is finished rather than when the
+// run ends, so a large batch doesn't keep a file descriptor open per file
+func TestRunClosesOutputFiles(t *testing.T) {
+	openFileCount(t)
+
+	dir := t.TempDir()
+	var sources []string
+	for index := range 200 {
+		path := filepath.Join(dir, "batch-"+strconv.Itoa(index)+".csv")
+		if err := os.WriteFile(path, []byte("Item ARK\nark:/21198/zz00091vxj\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sources = append(sources, path)
+	}
+
+	var mutex sync.Mutex
+	var counts []int
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		counts = append(counts, openFileCount(t))
+		mutex.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("Item ARK,IIIF Manifest URL\n"))
+	})
+
+	exitCode, _, err := run(context.Background(), testConfig(t, h.server.URL, sources...))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Len(t, counts, len(sources))
+	assert.Less(t, counts[len(counts)-1]-counts[0], 20)
+}
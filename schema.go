@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema describes the columns each object type's rows need, checked before upload, and optionally the order
+// --canonical-header puts columns in
+type Schema struct {
+	Types   map[string]TypeSchema `json:"types" yaml:"types"`
+	Columns []string              `json:"columns" yaml:"columns"`
+}
+
+// TypeSchema lists the columns rows of an object type need a value in, and the ones they may have a value in;
+// optional columns are only listed for reference, and are never reported
+type TypeSchema struct {
+	Required []string `json:"required" yaml:"required"`
+	Optional []string `json:"optional" yaml:"optional"`
+}
+
+// LoadSchema reads a schema from a JSON file, or a YAML file if it doesn't end in .json
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema Schema
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&schema)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&schema)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema file %s: %w", path, err)
+	}
+	if err := schema.validate(); err != nil {
+		return nil, fmt.Errorf("invalid schema file %s: %w", path, err)
+	}
+	return &schema, nil
+}
+
+// validate checks that the schema only describes known object types, doesn't make a column both required
+// and optional, and doesn't list a column twice in its column order
+func (schema *Schema) validate() error {
+	if len(schema.Types) == 0 && len(schema.Columns) == 0 {
+		return errors.New("no object types or columns described")
+	}
+	for index, column := range schema.Columns {
+		if strings.TrimSpace(column) == "" {
+			return errors.New("empty column name in column order")
+		}
+		if slices.Contains(schema.Columns[:index], column) {
+			return fmt.Errorf("column %q is listed twice in column order", column)
+		}
+	}
+	for objectType, typeSchema := range schema.Types {
+		normalized := normalizeObjectType(objectType)
+		if normalized != objectTypeCollection && normalized != objectTypeWork && normalized != objectTypePage {
+			return fmt.Errorf("unknown object type %q", objectType)
+		}
+		for _, column := range typeSchema.Required {
+			if slices.Contains(typeSchema.Optional, column) {
+				return fmt.Errorf("column %q is both required and optional for %s rows", column, objectType)
+			}
+		}
+	}
+	return nil
+}
+
+// forType returns the schema of an object type, however its name is capitalized
+func (schema *Schema) forType(objectType string) (TypeSchema, bool) {
+	for name, typeSchema := range schema.Types {
+		if normalizeObjectType(name) == objectType {
+			return typeSchema, true
+		}
+	}
+	return TypeSchema{}, false
+}
+
+// ValidateSchema checks that every row has a value in the columns its object type requires; a required column
+// missing from the header is reported once, against the header row
+func ValidateSchema(records [][]string, schema *Schema) []ValidationProblem {
+	if len(records) == 0 || schema == nil {
+		return nil
+	}
+
+	var problems []ValidationProblem
+	header := records[0]
+	typeIndex := columnIndex(header, objectTypeColumn)
+	missing := map[string]bool{}
+
+	for rowIndex, row := range records[1:] {
+		objectType := normalizeObjectType(cell(row, typeIndex))
+		typeSchema, found := schema.forType(objectType)
+		if !found {
+			continue
+		}
+
+		for _, column := range typeSchema.Required {
+			index := columnIndex(header, column)
+			switch {
+			case index == -1 && !missing[column]:
+				missing[column] = true
+				reason := fmt.Sprintf("missing column %q, which %s rows require", column, objectType)
+				problems = append(problems, ValidationProblem{Row: 1, Reason: reason})
+			case index != -1 && cell(row, index) == "":
+				reason := fmt.Sprintf("missing value, which %s rows require", objectType)
+				problems = append(problems, ValidationProblem{rowIndex + 2, column, "", reason})
+			}
+		}
+	}
+	return problems
+}
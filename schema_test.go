@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSchemaDir is the directory holding the sample schema
+var TestSchemaDir = "test/test-resources/schema"
+
+// testSchemaCSV has a work and a page that each miss a value the sample schema requires of them
+const testSchemaCSV = `Item ARK,Parent ARK,Object Type,Title,File Name,Item Sequence
+ark:/21198/zz0001,,Collection,Papers,,
+ark:/21198/zz0002,ark:/21198/zz0001,Work,,work.tif,
+ark:/21198/zz0003,ark:/21198/zz0002,Page,,page.tif,
+`
+
+// TestValidateSchemaByObjectType tests that Work and Page rows are checked against their own columns
+func TestValidateSchemaByObjectType(t *testing.T) {
+	schema, err := LoadSchema(TestSchemaDir + "/schema.yaml")
+	if !assert.Nil(t, err) {
+		return
+	}
+	records, err := readCSVRecords(writeTestCSV(t, "batch.csv", testSchemaCSV))
+	assert.Nil(t, err)
+
+	assert.Equal(t, []ValidationProblem{
+		{3, "Title", "", "missing value, which Work rows require"},
+		{Row: 1, Reason: `missing column "Rights.statementLocal", which Work rows require`},
+		{4, "Item Sequence", "", "missing value, which Page rows require"},
+	}, ValidateSchema(records, schema))
+}
+
+// TestLoadSchemaJSON tests that a JSON schema is read, matching object types however they're capitalized
+func TestLoadSchemaJSON(t *testing.T) {
+	path := writeTestCSV(t, "schema.json", `{"types": {"page": {"required": ["File Name", "Item Sequence"]}}}`)
+
+	schema, err := LoadSchema(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	records, err := readCSVRecords(writeTestCSV(t, "batch.csv", testSchemaCSV))
+	assert.Nil(t, err)
+
+	problems := ValidateSchema(records, schema)
+	if assert.Len(t, problems, 1) {
+		assert.Equal(t, 4, problems[0].Row)
+		assert.Equal(t, "Item Sequence", problems[0].Column)
+	}
+}
+
+// TestLoadSchemaInvalid tests that schemas that can't be applied are rejected
+func TestLoadSchemaInvalid(t *testing.T) {
+	tests := map[string]string{
+		"empty.yaml":    "types: {}\n",
+		"unknown.yaml":  "types:\n  Folder:\n    required: [Title]\n",
+		"conflict.yaml": "types:\n  Work:\n    required: [Title]\n    optional: [Title]\n",
+		"typo.yaml":     "types:\n  Work:\n    requried: [Title]\n",
+		"typo.json":     `{"types": {"Work": {"requried": ["Title"]}}}`,
+		"twice.yaml":    "columns: [Title, Title]\n",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadSchema(writeTestCSV(t, name, content))
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestValidateFileSchema tests that a loaded schema is part of the checks run before upload
+func TestValidateFileSchema(t *testing.T) {
+	schema, err := LoadSchema(TestSchemaDir + "/schema.yaml")
+	if !assert.Nil(t, err) {
+		return
+	}
+	problems, err := ValidateFile(writeTestCSV(t, "batch.csv", testSchemaCSV), Config{Schema: schema})
+	assert.Nil(t, err)
+	assert.Len(t, problems, 3)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// ShuffleFiles returns the files in a random order, the same one for the same seed; the files passed in are
+// left as they are
+func ShuffleFiles(paths []string, seed int64) []string {
+	shuffled := append([]string{}, paths...)
+	random := rand.New(rand.NewSource(seed))
+	random.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
+
+// shuffleSeed returns the seed to shuffle the files with: the one given with --seed, or one taken from the clock
+func shuffleSeed(seed int64, given bool) int64 {
+	if given {
+		return seed
+	}
+	return time.Now().UnixNano()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestShuffleFiles tests that a fixed seed always yields the same order of the same files
+func TestShuffleFiles(t *testing.T) {
+	paths := []string{"a.csv", "b.csv", "c.csv", "d.csv", "e.csv", "f.csv"}
+
+	shuffled := ShuffleFiles(paths, 42)
+	assert.Equal(t, shuffled, ShuffleFiles(paths, 42))
+	assert.ElementsMatch(t, paths, shuffled)
+	assert.NotEqual(t, paths, shuffled)
+	assert.Equal(t, []string{"a.csv", "b.csv", "c.csv", "d.csv", "e.csv", "f.csv"}, paths)
+}
+
+// TestShuffleSeed tests that a given seed is kept and a missing one is made up
+func TestShuffleSeed(t *testing.T) {
+	assert.Equal(t, int64(7), shuffleSeed(7, true))
+	assert.NotZero(t, shuffleSeed(0, false))
+}
+
+// TestRunShuffleLimit tests that shuffled files are uploaded in the seed's order, and that the limit takes the
+// first of them
+func TestRunShuffleLimit(t *testing.T) {
+	var uploaded []string
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, header, err := r.FormFile("file"); err == nil {
+			uploaded = append(uploaded, header.Filename)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	var sources []string
+	for _, name := range []string{"one.csv", "two.csv", "three.csv", "four.csv"} {
+		sources = append(sources, writeTestCSV(t, name, testCollectionCSV))
+	}
+	var expected []string
+	for _, path := range ShuffleFiles(sources, 3)[:2] {
+		expected = append(expected, filepath.Base(path))
+	}
+
+	cfg := testConfig(t, h.server.URL, sources...)
+	cfg.Shuffle, cfg.Seed, cfg.Limit = true, 3, 2
+	exitCode, _, err := run(context.Background(), cfg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, expected, uploaded)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ParseSince parses a --since value: an RFC3339 timestamp, or @path to use the modification time of a file
+func ParseSince(value string) (time.Time, error) {
+	if path, found := strings.CutPrefix(value, "@"); found {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("can't read the modification time of %s: %w", path, err)
+		}
+		return info.ModTime(), nil
+	}
+
+	threshold, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since value %q: expected an RFC3339 timestamp or @file", value)
+	}
+	return threshold, nil
+}
+
+// FilterSince keeps the files modified after the threshold. A file whose modification time can't be read,
+// such as one that doesn't exist, is kept rather than quietly dropped, so that the run fails it as missing.
+func FilterSince(paths []string, threshold time.Time) []string {
+	var kept []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err == nil && !info.ModTime().After(threshold) {
+			Logger.Info("Skipping file not modified since threshold",
+				zap.String("path", path),
+				zap.Time("modified", info.ModTime()),
+				zap.Time("since", threshold))
+			continue
+		}
+		kept = append(kept, path)
+	}
+	return kept
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// touch creates a file with the supplied modification time
+func touch(t *testing.T, path string, modified time.Time) {
+	if err := os.WriteFile(path, []byte("Item ARK\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestParseSince tests that timestamps and @file references are parsed
+func TestParseSince(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "last-run")
+	modified := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	touch(t, marker, modified)
+
+	threshold, err := ParseSince("2024-06-01T00:00:00Z")
+	assert.Nil(t, err)
+	assert.True(t, threshold.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+
+	threshold, err = ParseSince("@" + marker)
+	assert.Nil(t, err)
+	assert.True(t, threshold.Equal(modified))
+
+	_, err = ParseSince("yesterday")
+	assert.NotNil(t, err)
+
+	_, err = ParseSince("@" + filepath.Join(t.TempDir(), "missing"))
+	assert.NotNil(t, err)
+}
+
+// TestFilterSince tests that files not modified after the threshold are skipped
+func TestFilterSince(t *testing.T) {
+	logger, sink := createLogger()
+	Logger = logger
+
+	dir := t.TempDir()
+	threshold := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	older := filepath.Join(dir, "older.csv")
+	same := filepath.Join(dir, "same.csv")
+	newer := filepath.Join(dir, "newer.csv")
+	missing := filepath.Join(dir, "missing.csv")
+	touch(t, older, threshold.Add(-time.Hour))
+	touch(t, same, threshold)
+	touch(t, newer, threshold.Add(time.Hour))
+
+	kept := FilterSince([]string{older, same, newer, missing}, threshold)
+
+	assert.Equal(t, []string{newer, missing}, kept)
+	assert.Contains(t, sink.String(), "Skipping file not modified since threshold")
+	assert.Contains(t, sink.String(), older)
+}
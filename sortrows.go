@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// objectTypeRanks orders rows so that collections come before their works, and works before their pages;
+// rows of any other type come last
+var objectTypeRanks = map[string]int{
+	objectTypeCollection: 0,
+	objectTypeWork:       1,
+	objectTypePage:       2,
+}
+
+// objectTypeRank returns where rows of an object type are sorted to
+func objectTypeRank(objectType string) int {
+	if rank, found := objectTypeRanks[normalizeObjectType(objectType)]; found {
+		return rank
+	}
+	return len(objectTypeRanks)
+}
+
+// SortRows sorts the data rows by the key column, after putting collection rows before work rows and work rows
+// before page rows; the header stays first, and rows with the same type and key keep their order, so the same
+// CSV always sorts the same way
+func SortRows(key string) CSVTransform {
+	return func(records [][]string) ([][]string, error) {
+		if len(records) == 0 {
+			return records, nil
+		}
+
+		keyIndex := columnIndex(records[0], key)
+		if keyIndex == -1 {
+			return nil, fmt.Errorf("can't sort rows by %q, which isn't a column", key)
+		}
+		typeIndex := columnIndex(records[0], objectTypeColumn)
+
+		slices.SortStableFunc(records[1:], func(a, b []string) int {
+			if rankA, rankB := objectTypeRank(cell(a, typeIndex)), objectTypeRank(cell(b, typeIndex)); rankA != rankB {
+				return rankA - rankB
+			}
+			return strings.Compare(cell(a, keyIndex), cell(b, keyIndex))
+		})
+		return records, nil
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testUnsortedCSV has pages before their works and works before their collection, with two rows sharing a key
+const testUnsortedCSV = `Item ARK,Parent ARK,Object Type,Title
+ark:/21198/zz0005,ark:/21198/zz0003,Page,Page 2
+ark:/21198/zz0003,ark:/21198/zz0001,Work,Letter
+ark:/21198/zz0004,ark:/21198/zz0003,page,Page 1
+ark:/21198/zz0002,ark:/21198/zz0001,Work,Map
+ark:/21198/zz0001,,Collection,Papers
+ark:/21198/zz0002,ark:/21198/zz0001,Work,Map (duplicate)
+`
+
+// TestSortRows tests that the header stays first, collections come before works and works before pages, and
+// rows are sorted by the key within each type without reordering rows that share a key
+func TestSortRows(t *testing.T) {
+	records, err := readCSVRecords(writeTestCSV(t, "unsorted.csv", testUnsortedCSV))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	sorted, err := SortRows(itemARKColumn)(records)
+	assert.Nil(t, err)
+
+	var titles []string
+	for _, row := range sorted {
+		titles = append(titles, row[3])
+	}
+	assert.Equal(t, []string{"Title", "Papers", "Map", "Map (duplicate)", "Letter", "Page 1", "Page 2"}, titles)
+}
+
+// TestSortRowsByTitle tests sorting by another column, with rows of unknown types last
+func TestSortRowsByTitle(t *testing.T) {
+	records := [][]string{
+		{"Object Type", "Title"},
+		{"", "Alpha"},
+		{"Work", "Zulu"},
+		{"Work", "Bravo"},
+	}
+
+	sorted, err := SortRows(titleColumn)(records)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"Object Type", "Title"}, {"Work", "Bravo"}, {"Work", "Zulu"}, {"", "Alpha"}}, sorted)
+
+	_, err = SortRows("Item Sequence")(records)
+	assert.ErrorContains(t, err, `"Item Sequence"`)
+}
+
+// TestSortRowsStableUpload tests that CSVs holding the same rows in different orders upload the same bytes
+func TestSortRowsStableUpload(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(testUnsortedCSV, "\n"), "\n")
+	reversed := []string{lines[0]}
+	for index := len(lines) - 1; index > 0; index-- {
+		reversed = append(reversed, lines[index])
+	}
+	// The two rows sharing a key stay in the order they were in
+	reversed[1], reversed[3] = reversed[3], reversed[1]
+
+	transforms := []CSVTransform{SortRows(itemARKColumn)}
+	first, err := transformCSV(strings.NewReader(testUnsortedCSV), CSVInput{}, transforms)
+	assert.Nil(t, err)
+	second, err := transformCSV(strings.NewReader(strings.Join(reversed, "\n")+"\n"), CSVInput{}, transforms)
+	assert.Nil(t, err)
+
+	firstBytes, _ := io.ReadAll(first)
+	secondBytes, _ := io.ReadAll(second)
+	assert.True(t, bytes.Equal(firstBytes, secondBytes), "%s\n%s", firstBytes, secondBytes)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+// uploadFile uploads a CSV to Fester, as several requests if the CSV is to be split by object type or into chunks
+func uploadFile(ctx context.Context, filePath, postURL string,
+	opts UploadOptions) (*http.Response, []byte, error) {
+	if opts.SplitByType || opts.ChunkRows > 0 {
+		return uploadSegmented(ctx, filePath, postURL, opts)
+	}
+	return uploadCSV(ctx, filePath, postURL, opts)
+}
+
+// ValidateChunkRows validates the number of rows in each chunk of a CSV uploaded in chunks
+func ValidateChunkRows(rows int) error {
+	if rows < 0 {
+		return fmt.Errorf("invalid --chunk-rows %d: expected a number of rows, or 0 to upload CSVs whole", rows)
+	}
+	return nil
+}
+
+// SplitByType splits CSV records into collection, work, and page segments, in that order and each with the
+// header; rows of any other type go in a final segment, and empty segments are left out
+func SplitByType(records [][]string) [][][]string {
+	if len(records) == 0 {
+		return nil
+	}
+
+	typeIndex := columnIndex(records[0], objectTypeColumn)
+	order := []string{objectTypeCollection, objectTypeWork, objectTypePage, ""}
+	rowsByType := map[string][][]string{}
+
+	for _, row := range records[1:] {
+		objectType := normalizeObjectType(cell(row, typeIndex))
+		if objectType != objectTypeCollection && objectType != objectTypeWork && objectType != objectTypePage {
+			objectType = ""
+		}
+		rowsByType[objectType] = append(rowsByType[objectType], row)
+	}
+
+	var segments [][][]string
+	for _, objectType := range order {
+		if rows := rowsByType[objectType]; len(rows) > 0 {
+			segments = append(segments, append([][]string{records[0]}, rows...))
+		}
+	}
+	return segments
+}
+
+// ChunkRows splits CSV records into chunks of at most size data rows, each with the header; collection rows are
+// moved ahead of works, and works ahead of pages, so that no row is uploaded in a chunk before its parent's
+func ChunkRows(records [][]string, size int) [][][]string {
+	if len(records) == 0 || size <= 0 {
+		return nil
+	}
+
+	typeIndex := columnIndex(records[0], objectTypeColumn)
+	rows := slices.Clone(records[1:])
+	slices.SortStableFunc(rows, func(a, b []string) int {
+		return objectTypeRank(cell(a, typeIndex)) - objectTypeRank(cell(b, typeIndex))
+	})
+
+	var chunks [][][]string
+	for start := 0; start < len(rows); start += size {
+		end := min(start+size, len(rows))
+		chunks = append(chunks, append([][]string{records[0]}, rows[start:end]...))
+	}
+	return chunks
+}
+
+// segmentCSV splits CSV records into the segments that are uploaded as separate requests: one per object type
+// with --split-by-type, and chunks of at most --chunk-rows rows
+func segmentCSV(records [][]string, splitByType bool, chunkRows int) [][][]string {
+	segments := [][][]string{records}
+	if splitByType {
+		segments = SplitByType(records)
+	}
+	if chunkRows > 0 {
+		var chunks [][][]string
+		for _, segment := range segments {
+			chunks = append(chunks, ChunkRows(segment, chunkRows)...)
+		}
+		segments = chunks
+	}
+	return segments
+}
+
+// MergeSegments combines the CSVs Fester returned for each segment into one CSV with the rows in their
+// original order, matching rows on their Item ARK; rows Fester didn't return are kept as they were
+func MergeSegments(original [][]string, returned [][][]string) [][]string {
+	var header []string
+	returnedRows := map[string][]string{}
+
+	for _, segment := range returned {
+		if len(segment) == 0 {
+			continue
+		}
+		if header == nil {
+			header = segment[0]
+		}
+		itemIndex := columnIndex(segment[0], itemARKColumn)
+		for _, row := range segment[1:] {
+			returnedRows[cell(row, itemIndex)] = reorderRow(segment[0], row, header)
+		}
+	}
+	if header == nil {
+		return original
+	}
+
+	merged := [][]string{header}
+	itemIndex := columnIndex(original[0], itemARKColumn)
+	for _, row := range original[1:] {
+		if returnedRow, found := returnedRows[cell(row, itemIndex)]; found {
+			merged = append(merged, returnedRow)
+		} else {
+			merged = append(merged, reorderRow(original[0], row, header))
+		}
+	}
+	return merged
+}
+
+// reorderRow lays out a row's cells in the column order of another header, leaving missing columns empty
+func reorderRow(fromHeader []string, row []string, toHeader []string) []string {
+	reordered := make([]string, len(toHeader))
+	for index, column := range toHeader {
+		if fromIndex := columnIndex(fromHeader, column); fromIndex != -1 && fromIndex < len(row) {
+			reordered[index] = row[fromIndex]
+		}
+	}
+	return reordered
+}
+
+// uploadSegmented uploads the segments of a CSV as separate requests, in dependency order, and merges what
+// Fester returns into one CSV; it stops at the first segment Fester rejects
+func uploadSegmented(ctx context.Context, filePath, postURL string,
+	opts UploadOptions) (*http.Response, []byte, error) {
+	records, err := opts.Input.readRecords(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if records, err = applyTransforms(records, opts.Transforms); err != nil {
+		return nil, nil, err
+	}
+
+	segments := segmentCSV(records, opts.SplitByType, opts.ChunkRows)
+	if len(segments) == 0 {
+		return nil, nil, fmt.Errorf("%s has no rows to upload", filepath.Base(filePath))
+	}
+
+	var response *http.Response
+	var returned [][][]string
+	uploadName := csvName(filePath)
+
+	for index, segment := range segments {
+		content, err := writeCSVRecords(segment)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		Logger.Info("Uploading segment of CSV",
+			zap.String("filename", filepath.Base(uploadName)),
+			zap.Int("segment", index+1),
+			zap.Int("segments", len(segments)),
+			zap.String("object_type", normalizeObjectType(cell(segment[1], columnIndex(segment[0], objectTypeColumn)))),
+			zap.Int("rows", len(segment)-1))
+
+		var body []byte
+		response, body, err = postCSV(ctx, uploadName, content, postURL, opts)
+		if err != nil || !IsSuccessStatus(response.StatusCode) {
+			return response, body, err
+		}
+
+		reader := csv.NewReader(bytes.NewReader(body))
+		reader.FieldsPerRecord = -1
+		segmentRecords, err := reader.ReadAll()
+		if err != nil {
+			return response, body, fmt.Errorf("error reading Fester's response: %w", err)
+		}
+		returned = append(returned, segmentRecords)
+	}
+
+	merged, err := writeCSVRecords(MergeSegments(records, returned))
+	if err != nil {
+		return response, nil, err
+	}
+	return response, merged.Bytes(), nil
+}
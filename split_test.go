@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMixedDir is the directory of CSVs that mix collection, work, and page rows
+var TestMixedDir string = "test/test-resources/mixed"
+
+// newFesterizingStub creates a stub Fester that returns each uploaded CSV with an IIIF Manifest URL column
+// filled in from the Item ARK, and records the object types of the rows in each request
+func newFesterizingStub(t *testing.T, requests *[][]string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		records, err := csv.NewReader(file).ReadAll()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		itemIndex := columnIndex(records[0], itemARKColumn)
+		typeIndex := columnIndex(records[0], objectTypeColumn)
+		var objectTypes []string
+		records[0] = append(records[0], manifestURLColumn)
+		for index, row := range records[1:] {
+			objectTypes = append(objectTypes, row[typeIndex])
+			records[index+1] = append(row, "https://iiif.example.edu/"+row[itemIndex]+"/manifest")
+		}
+		*requests = append(*requests, objectTypes)
+
+		w.WriteHeader(http.StatusCreated)
+		csv.NewWriter(w).WriteAll(records)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// TestSplitByType tests that rows are split into collection, work, and page segments that each have the header
+func TestSplitByType(t *testing.T) {
+	records, err := readCSVRecords(TestMixedDir + "/mixed.csv")
+	assert.Nil(t, err)
+
+	segments := SplitByType(records)
+	assert.Len(t, segments, 3)
+	for _, segment := range segments {
+		assert.Equal(t, records[0], segment[0])
+	}
+	assert.Equal(t, "ark:/21198/zz0001", segments[0][1][1])
+	assert.Len(t, segments[1], 3)
+	assert.Len(t, segments[2], 3)
+
+	// Types without rows are left out
+	segments = SplitByType([][]string{{"Item ARK", "Object Type"}, {"ark:/21198/zz0002", "Work"}})
+	assert.Len(t, segments, 1)
+}
+
+// TestUploadSplitByType tests that a mixed CSV is uploaded one type at a time and merged back in its original order
+func TestUploadSplitByType(t *testing.T) {
+	var requests [][]string
+	ts := newFesterizingStub(t, &requests)
+
+	response, body, err := uploadFile(context.Background(), TestMixedDir+"/mixed.csv", ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", SplitByType: true, Headers: map[string]string{}})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Equal(t, [][]string{{"Collection"}, {"Work", "Work"}, {"Page", "Page"}}, requests)
+
+	merged, err := csv.NewReader(strings.NewReader(string(body))).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, merged, 6)
+	assert.Equal(t, manifestURLColumn, merged[0][len(merged[0])-1])
+
+	var arks []string
+	for _, row := range merged[1:] {
+		arks = append(arks, row[1])
+		assert.Equal(t, "https://iiif.example.edu/"+row[1]+"/manifest", row[len(row)-1])
+	}
+	assert.Equal(t, []string{"ark:/21198/zz0002", "ark:/21198/zz0003", "ark:/21198/zz0001", "ark:/21198/zz0004",
+		"ark:/21198/zz0005"}, arks)
+}
+
+// TestUploadSplitByTypeStopsOnError tests that later segments aren't uploaded once Fester rejects one
+func TestUploadSplitByTypeStopsOnError(t *testing.T) {
+	var uploads int32
+	ts := newStubFester(t, http.StatusInternalServerError, "error", &uploads)
+
+	response, body, err := uploadFile(context.Background(), TestMixedDir+"/mixed.csv", ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", SplitByType: true, Headers: map[string]string{}})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+	assert.Equal(t, "error", string(body))
+	assert.Equal(t, int32(1), uploads)
+}
+
+// TestChunkRows tests that rows are split into chunks of at most the given size that each have the header, with
+// the collection row in the first chunk
+func TestChunkRows(t *testing.T) {
+	records, err := readCSVRecords(TestMixedDir + "/mixed.csv")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	chunks := ChunkRows(records, 2)
+	if !assert.Len(t, chunks, 3) {
+		return
+	}
+	for _, chunk := range chunks {
+		assert.Equal(t, records[0], chunk[0])
+	}
+	assert.Equal(t, "ark:/21198/zz0001", chunks[0][1][1])
+	assert.Equal(t, []int{3, 3, 2}, []int{len(chunks[0]), len(chunks[1]), len(chunks[2])})
+
+	assert.Len(t, ChunkRows(records, 5), 1)
+	assert.Len(t, ChunkRows(records, 100), 1)
+	assert.Empty(t, ChunkRows(records[:1], 2))
+}
+
+// TestUploadChunkRows tests that a CSV is uploaded in chunks, parents first, and reassembled in its original
+// order with every row's manifest URL
+func TestUploadChunkRows(t *testing.T) {
+	var requests [][]string
+	ts := newFesterizingStub(t, &requests)
+
+	response, body, err := uploadFile(context.Background(), TestMixedDir+"/mixed.csv", ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", ChunkRows: 2, Headers: map[string]string{}})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Equal(t, [][]string{{"Collection", "Work"}, {"Work", "Page"}, {"Page"}}, requests)
+
+	merged, err := csv.NewReader(strings.NewReader(string(body))).ReadAll()
+	assert.Nil(t, err)
+	original, err := readCSVRecords(TestMixedDir + "/mixed.csv")
+	assert.Nil(t, err)
+	if assert.Len(t, merged, len(original)) {
+		for index, row := range merged[1:] {
+			assert.Equal(t, original[index+1], row[:len(row)-1])
+			assert.Equal(t, "https://iiif.example.edu/"+row[1]+"/manifest", row[len(row)-1])
+		}
+	}
+}
+
+// TestUploadChunkRowsByType tests that each object type's segment is chunked when splitting by type as well
+func TestUploadChunkRowsByType(t *testing.T) {
+	var requests [][]string
+	ts := newFesterizingStub(t, &requests)
+
+	_, _, err := uploadFile(context.Background(), TestMixedDir+"/mixed.csv", ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", SplitByType: true, ChunkRows: 1, Headers: map[string]string{}})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"Collection"}, {"Work"}, {"Work"}, {"Page"}, {"Page"}}, requests)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// stdinSource is the source argument that stands for a CSV piped to festerize
+const stdinSource = "-"
+
+// defaultStdinName is the filename a CSV read from stdin is uploaded and saved as
+const defaultStdinName = "stdin.csv"
+
+// ValidateStdinName validates the filename a CSV read from stdin is uploaded and saved as
+func ValidateStdinName(name string) error {
+	if name == "" || filepath.Base(name) != name || !IsCSVFile(name) {
+		return fmt.Errorf("invalid stdin name %q: expected a filename ending in .csv", name)
+	}
+	return nil
+}
+
+// hasStdinSource checks whether one of the sources is stdin
+func hasStdinSource(sources []string) bool {
+	for _, source := range sources {
+		if source == stdinSource {
+			return true
+		}
+	}
+	return false
+}
+
+// BufferStdin copies a CSV piped to festerize into a temporary file with the supplied name, so it can be
+// checked and uploaded like any other file; the returned func removes it
+func BufferStdin(in io.Reader, name string) (string, func(), error) {
+	dir, err := os.MkdirTemp("", "festerize-stdin-")
+	if err != nil {
+		return "", nil, err
+	}
+	remove := func() { os.RemoveAll(dir) }
+
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		remove()
+		return "", nil, err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, in); err != nil {
+		remove()
+		return "", nil, fmt.Errorf("error reading CSV from stdin: %w", err)
+	}
+	return path, remove, nil
+}
+
+// replaceStdinSource replaces the stdin source with the path stdin was buffered to
+func replaceStdinSource(sources []string, path string) []string {
+	replaced := make([]string, len(sources))
+	for index, source := range sources {
+		if source == stdinSource {
+			source = path
+		}
+		replaced[index] = source
+	}
+	return replaced
+}
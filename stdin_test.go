@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValidateStdinName tests that the stdin name must be a CSV filename without a directory
+func TestValidateStdinName(t *testing.T) {
+	assert.Nil(t, ValidateStdinName(defaultStdinName))
+	assert.Nil(t, ValidateStdinName("batch.csv.gz"))
+
+	for _, name := range []string{"", "batch", "batch.txt", "dir/batch.csv"} {
+		assert.NotNil(t, ValidateStdinName(name), name)
+	}
+}
+
+// TestReplaceStdinSource tests that only the stdin source is replaced
+func TestReplaceStdinSource(t *testing.T) {
+	sources := []string{"one.csv", stdinSource, "two.csv"}
+	assert.True(t, hasStdinSource(sources))
+	assert.False(t, hasStdinSource([]string{"one.csv"}))
+	assert.Equal(t, []string{"one.csv", "/tmp/piped.csv", "two.csv"}, replaceStdinSource(sources, "/tmp/piped.csv"))
+}
+
+// TestRunStdin tests that a CSV piped to festerize is uploaded and saved under the stdin name
+func TestRunStdin(t *testing.T) {
+	h := newRunHarness(t, respondWith(http.StatusCreated, "Festerized CSV"))
+
+	content, err := os.ReadFile(TestDirUnFester + "/ballin.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig(t, h.server.URL, stdinSource)
+	cfg.In, cfg.StdinName = strings.NewReader(string(content)), "piped.csv"
+
+	// An existing output directory is used without a prompt, since stdin can't answer it
+	if err := os.MkdirAll(cfg.OutputDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	exitCode, results, err := run(context.Background(), cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, exitCode)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "piped.csv", results[0].Filename)
+		assert.Equal(t, stdinSource, results[0].Path)
+	}
+
+	output, err := os.ReadFile(filepath.Join(cfg.OutputDir, "piped.csv"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Festerized CSV", string(output))
+}
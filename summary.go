@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// writeSummaryTable writes the outcome of each file as an aligned table, for an at-a-glance view of the run
+func writeSummaryTable(w io.Writer, results []FileResult) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "FILE\tSTATUS CODE\tRESULT\tDURATION\tMANIFEST URLS")
+	for _, result := range results {
+		statusCode := "-"
+		if result.StatusCode != 0 {
+			statusCode = strconv.Itoa(result.StatusCode)
+		}
+		outcome := "FAIL"
+		switch result.Status {
+		case StatusSuccess:
+			outcome = "OK"
+		case StatusSkipped:
+			outcome = "SKIP"
+		}
+		duration := time.Duration(result.DurationSeconds * float64(time.Second)).Round(time.Millisecond)
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%d\n", result.Filename, statusCode, outcome, duration,
+			len(result.ManifestURLs))
+	}
+	return table.Flush()
+}
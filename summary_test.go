@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWriteSummaryTable tests that the table's columns stay aligned for a mix of successes and failures, with
+// filenames of different lengths
+func TestWriteSummaryTable(t *testing.T) {
+	results := []FileResult{
+		{Filename: "ballin.csv", Status: StatusSuccess, StatusCode: 201, DurationSeconds: 1.2345,
+			ManifestURLs: []string{"https://iiif.example.edu/collection", "https://iiif.example.edu/work"}},
+		{Filename: "a-very-long-filename-from-the-digitization-lab.csv", Status: StatusFailure, StatusCode: 500,
+			DurationSeconds: 0.5},
+		{Filename: "missing.csv", Status: StatusFailure},
+	}
+
+	var buffer bytes.Buffer
+	assert.Nil(t, writeSummaryTable(&buffer, results))
+
+	expected := strings.Join([]string{
+		"FILE                                                STATUS CODE  RESULT  DURATION  MANIFEST URLS",
+		"ballin.csv                                          201          OK      1.235s    2",
+		"a-very-long-filename-from-the-digitization-lab.csv  500          FAIL    500ms     0",
+		"missing.csv                                         -            FAIL    0s        0",
+		"",
+	}, "\n")
+	assert.Equal(t, expected, buffer.String())
+}
+
+// TestWriteSummaryTableManyRows tests that every row of a large batch is written
+func TestWriteSummaryTableManyRows(t *testing.T) {
+	results := make([]FileResult, 500)
+	for index := range results {
+		results[index] = FileResult{Filename: "file.csv", Status: StatusSuccess, StatusCode: 201}
+	}
+
+	var buffer bytes.Buffer
+	assert.Nil(t, writeSummaryTable(&buffer, results))
+	assert.Equal(t, 501, strings.Count(buffer.String(), "\n"))
+}
+
+// TestRunSummaryTable tests that the summary table is printed at the end of a run, unless it's quiet
+func TestRunSummaryTable(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	ts := newRunStub(t, http.StatusOK, http.StatusCreated, "Festerized CSV")
+
+	for _, quiet := range []bool{false, true} {
+		cfg := testConfig(t, ts.URL, TestDirUnFester+"/ballin.csv")
+		cfg.SummaryTable, cfg.Quiet = true, quiet
+
+		printed := captureStdout(t, func() {
+			exitCode, _, err := run(context.Background(), cfg)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, exitCode)
+		})
+
+		if quiet {
+			assert.NotContains(t, printed, "SUCCESS!")
+			assert.NotContains(t, printed, "MANIFEST URLS")
+		} else {
+			assert.Contains(t, printed, "SUCCESS!")
+			assert.Regexp(t, `ballin\.csv +201 +OK`, printed)
+		}
+	}
+}
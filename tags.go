@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// tagKeyRegexp matches the keys a --tag can have, so they can be filtered on without quoting
+var tagKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ParseTags parses key=value tags, each given once
+func ParseTags(values []string) (map[string]string, error) {
+	parsed := make(map[string]string, len(values))
+	for _, value := range values {
+		key, tagValue, found := strings.Cut(value, "=")
+		key, tagValue = strings.TrimSpace(key), strings.TrimSpace(tagValue)
+		switch {
+		case !found || !tagKeyRegexp.MatchString(key) || tagValue == "":
+			return nil, fmt.Errorf("invalid tag %q: expected key=value, with a key of letters, digits, '.', '_', or '-'",
+				value)
+		case parsed[key] != "":
+			return nil, fmt.Errorf("invalid tag %q: %s is already tagged %q", value, key, parsed[key])
+		}
+		parsed[key] = tagValue
+	}
+	return parsed, nil
+}
+
+// withTags attaches the run's tags to every entry the logger writes, together under a tags field so they can't
+// be mistaken for the entry's own fields
+func withTags(log *zap.Logger, runTags map[string]string) *zap.Logger {
+	if len(runTags) == 0 {
+		return log
+	}
+	return log.WithOptions(zap.Fields(zap.Any("tags", runTags)))
+}
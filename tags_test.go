@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+// TestParseTags tests parsing key=value tags, and rejecting malformed and repeated ones
+func TestParseTags(t *testing.T) {
+	parsed, err := ParseTags([]string{"project=aids-posters", " ticket = LIB-1234 ", "note=a=b"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"project": "aids-posters", "ticket": "LIB-1234", "note": "a=b"}, parsed)
+
+	for _, values := range [][]string{
+		{"project"},
+		{"=aids-posters"},
+		{"project="},
+		{"my project=aids-posters"},
+		{"project=aids-posters", "project=lapl-photos"},
+	} {
+		_, err := ParseTags(values)
+		assert.Error(t, err, values)
+	}
+}
+
+// TestLoggerTags tests that the tags are attached to every log entry of a run, the session's included
+func TestLoggerTags(t *testing.T) {
+	defer func() { batchID, tags = "", nil }()
+	path := useTestLogFile(t)
+	batchID, tags = "ci-1234", map[string]string{"project": "aids-posters", "ticket": "LIB-1234"}
+
+	assert.Nil(t, logSessionStart(LogFormatJSON))
+	log := withTags(withBatchID(logger(LogFormatJSON, false, zapcore.InfoLevel, LogSampling{}), batchID), tags)
+	log.Info("Uploading file to Fester")
+	log.Sync()
+	assert.Nil(t, logSessionEnd(LogFormatJSON, 0))
+
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 3)
+	for _, line := range lines {
+		var entry map[string]any
+		assert.Nil(t, json.Unmarshal([]byte(line), &entry))
+		assert.Equal(t, map[string]any{"project": "aids-posters", "ticket": "LIB-1234"}, entry["tags"], line)
+	}
+}
+
+// TestReportTags tests that the tags are included in the report, and left out when there are none
+func TestReportTags(t *testing.T) {
+	defer func() { tags = nil }()
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+
+	tags = map[string]string{"project": "aids-posters"}
+	assert.Nil(t, WriteReport(reportPath, NewReport(nil)))
+	report, err := ReadReport(reportPath)
+	assert.Nil(t, err)
+	assert.Equal(t, tags, report.Tags)
+
+	tags = nil
+	assert.Nil(t, WriteReport(reportPath, NewReport(nil)))
+	content, err := os.ReadFile(reportPath)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(content), `"tags"`)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxThrottleBurst is the most bytes a throttled upload sends at once, so its rate stays even
+const maxThrottleBurst int = 32 * 1024
+
+// rateUnits are the units an upload rate can be given in, per second; the longer suffixes come first so that
+// "KB" isn't read as a "B" rate
+var rateUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"KIB", 1 << 10},
+	{"MIB", 1 << 20},
+	{"GIB", 1 << 30},
+	{"KB", 1e3},
+	{"MB", 1e6},
+	{"GB", 1e9},
+	{"B", 1},
+}
+
+// ParseRate parses an upload rate such as 5MB/s or 512KiB into bytes per second; a rate without a unit is in
+// bytes, and an empty rate or 0 means the uploads aren't throttled
+func ParseRate(value string) (int64, error) {
+	rate := strings.ToUpper(strings.TrimSpace(value))
+	rate = strings.TrimSuffix(rate, "/S")
+	if rate == "" {
+		return 0, nil
+	}
+
+	multiplier := 1.0
+	for _, unit := range rateUnits {
+		if strings.HasSuffix(rate, unit.suffix) {
+			rate, multiplier = strings.TrimSpace(strings.TrimSuffix(rate, unit.suffix)), unit.bytes
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(rate, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid upload rate %q: expected a rate such as 5MB/s or 512KiB/s", value)
+	}
+	return int64(number * multiplier), nil
+}
+
+// throttledReader is a reader that's read no faster than its rate, using a token bucket that starts empty
+type throttledReader struct {
+	ctx    context.Context
+	reader io.ReadCloser
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// newThrottledReader wraps a reader so that it's read at no more than rate bytes per second
+func newThrottledReader(ctx context.Context, reader io.ReadCloser, rate int64) *throttledReader {
+	burst := int(rate / 10)
+	if burst > maxThrottleBurst {
+		burst = maxThrottleBurst
+	} else if burst < 1 {
+		burst = 1
+	}
+	return &throttledReader{ctx: ctx, reader: reader, rate: float64(rate), burst: burst, last: time.Now()}
+}
+
+// Read waits until the bucket holds enough tokens for what's asked for, up to a burst, and then reads it
+func (throttled *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttled.burst {
+		p = p[:throttled.burst]
+	}
+
+	throttled.refill()
+	if missing := float64(len(p)) - throttled.tokens; missing > 0 {
+		timer := time.NewTimer(time.Duration(missing / throttled.rate * float64(time.Second)))
+		select {
+		case <-throttled.ctx.Done():
+			timer.Stop()
+			return 0, throttled.ctx.Err()
+		case <-timer.C:
+		}
+		throttled.refill()
+	}
+
+	n, err := throttled.reader.Read(p)
+	throttled.tokens -= float64(n)
+	return n, err
+}
+
+// refill adds the tokens earned since the bucket was last refilled, up to a burst
+func (throttled *throttledReader) refill() {
+	now := time.Now()
+	throttled.tokens += now.Sub(throttled.last).Seconds() * throttled.rate
+	if burst := float64(throttled.burst); throttled.tokens > burst {
+		throttled.tokens = burst
+	}
+	throttled.last = now
+}
+
+// Close closes the wrapped reader
+func (throttled *throttledReader) Close() error {
+	return throttled.reader.Close()
+}
+
+// throttleRequest caps the rate the request's body is sent at, including when it's sent again after a 429
+func throttleRequest(request *http.Request, rate int64) {
+	if request.Body == nil || rate <= 0 {
+		return
+	}
+
+	ctx := request.Context()
+	request.Body = newThrottledReader(ctx, request.Body, rate)
+	if getBody := request.GetBody; getBody != nil {
+		request.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return newThrottledReader(ctx, body, rate), nil
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseRate tests parsing upload rates in decimal and binary units
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		value string
+		rate  int64
+		fail  bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"5MB/s", 5000000, false},
+		{"5mb/s", 5000000, false},
+		{"512KiB/s", 512 * 1024, false},
+		{"1.5 GB", 1500000000, false},
+		{"2048", 2048, false},
+		{"100B/s", 100, false},
+		{"fast", 0, true},
+		{"-1MB/s", 0, true},
+		{"5MB/m", 0, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			rate, err := ParseRate(tc.value)
+			if tc.fail {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.rate, rate)
+		})
+	}
+}
+
+// TestThrottledReader tests that a throttled body of a known size takes at least as long as its rate allows
+func TestThrottledReader(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 20000)
+	reader := newThrottledReader(context.Background(), io.NopCloser(bytes.NewReader(content)), 100000)
+
+	started := time.Now()
+	read, err := io.ReadAll(reader)
+
+	assert.Nil(t, err)
+	assert.Equal(t, content, read)
+	assert.GreaterOrEqual(t, time.Since(started), 190*time.Millisecond)
+}
+
+// TestThrottledReaderCancelled tests that a throttled read stops when its context is cancelled
+func TestThrottledReaderCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reader := newThrottledReader(ctx, io.NopCloser(bytes.NewReader([]byte("content"))), 1)
+
+	_, err := io.ReadAll(reader)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestUploadCSVMaxUploadRate tests that a capped upload takes at least as long as its rate allows, and is
+// still sent with its length
+func TestUploadCSVMaxUploadRate(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	var received int
+	var contentLength int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, contentLength = len(body), r.ContentLength
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	content := bytes.Repeat([]byte("a,b,c\n"), 2000)
+
+	started := time.Now()
+	_, _, err := postCSV(context.Background(), "big.csv", bytes.NewReader(content), ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", MaxRate: 40000, Headers: map[string]string{}})
+
+	assert.Nil(t, err)
+	assert.Greater(t, received, len(content))
+	assert.Equal(t, int64(received), contentLength)
+	assert.GreaterOrEqual(t, time.Since(started), time.Duration(float64(received)/40000*float64(time.Second)))
+}
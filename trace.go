@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// UploadTrace records when each phase of an upload's request happened; the hooks can be called from other
+// goroutines, such as the one resolving DNS, so it's locked
+type UploadTrace struct {
+	mutex        sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	reused       bool
+}
+
+// TracePhases are how long each phase of an upload's request took; a phase that didn't happen, such as DNS
+// when a connection was reused, is zero
+type TracePhases struct {
+	DNS             time.Duration
+	Connect         time.Duration
+	TLS             time.Duration
+	TimeToFirstByte time.Duration
+	Total           time.Duration
+	ReusedConn      bool
+}
+
+// withUploadTrace attaches a trace to the context of an upload's request
+func withUploadTrace(ctx context.Context) (context.Context, *UploadTrace) {
+	trace := &UploadTrace{start: time.Now()}
+	return httptrace.WithClientTrace(ctx, trace.clientTrace()), trace
+}
+
+// clientTrace returns the hooks that record the phases of a request; a request that's retried after a 429
+// starts over, so only its last attempt is recorded
+func (trace *UploadTrace) clientTrace() *httptrace.ClientTrace {
+	record := func(at *time.Time) {
+		trace.mutex.Lock()
+		defer trace.mutex.Unlock()
+		*at = time.Now()
+	}
+
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			trace.mutex.Lock()
+			defer trace.mutex.Unlock()
+			trace.start = time.Now()
+			trace.dnsStart, trace.dnsDone = time.Time{}, time.Time{}
+			trace.connectStart, trace.connectDone = time.Time{}, time.Time{}
+			trace.tlsStart, trace.tlsDone = time.Time{}, time.Time{}
+			trace.firstByte = time.Time{}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			trace.mutex.Lock()
+			defer trace.mutex.Unlock()
+			trace.reused = info.Reused
+		},
+		DNSStart:             func(httptrace.DNSStartInfo) { record(&trace.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { record(&trace.dnsDone) },
+		ConnectStart:         func(string, string) { record(&trace.connectStart) },
+		ConnectDone:          func(string, string, error) { record(&trace.connectDone) },
+		TLSHandshakeStart:    func() { record(&trace.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { record(&trace.tlsDone) },
+		GotFirstResponseByte: func() { record(&trace.firstByte) },
+	}
+}
+
+// Phases returns how long each phase of the request took, counting the total up to now
+func (trace *UploadTrace) Phases() TracePhases {
+	trace.mutex.Lock()
+	defer trace.mutex.Unlock()
+
+	return TracePhases{
+		DNS:             between(trace.dnsStart, trace.dnsDone),
+		Connect:         between(trace.connectStart, trace.connectDone),
+		TLS:             between(trace.tlsStart, trace.tlsDone),
+		TimeToFirstByte: between(trace.start, trace.firstByte),
+		Total:           time.Since(trace.start),
+		ReusedConn:      trace.reused,
+	}
+}
+
+// between returns the time from start to end, or zero if either didn't happen
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// logUploadTrace logs the phase timings of an upload's request
+func logUploadTrace(url string, trace *UploadTrace) {
+	phases := trace.Phases()
+	Logger.Debug("Upload trace",
+		zap.String("url", url),
+		zap.Duration("dns", phases.DNS),
+		zap.Duration("connect", phases.Connect),
+		zap.Duration("tls", phases.TLS),
+		zap.Duration("time_to_first_byte", phases.TimeToFirstByte),
+		zap.Duration("total", phases.Total),
+		zap.Bool("reused_conn", phases.ReusedConn))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUploadTracePhases tests that the phases of a request to a local server are recorded
+func TestUploadTracePhases(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	// Ask for localhost by name so that it's resolved
+	url := strings.Replace(ts.URL, "127.0.0.1", "localhost", 1)
+	ctx, trace := withUploadTrace(context.Background())
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	assert.Nil(t, err)
+
+	// The test server's certificate is for its IP address, not for localhost
+	client := ts.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = true
+	response, err := client.Do(request)
+	if !assert.Nil(t, err) {
+		return
+	}
+	response.Body.Close()
+
+	phases := trace.Phases()
+	assert.Positive(t, phases.DNS)
+	assert.Positive(t, phases.Connect)
+	assert.Positive(t, phases.TLS)
+	assert.GreaterOrEqual(t, phases.TimeToFirstByte, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, phases.Total, phases.TimeToFirstByte)
+	assert.False(t, phases.ReusedConn)
+}
+
+// TestUploadCSVTrace tests that --trace logs the phase timings of each upload
+func TestUploadCSVTrace(t *testing.T) {
+	logger, sink := createLogger()
+	Logger = logger
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	for _, enabled := range []bool{false, true} {
+		_, _, err := uploadCSV(context.Background(), TestDirUnFester+"/ballin.csv", ts.URL+"/collections",
+			UploadOptions{IIIFVersion: "2", Trace: enabled, Headers: map[string]string{}})
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, 1, strings.Count(sink.String(), `"M":"Upload trace"`))
+	assert.Contains(t, sink.String(), `"connect":`)
+	assert.Contains(t, sink.String(), `"time_to_first_byte":`)
+}
+
+// TestBetween tests that a phase that didn't happen takes no time
+func TestBetween(t *testing.T) {
+	start := time.Now()
+	assert.Equal(t, time.Second, between(start, start.Add(time.Second)))
+	assert.Zero(t, between(time.Time{}, start))
+	assert.Zero(t, between(start, time.Time{}))
+}
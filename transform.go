@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// CSVTransform rewrites the records of a CSV, header first, before it's uploaded
+type CSVTransform func(records [][]string) ([][]string, error)
+
+// transforms returns the transforms selected for the run in the order they're applied
+func (cfg Config) transforms() []CSVTransform {
+	var transforms []CSVTransform
+	if len(cfg.Input.Renames) > 0 {
+		transforms = append(transforms, RenameColumns(cfg.Input.Renames))
+	}
+	if cfg.FixObjectTypes {
+		transforms = append(transforms, FixObjectTypes)
+	}
+	if cfg.ARKPrefix != "" {
+		transforms = append(transforms, FilterByARKPrefix(cfg.ARKPrefix, cfg.KeepCollectionRows))
+	}
+	if cfg.SortRows {
+		transforms = append(transforms, SortRows(cfg.SortKey))
+	}
+	if cfg.CollectionName != "" {
+		transforms = append(transforms, SetCollectionName(cfg.CollectionName))
+	}
+	if cfg.NormalizeDelimiter && cfg.Input.Delimiter != 0 && cfg.Input.Delimiter != ',' {
+		transforms = append(transforms, NormalizeDelimiter)
+	}
+	if cfg.CanonicalHeader {
+		transforms = append(transforms, CanonicalHeader(cfg.Schema))
+	}
+	return transforms
+}
+
+// transformCSV applies the transforms to a CSV read as the input says; without any, the CSV is passed through
+// untouched, and with any, it's rewritten comma-delimited
+func transformCSV(reader io.Reader, input CSVInput, transforms []CSVTransform) (io.Reader, error) {
+	if len(transforms) == 0 {
+		return reader, nil
+	}
+
+	csvReader := input.newReader(reader)
+	csvReader.FieldsPerRecord = -1
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if records, err = applyTransforms(records, transforms); err != nil {
+		return nil, err
+	}
+	return writeCSVRecords(records)
+}
+
+// applyTransforms applies the transforms to CSV records in turn
+func applyTransforms(records [][]string, transforms []CSVTransform) ([][]string, error) {
+	var err error
+	for _, transform := range transforms {
+		if records, err = transform(records); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// writeCSVRecords serializes CSV records
+func writeCSVRecords(records [][]string) (*bytes.Buffer, error) {
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	if err := writer.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buffer, nil
+}
+
+// NormalizeDelimiter leaves the records as they are; since transformed CSVs are written comma-delimited,
+// applying it converts a CSV split on another --delimiter
+func NormalizeDelimiter(records [][]string) ([][]string, error) {
+	return records, nil
+}
+
+// ColumnRename is one old=new column mapping given with --map
+type ColumnRename struct {
+	Old string
+	New string
+}
+
+// ParseFieldMap parses old=new column mappings, keeping them in the order they were given
+func ParseFieldMap(mappings []string) ([]ColumnRename, error) {
+	fields := make([]ColumnRename, 0, len(mappings))
+	for _, mapping := range mappings {
+		oldName, newName, found := strings.Cut(mapping, "=")
+		oldName, newName = strings.TrimSpace(oldName), strings.TrimSpace(newName)
+		if !found || oldName == "" || newName == "" {
+			return nil, fmt.Errorf("invalid column mapping %q: expected old=new", mapping)
+		}
+		fields = append(fields, ColumnRename{oldName, newName})
+	}
+	return fields, nil
+}
+
+// renameHeader renames the header cells found in the mapping, returning the old names it didn't find. Every
+// old name is looked up in the original header before any cell is renamed, so that swaps such as A=B,B=A
+// work; if an old name is mapped more than once, the last mapping wins.
+func renameHeader(header []string, mapping []ColumnRename) []string {
+	indexes := make([]int, len(mapping))
+	for position, rename := range mapping {
+		indexes[position] = columnIndex(header, rename.Old)
+	}
+
+	var missing []string
+	for position, rename := range mapping {
+		if indexes[position] == -1 {
+			missing = append(missing, rename.Old)
+			continue
+		}
+		header[indexes[position]] = rename.New
+	}
+	return missing
+}
+
+// RenameColumns renames the header cells found in the mapping, leaving data rows untouched
+func RenameColumns(mapping []ColumnRename) CSVTransform {
+	return func(records [][]string) ([][]string, error) {
+		if len(records) == 0 {
+			return records, nil
+		}
+
+		for _, column := range renameHeader(records[0], mapping) {
+			Logger.Warn("Column to rename not found in CSV header",
+				zap.String("column", column))
+		}
+		return records, nil
+	}
+}
+
+// SetCollectionName sets the title of the CSV's only collection row, adding a Title column if there isn't one
+func SetCollectionName(name string) CSVTransform {
+	return func(records [][]string) ([][]string, error) {
+		if len(records) == 0 {
+			return nil, errors.New("can't set the collection name of an empty CSV")
+		}
+
+		typeIndex := columnIndex(records[0], objectTypeColumn)
+		if typeIndex == -1 {
+			return nil, fmt.Errorf("can't set the collection name without an %s column", objectTypeColumn)
+		}
+
+		collectionRow := -1
+		for index, row := range records[1:] {
+			if normalizeObjectType(cell(row, typeIndex)) == objectTypeCollection {
+				if collectionRow != -1 {
+					return nil, errors.New("can't set the collection name of a CSV with more than one collection row")
+				}
+				collectionRow = index + 1
+			}
+		}
+		if collectionRow == -1 {
+			return nil, errors.New("can't set the collection name of a CSV without a collection row")
+		}
+
+		titleIndex := columnIndex(records[0], titleColumn)
+		if titleIndex == -1 {
+			titleIndex = len(records[0])
+			records[0] = append(records[0], titleColumn)
+		}
+
+		row := records[collectionRow]
+		for len(row) <= titleIndex {
+			row = append(row, "")
+		}
+		row[titleIndex] = name
+		records[collectionRow] = row
+		return records, nil
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readCSVString parses CSV content into records for comparison
+func readCSVString(t *testing.T, content string) [][]string {
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+// transformString applies the transforms to CSV content and returns the result
+func transformString(t *testing.T, content string, transforms ...CSVTransform) string {
+	reader, err := transformCSV(strings.NewReader(content), CSVInput{}, transforms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transformed, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(transformed)
+}
+
+// TestParseFieldMap tests that column mappings are parsed and malformed ones rejected
+func TestParseFieldMap(t *testing.T) {
+	fields, err := ParseFieldMap([]string{"Type=Object Type", " ARK = Item ARK "})
+	assert.Nil(t, err)
+	assert.Equal(t, []ColumnRename{{"Type", "Object Type"}, {"ARK", "Item ARK"}}, fields)
+
+	for _, mapping := range []string{"Type", "=Object Type", "Type="} {
+		_, err := ParseFieldMap([]string{mapping})
+		assert.NotNil(t, err, mapping)
+	}
+}
+
+// TestRenameColumns tests that only header cells are renamed and unknown mappings are ignored with a warning
+func TestRenameColumns(t *testing.T) {
+	logger, sink := createLogger()
+	defer logger.Sync()
+
+	Logger = logger
+
+	content := "Type,ARK,Title\nType,ark:/21198/zz00093mjc,Type\n"
+	transformed := transformString(t, content, RenameColumns([]ColumnRename{
+		{"Type", "Object Type"},
+		{"ARK", "Item ARK"},
+		{"Missing", "Unused"},
+	}))
+
+	assert.Equal(t, [][]string{
+		{"Object Type", "Item ARK", "Title"},
+		{"Type", "ark:/21198/zz00093mjc", "Type"},
+	}, readCSVString(t, transformed))
+	assert.Contains(t, sink.String(), "Column to rename not found in CSV header")
+	assert.Contains(t, sink.String(), "Missing")
+}
+
+// TestRenameColumnsSwap tests that columns can swap names, since each old name is looked up in the original header
+func TestRenameColumnsSwap(t *testing.T) {
+	logger, _ := createLogger()
+	Logger = logger
+
+	for range 10 {
+		transformed := transformString(t, "A,B,C\n1,2,3\n", RenameColumns([]ColumnRename{{"A", "B"}, {"B", "A"}}))
+		assert.Equal(t, [][]string{{"B", "A", "C"}, {"1", "2", "3"}}, readCSVString(t, transformed))
+	}
+}
+
+// TestSetCollectionName tests that the collection row's title is rewritten or injected
+func TestSetCollectionName(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected [][]string
+	}{
+		{
+			"Rewrite title",
+			"Item ARK,Object Type,Title\nark:/1,Collection,Old\nark:/2,Work,Work title\n",
+			[][]string{{"Item ARK", "Object Type", "Title"}, {"ark:/1", "Collection", "New"}, {"ark:/2", "Work", "Work title"}},
+		},
+		{
+			"Inject title",
+			"Item ARK,Object Type\nark:/2,Work\nark:/1,collection\n",
+			[][]string{{"Item ARK", "Object Type", "Title"}, {"ark:/2", "Work"}, {"ark:/1", "collection", "New"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			transformed := transformString(t, tc.content, SetCollectionName("New"))
+			assert.Equal(t, tc.expected, readCSVString(t, transformed))
+		})
+	}
+}
+
+// TestSetCollectionNameInvalid tests that the override needs exactly one collection row
+func TestSetCollectionNameInvalid(t *testing.T) {
+	for _, content := range []string{
+		"Item ARK,Object Type,Title\nark:/2,Work,Work title\n",
+		"Item ARK,Object Type,Title\nark:/1,Collection,One\nark:/2,Collection,Two\n",
+		"Item ARK,Title\nark:/1,Old\n",
+	} {
+		_, err := transformCSV(strings.NewReader(content), CSVInput{}, []CSVTransform{SetCollectionName("New")})
+		assert.NotNil(t, err, content)
+	}
+}
+
+// TestUploadCSVRenamesColumns tests that Fester receives the renamed header
+func TestUploadCSVRenamesColumns(t *testing.T) {
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		content, _ := io.ReadAll(file)
+		received = string(content)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	_, _, err := uploadCSV(context.Background(), TestDirUnFester+"/chase.csv", ts.URL+"/collections",
+		UploadOptions{IIIFVersion: "2", Headers: map[string]string{},
+			Transforms: []CSVTransform{RenameColumns([]ColumnRename{{"Item ARK", "ARK"}})}})
+	assert.Nil(t, err)
+
+	records := readCSVString(t, received)
+	assert.Equal(t, "ARK", records[0][1])
+	assert.Equal(t, "ark:/21198/zz00093mjc", records[1][1])
+}
+
+// TestNormalizeDelimiter tests that a tab-delimited CSV is only rewritten comma-delimited when asked
+func TestNormalizeDelimiter(t *testing.T) {
+	cfg := Config{Input: CSVInput{Delimiter: '\t'}}
+	assert.Empty(t, cfg.transforms())
+	cfg.NormalizeDelimiter = true
+	assert.Len(t, cfg.transforms(), 1)
+
+	file, err := os.Open(TestDirDelimited + "/tabs.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader, err := transformCSV(file, cfg.Input, cfg.transforms())
+	if err != nil {
+		t.Fatal(err)
+	}
+	transformed, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, [][]string{
+		{"Item ARK", "Parent ARK", "Object Type", "Title"},
+		{"ark:/21198/zz00091vxj", "", "Collection", "Ballin papers, 1890-1956"},
+		{"ark:/21198/zz00093cw5", "ark:/21198/zz00091vxj", "Work", "Sight Taste mural"},
+	}, readCSVString(t, string(transformed)))
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ARKPattern matches an ARK: the "ark:" label, a NAAN (optionally preceded by a slash), and a name that
+// may carry a shoulder and slash-separated qualifiers, e.g. ark:/21198/zz00091vxj
+const ARKPattern string = `^ark:/?[0-9bcdfghjkmnpqrstvwxz]{5,}/[0-9A-Za-z=~*+@_$.-]+(/[0-9A-Za-z=~*+@_$.-]+)*$`
+
+var arkRegexp = regexp.MustCompile(ARKPattern)
+
+// ValidationProblem describes a problem found in a CSV before it's uploaded; rows are numbered from
+// the header, which is row 1
+type ValidationProblem struct {
+	Row    int
+	Column string
+	Value  string
+	Reason string
+}
+
+// String formats the problem for people to read
+func (p ValidationProblem) String() string {
+	if p.Column == "" {
+		return fmt.Sprintf("row %d: %s", p.Row, p.Reason)
+	}
+	return fmt.Sprintf("row %d, %s %q: %s", p.Row, p.Column, p.Value, p.Reason)
+}
+
+// validationEnabled checks whether any local validation of CSVs was requested
+func (cfg Config) validationEnabled() bool {
+	return cfg.ValidateARKs || cfg.ValidateCSV || cfg.Schema != nil || len(cfg.MultiValueColumns) > 0 ||
+		cfg.MaxCellLength > 0 || cfg.WarnUnknownObjectTypes
+}
+
+// ValidateFile runs the validations selected for the run against a CSV file
+func ValidateFile(path string, cfg Config) ([]ValidationProblem, error) {
+	if !cfg.validationEnabled() {
+		return nil, nil
+	}
+
+	// A CSV that doesn't parse cleanly can't be checked any further
+	if cfg.ValidateCSV {
+		if problems, err := ValidateStructure(path, cfg.Input); err != nil || len(problems) > 0 {
+			return problems, err
+		}
+	}
+
+	records, err := cfg.Input.readRenamedRecords(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Long cells are worth a warning, but don't stop the file being uploaded
+	if cfg.MaxCellLength > 0 {
+		warnAboutLongCells(path, records, cfg.MaxCellLength)
+	}
+	if cfg.WarnUnknownObjectTypes {
+		warnAboutUnknownObjectTypes(path, records, cfg.FixObjectTypes)
+	}
+
+	var problems []ValidationProblem
+	if cfg.ValidateARKs {
+		problems = append(problems, ValidateARKs(records)...)
+	}
+	if cfg.Schema != nil {
+		problems = append(problems, ValidateSchema(records, cfg.Schema)...)
+	}
+	if len(cfg.MultiValueColumns) > 0 {
+		problems = append(problems, ValidateMultiValue(records, cfg.MultiValueColumns, cfg.MultiValueSeparator)...)
+	}
+	return problems, nil
+}
+
+// ValidateStructure checks that a CSV, read as the input says, parses cleanly, with every row having as many
+// fields as the header; rows with the wrong number of fields are all reported, but parsing stops at the first
+// malformed row since what follows it can't be trusted
+func ValidateStructure(path string, input CSVInput) ([]ValidationProblem, error) {
+	file, err := input.open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var problems []ValidationProblem
+	reader := input.newReader(file)
+	for rowNumber := 1; ; rowNumber++ {
+		_, err := reader.Read()
+		if err == io.EOF {
+			return problems, nil
+		}
+
+		var parseErr *csv.ParseError
+		if !errors.As(err, &parseErr) {
+			if err != nil {
+				return problems, err
+			}
+			continue
+		}
+
+		reason := fmt.Sprintf("line %d: %s", parseErr.Line, parseErr.Err)
+		problems = append(problems, ValidationProblem{Row: rowNumber, Reason: reason})
+		if !errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			return problems, nil
+		}
+	}
+}
+
+// ValidateARKs checks that the Item ARK and Parent ARK columns hold well-formed ARKs; every row needs an
+// Item ARK, but Parent ARK is empty for collection rows
+func ValidateARKs(records [][]string) []ValidationProblem {
+	if len(records) == 0 {
+		return nil
+	}
+
+	var problems []ValidationProblem
+	itemIndex := columnIndex(records[0], itemARKColumn)
+	parentIndex := columnIndex(records[0], parentARKColumn)
+
+	for rowIndex, row := range records[1:] {
+		rowNumber := rowIndex + 2
+
+		if itemIndex != -1 {
+			value := cell(row, itemIndex)
+			if value == "" {
+				problems = append(problems, ValidationProblem{rowNumber, itemARKColumn, value, "missing ARK"})
+			} else if !arkRegexp.MatchString(value) {
+				problems = append(problems, ValidationProblem{rowNumber, itemARKColumn, value, "malformed ARK"})
+			}
+		}
+
+		if parentIndex != -1 {
+			value := cell(row, parentIndex)
+			if value != "" && !arkRegexp.MatchString(value) {
+				problems = append(problems, ValidationProblem{rowNumber, parentARKColumn, value, "malformed ARK"})
+			}
+		}
+	}
+	return problems
+}
+
+// cell returns the trimmed value of a row's cell, or an empty string if the row is too short
+func cell(row []string, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[index])
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestARKPattern tests the ARK pattern against well-formed and malformed ARKs
+func TestARKPattern(t *testing.T) {
+	tests := []struct {
+		ark   string
+		valid bool
+	}{
+		{"ark:/21198/zz00091vxj", true},
+		{"ark:21198/zz00091vxj", true},
+		{"ark:/13030/ft9g50098d", true},
+		{"ark:/21198/zz00091vxj/page1.tif", true},
+		{"ark:/b5072/fk2abc", true},
+		{"ark:/21198/", false},
+		{"ark:/2119/zz00091vxj", false},
+		{"ark://21198/zz00091vxj", false},
+		{"ARK:/21198/zz00091vxj", false},
+		{"21198/zz00091vxj", false},
+		{"ark:/21198/zz00091 vxj", false},
+		{"ark:/21198/zz00091vxj/", false},
+		{"", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.ark, func(t *testing.T) {
+			assert.Equal(t, tc.valid, arkRegexp.MatchString(tc.ark))
+		})
+	}
+}
+
+// TestValidateARKs tests that every malformed or missing ARK is reported with its row number
+func TestValidateARKs(t *testing.T) {
+	records := [][]string{
+		{"Item ARK", "Parent ARK", "Object Type"},
+		{"ark:/21198/zz00091vxj", "", "Collection"},
+		{"ark:/21198/zz00093cw5", "ark:/21198/zz00091vxj", "Work"},
+		{"", "ark:/21198/zz00093cw5", "Page"},
+		{"ark:21198/zz00093cxp", "ark/21198/zz00091vxj", "Work"},
+		{"zz0025dwd9", " ark:/21198/zz00091vxj "},
+	}
+
+	assert.Equal(t, []ValidationProblem{
+		{4, itemARKColumn, "", "missing ARK"},
+		{5, parentARKColumn, "ark/21198/zz00091vxj", "malformed ARK"},
+		{6, itemARKColumn, "zz0025dwd9", "malformed ARK"},
+	}, ValidateARKs(records))
+}
+
+// TestValidateARKsFixtures tests that the ARKs in the test CSVs are all valid
+func TestValidateARKsFixtures(t *testing.T) {
+	paths, err := filepath.Glob(TestDirUnFester + "/*.csv")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		records, err := readCSVRecords(path)
+		assert.Nil(t, err)
+		assert.Empty(t, ValidateARKs(records), path)
+	}
+}
+
+// TestValidateStructure tests that ragged rows and broken quoting are reported with their line numbers
+func TestValidateStructure(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected []ValidationProblem
+	}{
+		{"ragged.csv", []ValidationProblem{
+			{Row: 3, Reason: "line 3: wrong number of fields"},
+			{Row: 4, Reason: "line 4: wrong number of fields"},
+		}},
+		{"unterminated-quote.csv", []ValidationProblem{
+			{Row: 3, Reason: `line 3: extraneous or missing " in quoted-field`},
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.file, func(t *testing.T) {
+			problems, err := ValidateStructure(filepath.Join(TestDirMalformed, tc.file), CSVInput{})
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, problems)
+		})
+	}
+}
+
+// TestValidateStructureFixtures tests that the test CSVs all parse cleanly
+func TestValidateStructureFixtures(t *testing.T) {
+	paths, err := filepath.Glob(TestDirUnFester + "/*.csv")
+	assert.Nil(t, err)
+
+	for _, path := range append(paths, TestDirGzipped+"/chase.csv.gz") {
+		problems, err := ValidateStructure(path, CSVInput{})
+		assert.Nil(t, err)
+		assert.Empty(t, problems, path)
+	}
+}
+
+// TestValidateFileStructure tests that a malformed CSV is only rejected when --validate-csv is passed
+func TestValidateFileStructure(t *testing.T) {
+	path := filepath.Join(TestDirMalformed, "unterminated-quote.csv")
+
+	problems, err := ValidateFile(path, Config{})
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+
+	problems, err = ValidateFile(path, Config{ValidateCSV: true})
+	assert.Nil(t, err)
+	assert.Len(t, problems, 1)
+}
+
+// TestValidateFileRenamedColumns tests that a CSV is validated under the column names --map gives it
+func TestValidateFileRenamedColumns(t *testing.T) {
+	cfg := Config{ValidateARKs: true, Input: CSVInput{Renames: []ColumnRename{{"ARK", "Item ARK"}}}}
+	path := writeTestCSV(t, "renamed.csv", "ARK,Parent ARK,Object Type\nzz0025dwd9,,Collection\n")
+
+	problems, err := ValidateFile(path, cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, []ValidationProblem{{2, itemARKColumn, "zz0025dwd9", "malformed ARK"}}, problems)
+}
+
+// TestValidateStructureDelimiter tests that a tab-delimited CSV validates when split on tabs
+func TestValidateStructureDelimiter(t *testing.T) {
+	problems, err := ValidateStructure(TestDirDelimited+"/tabs.csv", CSVInput{Delimiter: '\t'})
+	assert.Nil(t, err)
+	assert.Empty(t, problems)
+}
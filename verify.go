@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// defaultVerifyConcurrency is the number of manifests --verify-output requests at once by default
+const defaultVerifyConcurrency int = 4
+
+// ManifestCheck is the outcome of requesting a manifest URL that Fester returned
+type ManifestCheck struct {
+	URL        string
+	StatusCode int
+	Err        error
+}
+
+// Resolved checks whether the manifest could be retrieved
+func (check ManifestCheck) Resolved() bool {
+	return check.Err == nil && IsSuccessStatus(check.StatusCode)
+}
+
+// ValidateVerifyConcurrency validates the number of manifests requested at once
+func ValidateVerifyConcurrency(concurrency int) error {
+	if concurrency < 1 {
+		return fmt.Errorf("invalid --verify-concurrency %d: expected at least 1", concurrency)
+	}
+	return nil
+}
+
+// VerifyManifests requests each manifest URL, at most concurrency at a time, and returns the outcomes in the
+// order of the URLs; requests are sent with the server's credentials and headers only if the manifests are on
+// the server's own host, so they aren't handed to an image server
+func VerifyManifests(ctx context.Context, client *http.Client, server string, urls []string, concurrency int,
+	headers map[string]string) []ManifestCheck {
+	checks := make([]ManifestCheck, len(urls))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for index, manifestURL := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			statusCode, err := checkManifest(ctx, client, server, manifestURL, headers)
+			checks[index] = ManifestCheck{URL: manifestURL, StatusCode: statusCode, Err: err}
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+// checkManifest requests a manifest with HEAD, falling back to GET for servers that don't allow HEAD, and
+// returns the status code
+func checkManifest(ctx context.Context, client *http.Client, server, manifestURL string,
+	headers map[string]string) (int, error) {
+	statusCode, err := requestManifest(ctx, client, http.MethodHead, server, manifestURL, headers)
+	if err == nil && (statusCode == http.StatusMethodNotAllowed || statusCode == http.StatusNotImplemented) {
+		return requestManifest(ctx, client, http.MethodGet, server, manifestURL, headers)
+	}
+	return statusCode, err
+}
+
+// requestManifest sends a single request for a manifest and returns the status code
+func requestManifest(ctx context.Context, client *http.Client, method, server, manifestURL string,
+	headers map[string]string) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, method, manifestURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("User-Agent", userAgent())
+	if sameHost(server, manifestURL) {
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		authenticator, err := NewAuthenticator(server, authToken)
+		if err != nil {
+			return 0, err
+		}
+		authenticator(request)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+	return response.StatusCode, nil
+}
+
+// sameHost checks whether two URLs are on the same host and port
+func sameHost(a, b string) bool {
+	urlA, errA := url.Parse(a)
+	urlB, errB := url.Parse(b)
+	return errA == nil && errB == nil && urlA.Host != "" && urlA.Host == urlB.Host
+}
+
+// verifyOutput checks that the manifests Fester returned for a file can be retrieved, logging each one that
+// can't, and returns an error if any can't
+func verifyOutput(ctx context.Context, filename, server string, urls []string, concurrency int,
+	headers map[string]string) error {
+	checks := VerifyManifests(ctx, newUploadClient(), server, urls, concurrency, headers)
+
+	unresolved := 0
+	for _, check := range checks {
+		if check.Resolved() {
+			continue
+		}
+		unresolved++
+		Logger.Error("Manifest could not be retrieved",
+			zap.String("filename", filename),
+			zap.String("url", check.URL),
+			zap.Int("status_code", check.StatusCode),
+			zap.Error(check.Err))
+	}
+
+	Logger.Info("Verified manifests",
+		zap.String("filename", filename),
+		zap.Int("manifests", len(checks)),
+		zap.Int("unresolved", unresolved))
+	if unresolved > 0 {
+		return fmt.Errorf("%d of %d manifests could not be retrieved", unresolved, len(checks))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newManifestStub returns a server that serves manifests under /good and answers 404 for everything else
+func newManifestStub(t *testing.T) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fester/status":
+			w.WriteHeader(http.StatusOK)
+		case "/good/manifest":
+			w.WriteHeader(http.StatusOK)
+		case "/get-only/manifest":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// TestVerifyManifests tests checking a mix of manifests that resolve and ones that don't
+func TestVerifyManifests(t *testing.T) {
+	ts := newManifestStub(t)
+	urls := []string{ts.URL + "/good/manifest", ts.URL + "/missing/manifest", ts.URL + "/get-only/manifest",
+		"http://127.0.0.1:0/manifest"}
+
+	checks := VerifyManifests(context.Background(), ts.Client(), ts.URL, urls, 2, nil)
+
+	if assert.Len(t, checks, 4) {
+		assert.Equal(t, urls[0], checks[0].URL)
+		assert.True(t, checks[0].Resolved())
+		assert.Equal(t, http.StatusNotFound, checks[1].StatusCode)
+		assert.False(t, checks[1].Resolved())
+		assert.True(t, checks[2].Resolved())
+		assert.Error(t, checks[3].Err)
+		assert.False(t, checks[3].Resolved())
+	}
+}
+
+// TestVerifyManifestsHeaders tests that headers are only sent to manifests on the Fester server's host
+func TestVerifyManifestsHeaders(t *testing.T) {
+	var seen []string
+	manifests := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("X-Api-Key"))
+	}))
+	defer manifests.Close()
+
+	headers := map[string]string{"X-Api-Key": "secret"}
+	VerifyManifests(context.Background(), manifests.Client(), manifests.URL, []string{manifests.URL + "/a"}, 1, headers)
+	VerifyManifests(context.Background(), manifests.Client(), "http://fester.example.org", []string{manifests.URL + "/b"},
+		1, headers)
+
+	assert.Equal(t, []string{"secret", ""}, seen)
+}
+
+// TestValidateVerifyConcurrency tests the bounds of --verify-concurrency
+func TestValidateVerifyConcurrency(t *testing.T) {
+	assert.NoError(t, ValidateVerifyConcurrency(1))
+	assert.Error(t, ValidateVerifyConcurrency(0))
+}
+
+// TestRunVerifyOutput tests that a file whose manifests don't all resolve fails
+func TestRunVerifyOutput(t *testing.T) {
+	h := newRunHarness(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/good/manifest":
+			w.WriteHeader(http.StatusOK)
+		case "/collections":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, "Item ARK,Object Type,IIIF Manifest URL\nark:/1,Collection,http://%s/good/manifest\n"+
+				"ark:/2,Work,http://%s/missing/manifest\n", r.Host, r.Host)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	cfg := testConfig(t, h.server.URL, writeTestCSV(t, "verify.csv", testCollectionCSV))
+	cfg.VerifyOutput, cfg.VerifyWorkers, cfg.StrictMode = true, 2, true
+	exitCode, results, err := run(context.Background(), cfg)
+
+	assert.Error(t, err)
+	assert.Equal(t, int(MANIFEST_UNAVAILABLE), exitCode)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, StatusFailure, results[0].Status)
+		assert.Len(t, results[0].ManifestURLs, 2)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Build metadata, set at build time with:
+//
+//	go build -ldflags "-X main.Commit=$(git rev-parse --short HEAD) -X main.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var Commit string = "dev"
+var BuildDate string = "dev"
+
+// Prints the festerize version
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the festerize version",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(versionString())
+		os.Exit(0)
+	},
+}
+
+// versionString returns the festerize version as shown to users
+func versionString() string {
+	return fmt.Sprintf("festerize %s (commit %s, built %s)", festerizeVersion, Commit, BuildDate)
+}
+
+// userAgent returns the User-Agent header sent with requests to Fester
+func userAgent() string {
+	return fmt.Sprintf("%s/%s", "Festerize", festerizeVersion)
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVersionString tests that the version output and User-Agent share the same version
+func TestVersionString(t *testing.T) {
+	originalVersion, originalCommit, originalBuildDate := festerizeVersion, Commit, BuildDate
+	defer func() { festerizeVersion, Commit, BuildDate = originalVersion, originalCommit, originalBuildDate }()
+
+	festerizeVersion = "1.2.3"
+	assert.Equal(t, "festerize 1.2.3 (commit dev, built dev)", versionString())
+	assert.Equal(t, "Festerize/1.2.3", userAgent())
+
+	Commit, BuildDate = "abc1234", "2024-06-01T12:00:00Z"
+	assert.Equal(t, "festerize 1.2.3 (commit abc1234, built 2024-06-01T12:00:00Z)", versionString())
+}